@@ -3,8 +3,17 @@ package main
 import (
 	"github.com/bornholm/ghostwriter/internal/build"
 	"github.com/bornholm/ghostwriter/internal/command"
+	"github.com/bornholm/ghostwriter/internal/command/batch"
 	"github.com/bornholm/ghostwriter/internal/command/fix"
+	"github.com/bornholm/ghostwriter/internal/command/kb"
+	"github.com/bornholm/ghostwriter/internal/command/mcp"
+	"github.com/bornholm/ghostwriter/internal/command/plan"
+	"github.com/bornholm/ghostwriter/internal/command/render"
+	"github.com/bornholm/ghostwriter/internal/command/research"
+	"github.com/bornholm/ghostwriter/internal/command/rewrite"
+	"github.com/bornholm/ghostwriter/internal/command/serve"
 	"github.com/bornholm/ghostwriter/internal/command/whitepaper"
+	"github.com/bornholm/ghostwriter/internal/command/write"
 
 	_ "github.com/bornholm/genai/llm/provider/all"
 )
@@ -14,5 +23,14 @@ func main() {
 		"ghostwriter", build.Version, "write/edit articles with LLMs",
 		whitepaper.Root(),
 		fix.Root(),
+		rewrite.Root(),
+		write.Root(),
+		research.Root(),
+		plan.Root(),
+		render.Root(),
+		kb.Root(),
+		serve.Root(),
+		mcp.Root(),
+		batch.Root(),
 	)
 }
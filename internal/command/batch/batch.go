@@ -0,0 +1,419 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/output"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	whitepaperui "github.com/bornholm/ghostwriter/internal/command/whitepaper"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/scraper"
+	"github.com/bornholm/ghostwriter/pkg/search"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
+	"github.com/gosimple/slug"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+type jobResult struct {
+	Job      Job
+	Output   string
+	Duration time.Duration
+	Err      error
+}
+
+func Batch() *cli.Command {
+	return &cli.Command{
+		Name:  "batch",
+		Usage: "Generate the articles and white papers listed in a YAML manifest",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:      "manifest",
+				Required:  true,
+				Aliases:   []string{"m"},
+				Usage:     "Path to the YAML manifest listing the jobs",
+				TakesFile: true,
+				EnvVars:   []string{"GHOSTWRITER_BATCH_MANIFEST"},
+			},
+			&cli.IntFlag{
+				Name:    "concurrency",
+				Value:   2,
+				Aliases: []string{"j"},
+				Usage:   "Maximum number of jobs running at the same time",
+				EnvVars: []string{"GHOSTWRITER_BATCH_CONCURRENCY"},
+			},
+			&cli.DurationFlag{
+				Name:    "job-timeout",
+				Value:   2 * time.Hour,
+				Usage:   "Maximum duration of a job",
+				EnvVars: []string{"GHOSTWRITER_BATCH_JOB_TIMEOUT", "GHOSTWRITER_TIMEOUT"},
+			},
+			&cli.StringFlag{
+				Name:    "chromium-path",
+				Value:   "",
+				EnvVars: []string{"GHOSTWRITER_CHROMIUM_PATH"},
+			},
+			&cli.BoolFlag{
+				Name:    "no-sandbox",
+				Value:   false,
+				EnvVars: []string{"GHOSTWRITER_NO_SANDBOX"},
+			},
+			output.Flag(),
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
+		},
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "batch")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			jobs, err := LoadManifest(cliCtx.String("manifest"))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			concurrency := cliCtx.Int("concurrency")
+			if concurrency < 1 {
+				concurrency = 1
+			}
+
+			pricing, budget, err := shared.UsageSettings(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			ctx := cliCtx.Context
+
+			// A single client for all jobs: they share its rate limiter and circuit breaker
+			resilientClient, err := llmclient.NewClient(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to create llm client")
+			}
+
+			webScraper, searchClient, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			runner := &runner{
+				client:       resilientClient,
+				scraper:      webScraper,
+				searchClient: searchClient,
+				timeout:      cliCtx.Duration("job-timeout"),
+				chromiumPath: cliCtx.String("chromium-path"),
+				noSandbox:    cliCtx.Bool("no-sandbox"),
+				total:        len(jobs),
+				out:          out,
+				locale:       l,
+				pricing:      pricing,
+				budget:       budget,
+			}
+
+			out.Printf("\n%s\n\n", l.T(locale.BatchHeader, len(jobs), concurrency))
+
+			results := make([]jobResult, len(jobs))
+			sem := make(chan struct{}, concurrency)
+			locks := corpusLocks(jobs)
+			var wg sync.WaitGroup
+
+			for i, job := range jobs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					// Wait for the other jobs of the corpus before taking a slot
+					lock := locks[filepath.Clean(job.CorpusStoragePath)]
+					lock.Lock()
+					defer lock.Unlock()
+
+					sem <- struct{}{}
+					defer func() { <-sem }()
+
+					results[i] = runner.run(ctx, i+1, job)
+				}()
+			}
+
+			wg.Wait()
+
+			failed := 0
+			for _, r := range results {
+				if r.Err != nil {
+					failed++
+					continue
+				}
+				result.Files = append(result.Files, r.Output)
+			}
+
+			if !out.JSONL() {
+				printSummary(l, results, failed)
+			}
+
+			if failed > 0 {
+				return errors.Errorf("%d job(s) failed", failed)
+			}
+
+			return nil
+		},
+	}
+}
+
+type runner struct {
+	client       llm.Client
+	scraper      scraper.Scraper
+	searchClient search.Client
+	timeout      time.Duration
+	chromiumPath string
+	noSandbox    bool
+	total        int
+	out          *output.Output
+	locale       locale.Locale
+	pricing      usage.Pricing
+	budget       usage.Budget
+
+	printMu sync.Mutex
+}
+
+// printf prints a line prefixed with the job number. Jobs run concurrently so
+// their output is interleaved: only one-line summaries are printed.
+func (r *runner) printf(number int, format string, args ...any) {
+	if r.out.JSONL() {
+		return
+	}
+	r.printMu.Lock()
+	defer r.printMu.Unlock()
+	fmt.Printf("%s [%d/%d] %s\n", time.Now().Format("15:04:05"), number, r.total, fmt.Sprintf(format, args...))
+}
+
+func (r *runner) run(ctx context.Context, number int, job Job) (result jobResult) {
+	start := time.Now()
+	r.printf(number, "▶ %s : %q", job.Type, job.Subject)
+
+	// In the jsonl format, every line carries the job number
+	var reporter *output.Reporter
+	if parent := r.out.Reporter(); parent != nil {
+		reporter = parent.ForJob(number, string(job.Type))
+	}
+
+	// A failing job must not abort the others
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result = jobResult{Job: job, Duration: time.Since(start), Err: errors.Errorf("panic: %v", recovered)}
+			r.printf(number, "✗ %q : %v", job.Subject, result.Err)
+			if reporter != nil {
+				_ = reporter.Result(output.Result{}, result.Err)
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// Each job has its own usage report, written next to its documents
+	tracker := usage.NewTracker(r.pricing, usage.WithBudget(r.budget))
+	ctx = usage.WithContextTracker(ctx, tracker)
+
+	emit := func(evt agent.Event) error {
+		if line := whitepaperui.SummarizeEvent(r.locale, evt); line != "" {
+			r.printf(number, "%s", line)
+		}
+		return nil
+	}
+
+	if reporter != nil {
+		emit = reporter.Emit
+	}
+	tracker.EmitTo(emit)
+
+	var (
+		produced string
+		err      error
+	)
+
+	switch job.Type {
+	case JobTypeWhitepaper:
+		produced, err = r.runWhitepaper(ctx, job, emit)
+	case JobTypeArticle:
+		produced, err = r.runArticle(ctx, job, emit)
+	}
+
+	result = jobResult{Job: job, Output: produced, Duration: time.Since(start), Err: err}
+
+	usageReport := shared.ReportUsage(tracker, job.usagePath(), emit)
+
+	if reporter != nil {
+		_ = reporter.Result(output.Result{Entrypoint: produced, Usage: usageReport}, err)
+	}
+
+	if err != nil {
+		r.printf(number, "✗ %q : %v", job.Subject, err)
+	} else {
+		r.printf(number, "✓ %q : %s", job.Subject, produced)
+	}
+
+	return result
+}
+
+func (r *runner) runWhitepaper(ctx context.Context, job Job, emit agent.EmitFunc) (string, error) {
+	opts := []wppkg.OrchestratorOptionFunc{
+		wppkg.WithTargetWordCount(job.TargetWords),
+		wppkg.WithResearchDepth(article.ResearchDepth(job.ResearchDepth)),
+		wppkg.WithOutputDir(job.OutputDir),
+		wppkg.WithChromiumPath(r.chromiumPath),
+		wppkg.WithNoSandbox(r.noSandbox),
+		wppkg.WithMaxReviewRounds(job.MaxReviewRounds),
+		wppkg.WithSkipResearch(job.SkipResearch),
+		wppkg.WithModelName(llmclient.ModelName()),
+		wppkg.WithScraper(r.scraper),
+		wppkg.WithSearchClient(r.searchClient),
+		wppkg.WithLocale(r.locale),
+	}
+
+	if job.StyleGuide != "" {
+		guidelines, err := shared.ReadStyleGuide(job.StyleGuide)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		opts = append(opts, wppkg.WithStyleGuidelines(guidelines))
+	}
+
+	if job.AdditionalContext != "" {
+		data, err := os.ReadFile(job.AdditionalContext)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read additional context file")
+		}
+		opts = append(opts, wppkg.WithAdditionalContext(string(data)))
+	}
+
+	kb, kbClose, err := r.knowledgeBase(ctx, job)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer func() { _ = kbClose() }()
+
+	opts = append(opts, wppkg.WithKnowledgeBase(kb))
+
+	result, err := wppkg.WriteWhitePaper(ctx, r.client, job.Subject, emit, opts...)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate white paper")
+	}
+
+	return result.Entrypoint, nil
+}
+
+func (r *runner) runArticle(ctx context.Context, job Job, emit agent.EmitFunc) (string, error) {
+	opts := []article.OrchestratorOptionFunc{
+		article.WithTargetWordCount(job.TargetWords),
+		article.WithResearchDepth(article.ResearchDepth(job.ResearchDepth)),
+		article.WithMaxReviewRounds(job.MaxReviewRounds),
+		article.WithSkipResearch(job.SkipResearch),
+		article.WithScraper(r.scraper),
+		article.WithSearchClient(r.searchClient),
+	}
+
+	if job.StyleGuide != "" {
+		guidelines, err := shared.ReadStyleGuide(job.StyleGuide)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		opts = append(opts, article.WithStyleGuidelines(guidelines))
+	}
+
+	if job.AdditionalContext != "" {
+		data, err := os.ReadFile(job.AdditionalContext)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read additional context file")
+		}
+		opts = append(opts, article.WithAdditionalContext(string(data)))
+	}
+
+	kb, kbClose, err := r.knowledgeBase(ctx, job)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer func() { _ = kbClose() }()
+
+	opts = append(opts, article.WithKnowledgeBase(kb))
+
+	document, err := article.WriteArticle(ctx, r.client, job.Subject, emit, opts...)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate article")
+	}
+
+	if err := os.MkdirAll(job.OutputDir, 0755); err != nil {
+		return "", errors.Wrap(err, "failed to create output directory")
+	}
+
+	articlePath := filepath.Join(job.OutputDir, slug.Make(job.Subject)+".md")
+	if err := os.WriteFile(articlePath, []byte(article.FormatMarkdown(document)), 0644); err != nil {
+		return "", errors.Wrap(err, "failed to write article")
+	}
+
+	return articlePath, nil
+}
+
+func (r *runner) knowledgeBase(ctx context.Context, job Job) (article.KnowledgeBase, func() error, error) {
+	kb, kbClose, err := shared.BuildKnowledgeBase(ctx, job.CorpusStoragePath, shared.Collection{Name: job.Subject, Reset: !job.SkipResearch})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not create knowledge base")
+	}
+
+	if len(job.Files) > 0 {
+		if err := shared.BootstrapKnowledgeBase(kb, job.Files); err != nil {
+			_ = kbClose()
+			return nil, nil, errors.Wrap(err, "could not bootstrap knowledge base")
+		}
+	}
+
+	return kb, kbClose, nil
+}
+
+// printSummary prints a table of the job results.
+func printSummary(l locale.Locale, results []jobResult, failed int) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, l.T(locale.BatchColumns))
+	for i, r := range results {
+		status, detail := "✓", r.Output
+		if r.Err != nil {
+			status, detail = "✗", r.Err.Error()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", i+1, status, r.Job.Type, truncate(r.Job.Subject, 50), r.Duration.Round(time.Second), detail)
+	}
+	_ = w.Flush()
+
+	fmt.Printf("\n%s\n", l.T(locale.BatchSummary, len(results)-failed, failed))
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+func Root() *cli.Command {
+	return Batch()
+}
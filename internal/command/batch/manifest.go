@@ -0,0 +1,217 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	"github.com/gosimple/slug"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type JobType string
+
+const (
+	JobTypeArticle    JobType = "article"
+	JobTypeWhitepaper JobType = "whitepaper"
+)
+
+// Manifest lists the documents to generate. Relative paths are resolved
+// against the directory of the manifest.
+//
+//	defaults:
+//	  type: article
+//	  style_guide: guide.md
+//	jobs:
+//	  - subject: "What are the latest news about 3I/ATLAS ?"
+//	    target_words: 2000
+//	  - subject: "Interstellar objects"
+//	    type: whitepaper
+//	    output_dir: out/interstellar
+type Manifest struct {
+	Defaults Job   `yaml:"defaults"`
+	Jobs     []Job `yaml:"jobs"`
+}
+
+// Job is a manifest entry. Unset fields take the value of the manifest
+// defaults, then of the corresponding CLI flag default.
+type Job struct {
+	Subject           string   `yaml:"subject"`
+	Type              JobType  `yaml:"type"`
+	TargetWords       int      `yaml:"target_words"`
+	StyleGuide        string   `yaml:"style_guide"`
+	AdditionalContext string   `yaml:"additional_context"`
+	ResearchDepth     string   `yaml:"research_depth"`
+	MaxReviewRounds   int      `yaml:"max_review_rounds"`
+	SkipResearch      bool     `yaml:"skip_research"`
+	Files             []string `yaml:"files"`
+	// OutputDir receives the white paper, or the article Markdown file.
+	OutputDir string `yaml:"output_dir"`
+	// CorpusStoragePath defaults to <output_dir>/.corpus so that concurrent
+	// jobs do not share (and mix) their research documents.
+	CorpusStoragePath string `yaml:"corpus_storage_path"`
+}
+
+// LoadManifest reads and validates the manifest at path, applying the
+// defaults to every job. Jobs may not share their output directory.
+func LoadManifest(path string) ([]Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read manifest")
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "could not parse manifest %q", path)
+	}
+
+	if len(manifest.Jobs) == 0 {
+		return nil, errors.Errorf("manifest %q has no jobs", path)
+	}
+
+	baseDir := filepath.Dir(path)
+	jobs := make([]Job, 0, len(manifest.Jobs))
+	outputDirs := make(map[string]int, len(manifest.Jobs))
+
+	for i, job := range manifest.Jobs {
+		job = job.withDefaults(manifest.Defaults)
+
+		if err := job.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid job #%d in manifest %q", i+1, path)
+		}
+
+		job.resolvePaths(baseDir)
+
+		outputDir := filepath.Clean(job.OutputDir)
+		if other, exists := outputDirs[outputDir]; exists {
+			return nil, errors.Errorf("jobs #%d and #%d in manifest %q have the same output directory %q", other, i+1, path, job.OutputDir)
+		}
+		outputDirs[outputDir] = i + 1
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// corpusLocks returns a lock for each corpus storage path of the jobs: the
+// jobs sharing a corpus run one at a time, as they would otherwise race on
+// its collection and its document cache.
+func corpusLocks(jobs []Job) map[string]*sync.Mutex {
+	locks := make(map[string]*sync.Mutex, len(jobs))
+	for _, job := range jobs {
+		path := filepath.Clean(job.CorpusStoragePath)
+		if _, exists := locks[path]; !exists {
+			locks[path] = &sync.Mutex{}
+		}
+	}
+	return locks
+}
+
+func (j Job) withDefaults(defaults Job) Job {
+	if j.Type == "" {
+		j.Type = defaults.Type
+	}
+	if j.Type == "" {
+		j.Type = JobTypeArticle
+	}
+	if j.TargetWords == 0 {
+		j.TargetWords = defaults.TargetWords
+	}
+	if j.TargetWords == 0 {
+		switch j.Type {
+		case JobTypeWhitepaper:
+			j.TargetWords = 10000
+		default:
+			j.TargetWords = 1500
+		}
+	}
+	if j.StyleGuide == "" {
+		j.StyleGuide = defaults.StyleGuide
+	}
+	if j.AdditionalContext == "" {
+		j.AdditionalContext = defaults.AdditionalContext
+	}
+	if j.ResearchDepth == "" {
+		j.ResearchDepth = defaults.ResearchDepth
+	}
+	if j.ResearchDepth == "" {
+		j.ResearchDepth = string(article.ResearchDeep)
+	}
+	if j.MaxReviewRounds == 0 {
+		j.MaxReviewRounds = defaults.MaxReviewRounds
+	}
+	if j.MaxReviewRounds == 0 {
+		j.MaxReviewRounds = 2
+	}
+	if !j.SkipResearch {
+		j.SkipResearch = defaults.SkipResearch
+	}
+	if len(j.Files) == 0 {
+		j.Files = defaults.Files
+	}
+	if j.CorpusStoragePath == "" {
+		j.CorpusStoragePath = defaults.CorpusStoragePath
+	}
+
+	j.Subject = strings.TrimSpace(j.Subject)
+	if j.OutputDir == "" {
+		j.OutputDir = slug.Make(j.Subject)
+	}
+	if j.CorpusStoragePath == "" {
+		j.CorpusStoragePath = filepath.Join(j.OutputDir, ".corpus")
+	}
+
+	return j
+}
+
+// usagePath returns the path of the usage report of the job: the white paper
+// output directory, or next to the article Markdown file.
+func (j Job) usagePath() string {
+	if j.Type == JobTypeArticle {
+		return filepath.Join(j.OutputDir, slug.Make(j.Subject)+".usage.json")
+	}
+	return filepath.Join(j.OutputDir, usage.FileName)
+}
+
+func (j Job) validate() error {
+	if j.Subject == "" {
+		return errors.New("subject is required")
+	}
+	switch j.Type {
+	case JobTypeArticle, JobTypeWhitepaper:
+	default:
+		return errors.Errorf("unknown type %q (expected %q or %q)", j.Type, JobTypeArticle, JobTypeWhitepaper)
+	}
+	return nil
+}
+
+func (j *Job) resolvePaths(baseDir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	}
+
+	// A style guide which is not a file of the manifest directory is kept as
+	// is, to be used as inline text
+	if guide := resolve(j.StyleGuide); guide != j.StyleGuide {
+		if _, err := os.Stat(guide); err == nil {
+			j.StyleGuide = guide
+		}
+	}
+	j.AdditionalContext = resolve(j.AdditionalContext)
+	j.OutputDir = resolve(j.OutputDir)
+	j.CorpusStoragePath = resolve(j.CorpusStoragePath)
+
+	files := make([]string, 0, len(j.Files))
+	for _, f := range j.Files {
+		files = append(files, resolve(f))
+	}
+	j.Files = files
+}
@@ -0,0 +1,159 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bornholm/ghostwriter/pkg/article"
+)
+
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "jobs.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	return path
+}
+
+func TestLoadManifestDefaults(t *testing.T) {
+	path := writeManifest(t, `
+defaults:
+  type: whitepaper
+  style_guide: guide.md
+  max_review_rounds: 3
+  files: [notes/*.md]
+jobs:
+  - subject: "  Interstellar objects  "
+  - subject: "Solar panels"
+    type: article
+    target_words: 800
+    style_guide: /styles/article.md
+    output_dir: out/solar
+    corpus_storage_path: shared/.corpus
+`)
+	baseDir := filepath.Dir(path)
+
+	if err := os.WriteFile(filepath.Join(baseDir, "guide.md"), []byte("Be concise."), 0644); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	jobs, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(jobs))
+	}
+
+	whitepaper := jobs[0]
+	expected := Job{
+		Subject:           "Interstellar objects",
+		Type:              JobTypeWhitepaper,
+		TargetWords:       10000,
+		StyleGuide:        filepath.Join(baseDir, "guide.md"),
+		ResearchDepth:     string(article.ResearchDeep),
+		MaxReviewRounds:   3,
+		Files:             []string{filepath.Join(baseDir, "notes/*.md")},
+		OutputDir:         filepath.Join(baseDir, "interstellar-objects"),
+		CorpusStoragePath: filepath.Join(baseDir, "interstellar-objects", ".corpus"),
+	}
+	if !reflect.DeepEqual(whitepaper, expected) {
+		t.Errorf("expected %+v, got %+v", expected, whitepaper)
+	}
+
+	articleJob := jobs[1]
+	expected = Job{
+		Subject:           "Solar panels",
+		Type:              JobTypeArticle,
+		TargetWords:       800,
+		StyleGuide:        "/styles/article.md",
+		ResearchDepth:     string(article.ResearchDeep),
+		MaxReviewRounds:   3,
+		Files:             []string{filepath.Join(baseDir, "notes/*.md")},
+		OutputDir:         filepath.Join(baseDir, "out/solar"),
+		CorpusStoragePath: filepath.Join(baseDir, "shared/.corpus"),
+	}
+	if !reflect.DeepEqual(articleJob, expected) {
+		t.Errorf("expected %+v, got %+v", expected, articleJob)
+	}
+}
+
+func TestLoadManifestValidation(t *testing.T) {
+	testCases := map[string]struct {
+		manifest string
+		err      string
+	}{
+		"no jobs": {
+			manifest: "defaults:\n  type: article\n",
+			err:      "has no jobs",
+		},
+		"missing subject": {
+			manifest: "jobs:\n  - type: article\n",
+			err:      "subject is required",
+		},
+		"unknown type": {
+			manifest: "jobs:\n  - subject: Solar\n    type: essay\n",
+			err:      `unknown type "essay"`,
+		},
+		"same output directory": {
+			manifest: "jobs:\n  - subject: Solar\n    output_dir: out\n  - subject: Wind\n    output_dir: ./out/\n",
+			err:      "jobs #1 and #2",
+		},
+		"same default output directory": {
+			manifest: "jobs:\n  - subject: Solar panels\n  - subject: solar-panels\n",
+			err:      "the same output directory",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadManifest(writeManifest(t, tc.manifest))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected the error to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestCorpusLocks(t *testing.T) {
+	path := writeManifest(t, `
+defaults:
+  corpus_storage_path: shared/.corpus
+jobs:
+  - subject: Solar
+  - subject: Wind
+    corpus_storage_path: ./shared/.corpus/
+  - subject: Tides
+    corpus_storage_path: tides/.corpus
+`)
+
+	jobs, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	locks := corpusLocks(jobs)
+	if len(locks) != 2 {
+		t.Fatalf("expected a lock per corpus, got %d", len(locks))
+	}
+
+	solar := locks[filepath.Clean(jobs[0].CorpusStoragePath)]
+	wind := locks[filepath.Clean(jobs[1].CorpusStoragePath)]
+	tides := locks[filepath.Clean(jobs[2].CorpusStoragePath)]
+
+	if solar == nil || solar != wind {
+		t.Error("expected the jobs sharing a corpus to share a lock")
+	}
+	if tides == nil || tides == solar {
+		t.Error("expected the job with its own corpus to have its own lock")
+	}
+}
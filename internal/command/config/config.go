@@ -0,0 +1,463 @@
+package config
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFiles are the configuration files looked up in the working
+// directory when no path is given.
+var DefaultFiles = []string{"ghostwriter.yaml", "ghostwriter.yml"}
+
+// DefaultProfile is the profile used when neither --profile nor
+// default_profile select one.
+const DefaultProfile = "default"
+
+var ErrProfileNotFound = errors.New("profile not found")
+
+// File is a ghostwriter.yaml project configuration file.
+//
+//	default_profile: draft
+//	profiles:
+//	  default:
+//	    llm:
+//	      provider: openrouter
+//	      model: google/gemini-2.5-flash
+//	      api_key: ${OPENROUTER_API_KEY}
+//	      resilience:
+//	        retries: 3
+//	        chat_rate: 10
+//	    style_guide: guides/house-style.md
+//	    corpus_storage_path: .corpus
+//	    pricing:
+//	      google/gemini-2.5-flash: {prompt: 0.3, completion: 2.5}
+//	    budget:
+//	      max_cost: 5
+//	  draft:
+//	    extends: default
+//	    research_depth: basic
+//	    max_review_rounds: 1
+type File struct {
+	DefaultProfile string             `yaml:"default_profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+
+	path string
+}
+
+// Profile bundles settings that would otherwise be given as flags or
+// GHOSTWRITER_* environment variables.
+type Profile struct {
+	// Extends names a profile whose settings are inherited.
+	Extends           string         `yaml:"extends"`
+	LLM               LLMProfile     `yaml:"llm"`
+	StyleGuide        string         `yaml:"style_guide"`
+	AdditionalContext string         `yaml:"additional_context"`
+	ResearchDepth     string         `yaml:"research_depth"`
+	MaxReviewRounds   int            `yaml:"max_review_rounds"`
+	SearchEngines     []string       `yaml:"search_engines"`
+	GoogleSearch      GoogleProfile  `yaml:"google_search"`
+	SearXNG           SearXNGProfile `yaml:"searxng"`
+	Scraper           string         `yaml:"scraper"`
+	Locale            string         `yaml:"locale"`
+	CorpusStoragePath string         `yaml:"corpus_storage_path"`
+	Render            RenderProfile  `yaml:"render"`
+	Timeout           time.Duration  `yaml:"timeout"`
+	// Pricing is the price per million tokens of the models, used to estimate
+	// the cost of a run.
+	Pricing usage.Pricing `yaml:"pricing"`
+	Budget  BudgetProfile `yaml:"budget"`
+	// Env sets arbitrary environment variables, e.g. the GHOSTWRITER_CORPUS_*
+	// provider of the knowledge base.
+	Env map[string]string `yaml:"env"`
+}
+
+type LLMProfile struct {
+	Provider   string            `yaml:"provider"`
+	Model      string            `yaml:"model"`
+	BaseURL    string            `yaml:"base_url"`
+	APIKey     string            `yaml:"api_key"`
+	Resilience ResilienceProfile `yaml:"resilience"`
+}
+
+// ResilienceProfile configures the retry, rate limiting and circuit breaker
+// of the LLM clients. Retries and rates are pointers since 0 is a valid
+// setting for them (no retry, no rate limit).
+type ResilienceProfile struct {
+	Retries          *int          `yaml:"retries"`
+	RetryDelay       time.Duration `yaml:"retry_delay"`
+	ChatRate         *float64      `yaml:"chat_rate"`
+	EmbeddingsRate   *float64      `yaml:"embeddings_rate"`
+	Burst            int           `yaml:"burst"`
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerReset     time.Duration `yaml:"breaker_reset"`
+}
+
+// GoogleProfile holds the Google Custom Search credentials of the google
+// search engine.
+type GoogleProfile struct {
+	APIKey string `yaml:"api_key"`
+	CX     string `yaml:"cx"`
+}
+
+// SearXNGProfile lists the SearXNG instances of the searxng search engine,
+// public instances being discovered when empty, and the settings of their
+// JSON API.
+type SearXNGProfile struct {
+	URLs       []string `yaml:"urls"`
+	JSON       bool     `yaml:"json"`
+	AuthHeader string   `yaml:"auth_header"`
+	Language   string   `yaml:"language"`
+	Categories []string `yaml:"categories"`
+	TimeRange  string   `yaml:"time_range"`
+	// SafeSearch is a pointer since 0 disables the filter.
+	SafeSearch *int `yaml:"safe_search"`
+}
+
+type BudgetProfile struct {
+	MaxTokens int64   `yaml:"max_tokens"`
+	MaxCost   float64 `yaml:"max_cost"`
+}
+
+type RenderProfile struct {
+	ChromiumPath string `yaml:"chromium_path"`
+	NoSandbox    bool   `yaml:"no_sandbox"`
+}
+
+// Load reads the configuration file at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config file")
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse config file %q", path)
+	}
+
+	file.path = path
+
+	return &file, nil
+}
+
+// Find returns the first of the DefaultFiles present in the working
+// directory, or an empty string.
+func Find() (string, error) {
+	for _, name := range DefaultFiles {
+		_, err := os.Stat(name)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", errors.Wrapf(err, "could not stat config file %q", name)
+		}
+	}
+	return "", nil
+}
+
+// Profile returns the named profile with its inherited settings applied.
+// An empty name selects default_profile, then the "default" profile; no
+// profile at all is not an error in that case.
+func (f *File) Profile(name string) (Profile, bool, error) {
+	explicit := name != ""
+	if name == "" {
+		name = f.DefaultProfile
+		explicit = name != ""
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+
+	if _, exists := f.Profiles[name]; !exists {
+		if explicit {
+			return Profile{}, false, errors.Wrapf(ErrProfileNotFound, "profile %q (available: %s)", name, strings.Join(f.profileNames(), ", "))
+		}
+		return Profile{}, false, nil
+	}
+
+	profile, err := f.resolve(name, map[string]bool{})
+	if err != nil {
+		return Profile{}, false, errors.WithStack(err)
+	}
+
+	profile.resolvePaths(filepath.Dir(f.path))
+
+	return profile, true, nil
+}
+
+func (f *File) resolve(name string, visited map[string]bool) (Profile, error) {
+	if visited[name] {
+		return Profile{}, errors.Errorf("profile %q extends itself", name)
+	}
+	visited[name] = true
+
+	profile, exists := f.Profiles[name]
+	if !exists {
+		return Profile{}, errors.Wrapf(ErrProfileNotFound, "profile %q", name)
+	}
+
+	if profile.Extends == "" {
+		return profile, nil
+	}
+
+	parent, err := f.resolve(profile.Extends, visited)
+	if err != nil {
+		return Profile{}, errors.Wrapf(err, "could not resolve profile %q", name)
+	}
+
+	return parent.merge(profile), nil
+}
+
+func (f *File) profileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// merge returns p overridden by the non-zero settings of child.
+func (p Profile) merge(child Profile) Profile {
+	override := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+
+	override(&p.LLM.Provider, child.LLM.Provider)
+	override(&p.LLM.Model, child.LLM.Model)
+	override(&p.LLM.BaseURL, child.LLM.BaseURL)
+	override(&p.LLM.APIKey, child.LLM.APIKey)
+	override(&p.StyleGuide, child.StyleGuide)
+	override(&p.AdditionalContext, child.AdditionalContext)
+	override(&p.ResearchDepth, child.ResearchDepth)
+	override(&p.Scraper, child.Scraper)
+	override(&p.Locale, child.Locale)
+	override(&p.CorpusStoragePath, child.CorpusStoragePath)
+	override(&p.Render.ChromiumPath, child.Render.ChromiumPath)
+	override(&p.GoogleSearch.APIKey, child.GoogleSearch.APIKey)
+	override(&p.GoogleSearch.CX, child.GoogleSearch.CX)
+	override(&p.SearXNG.AuthHeader, child.SearXNG.AuthHeader)
+	override(&p.SearXNG.Language, child.SearXNG.Language)
+	override(&p.SearXNG.TimeRange, child.SearXNG.TimeRange)
+
+	if r := child.LLM.Resilience; r != (ResilienceProfile{}) {
+		if r.Retries != nil {
+			p.LLM.Resilience.Retries = r.Retries
+		}
+		if r.RetryDelay != 0 {
+			p.LLM.Resilience.RetryDelay = r.RetryDelay
+		}
+		if r.ChatRate != nil {
+			p.LLM.Resilience.ChatRate = r.ChatRate
+		}
+		if r.EmbeddingsRate != nil {
+			p.LLM.Resilience.EmbeddingsRate = r.EmbeddingsRate
+		}
+		if r.Burst != 0 {
+			p.LLM.Resilience.Burst = r.Burst
+		}
+		if r.BreakerThreshold != 0 {
+			p.LLM.Resilience.BreakerThreshold = r.BreakerThreshold
+		}
+		if r.BreakerReset != 0 {
+			p.LLM.Resilience.BreakerReset = r.BreakerReset
+		}
+	}
+
+	if child.MaxReviewRounds != 0 {
+		p.MaxReviewRounds = child.MaxReviewRounds
+	}
+	if len(child.SearchEngines) > 0 {
+		p.SearchEngines = child.SearchEngines
+	}
+	if len(child.SearXNG.URLs) > 0 {
+		p.SearXNG.URLs = child.SearXNG.URLs
+	}
+	if child.SearXNG.JSON {
+		p.SearXNG.JSON = true
+	}
+	if len(child.SearXNG.Categories) > 0 {
+		p.SearXNG.Categories = child.SearXNG.Categories
+	}
+	if child.SearXNG.SafeSearch != nil {
+		p.SearXNG.SafeSearch = child.SearXNG.SafeSearch
+	}
+	if child.Render.NoSandbox {
+		p.Render.NoSandbox = true
+	}
+	if child.Timeout != 0 {
+		p.Timeout = child.Timeout
+	}
+	if child.Budget.MaxTokens != 0 {
+		p.Budget.MaxTokens = child.Budget.MaxTokens
+	}
+	if child.Budget.MaxCost != 0 {
+		p.Budget.MaxCost = child.Budget.MaxCost
+	}
+
+	env := make(map[string]string, len(p.Env)+len(child.Env))
+	for k, v := range p.Env {
+		env[k] = v
+	}
+	for k, v := range child.Env {
+		env[k] = v
+	}
+	p.Env = env
+
+	pricing := make(usage.Pricing, len(p.Pricing)+len(child.Pricing))
+	for model, price := range p.Pricing {
+		pricing[model] = price
+	}
+	for model, price := range child.Pricing {
+		pricing[model] = price
+	}
+	p.Pricing = pricing
+
+	p.Extends = ""
+
+	return p
+}
+
+// resolvePaths makes the file paths of the profile relative to the
+// directory of the configuration file.
+func (p *Profile) resolvePaths(baseDir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	}
+
+	// A style guide which is not a file of the config directory is kept as
+	// is, to be used as inline text
+	if guide := resolve(p.StyleGuide); guide != p.StyleGuide {
+		if _, err := os.Stat(guide); err == nil {
+			p.StyleGuide = guide
+		}
+	}
+	p.AdditionalContext = resolve(p.AdditionalContext)
+	p.CorpusStoragePath = resolve(p.CorpusStoragePath)
+}
+
+// Variables returns the environment variables equivalent to the profile
+// settings. Values may reference other environment variables with ${VAR}.
+func (p Profile) Variables() map[string]string {
+	env := make(map[string]string)
+
+	set := func(key string, value string) {
+		if value != "" {
+			env[key] = os.ExpandEnv(value)
+		}
+	}
+
+	for k, v := range p.Env {
+		set(k, v)
+	}
+
+	if p.LLM.Provider != "" {
+		set("GHOSTWRITER_CHAT_COMPLETION_PROVIDER", p.LLM.Provider)
+
+		prefix := "GHOSTWRITER_CHAT_COMPLETION_" + strings.ReplaceAll(strings.ToUpper(p.LLM.Provider), "-", "_") + "_"
+		set(prefix+"MODEL", p.LLM.Model)
+		set(prefix+"BASE_URL", p.LLM.BaseURL)
+		set(prefix+"API_KEY", p.LLM.APIKey)
+	}
+
+	set("GHOSTWRITER_STYLE_GUIDE", p.StyleGuide)
+	set("GHOSTWRITER_ADDITIONAL_CONTEXT", p.AdditionalContext)
+	set("GHOSTWRITER_RESEARCH_DEPTH", p.ResearchDepth)
+	set("GHOSTWRITER_SEARCH_ENGINES", strings.Join(p.SearchEngines, ","))
+	set("GHOSTWRITER_GOOGLE_API_KEY", p.GoogleSearch.APIKey)
+	set("GHOSTWRITER_GOOGLE_CX", p.GoogleSearch.CX)
+	set("GHOSTWRITER_SEARXNG_URLS", strings.Join(p.SearXNG.URLs, ","))
+	set("GHOSTWRITER_SEARXNG_AUTH_HEADER", p.SearXNG.AuthHeader)
+	set("GHOSTWRITER_SEARXNG_LANGUAGE", p.SearXNG.Language)
+	set("GHOSTWRITER_SEARXNG_CATEGORIES", strings.Join(p.SearXNG.Categories, ","))
+	set("GHOSTWRITER_SEARXNG_TIME_RANGE", p.SearXNG.TimeRange)
+	set("GHOSTWRITER_SCRAPER", p.Scraper)
+	set("GHOSTWRITER_LOCALE", p.Locale)
+	set("GHOSTWRITER_CORPUS_STORAGE_PATH", p.CorpusStoragePath)
+	set("GHOSTWRITER_CHROMIUM_PATH", p.Render.ChromiumPath)
+
+	if p.MaxReviewRounds != 0 {
+		set("GHOSTWRITER_MAX_REVIEW_ROUNDS", strconv.Itoa(p.MaxReviewRounds))
+	}
+	if p.Render.NoSandbox {
+		set("GHOSTWRITER_NO_SANDBOX", "true")
+	}
+	if p.SearXNG.JSON {
+		set("GHOSTWRITER_SEARXNG_JSON", "true")
+	}
+	if p.SearXNG.SafeSearch != nil {
+		set("GHOSTWRITER_SEARXNG_SAFE_SEARCH", strconv.Itoa(*p.SearXNG.SafeSearch))
+	}
+	if p.Timeout != 0 {
+		set("GHOSTWRITER_TIMEOUT", p.Timeout.String())
+	}
+
+	r := p.LLM.Resilience
+	if r.Retries != nil {
+		set("GHOSTWRITER_LLM_RETRIES", strconv.Itoa(*r.Retries))
+	}
+	if r.RetryDelay != 0 {
+		set("GHOSTWRITER_LLM_RETRY_DELAY", r.RetryDelay.String())
+	}
+	if r.ChatRate != nil {
+		set("GHOSTWRITER_LLM_CHAT_RATE", strconv.FormatFloat(*r.ChatRate, 'f', -1, 64))
+	}
+	if r.EmbeddingsRate != nil {
+		set("GHOSTWRITER_LLM_EMBEDDINGS_RATE", strconv.FormatFloat(*r.EmbeddingsRate, 'f', -1, 64))
+	}
+	if r.Burst != 0 {
+		set("GHOSTWRITER_LLM_BURST", strconv.Itoa(r.Burst))
+	}
+	if r.BreakerThreshold != 0 {
+		set("GHOSTWRITER_LLM_BREAKER_THRESHOLD", strconv.Itoa(r.BreakerThreshold))
+	}
+	if r.BreakerReset != 0 {
+		set("GHOSTWRITER_LLM_BREAKER_RESET", r.BreakerReset.String())
+	}
+	if p.Budget.MaxTokens != 0 {
+		set("GHOSTWRITER_MAX_TOKENS", strconv.FormatInt(p.Budget.MaxTokens, 10))
+	}
+	if p.Budget.MaxCost != 0 {
+		set("GHOSTWRITER_MAX_COST", strconv.FormatFloat(p.Budget.MaxCost, 'f', -1, 64))
+	}
+	if len(p.Pricing) > 0 {
+		// Not expanded: the table is not a template
+		if data, err := json.Marshal(p.Pricing); err == nil {
+			env["GHOSTWRITER_PRICING"] = string(data)
+		}
+	}
+
+	return env
+}
+
+// Apply exports the profile settings as environment variables, without
+// overriding the variables already set. Flags read their GHOSTWRITER_*
+// variable when parsed, so the resolution order of every command is:
+// flag, environment (including .env), profile, then flag default.
+func (p Profile) Apply() error {
+	for key, value := range p.Variables() {
+		if _, exists := os.LookupEnv(key); exists {
+			slog.Debug("environment variable overrides profile setting", slog.String("variable", key))
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return errors.Wrapf(err, "could not set %s", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func loadFile(t *testing.T, content string) *File {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ghostwriter.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	file, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	return file
+}
+
+const profilesFile = `
+default_profile: draft
+profiles:
+  default:
+    llm:
+      provider: openrouter
+      model: google/gemini-2.5-flash
+      resilience:
+        retries: 3
+        chat_rate: 10
+    research_depth: deep
+    max_review_rounds: 2
+    timeout: 1h
+    pricing:
+      google/gemini-2.5-flash: {prompt: 0.3, completion: 2.5}
+    env:
+      GHOSTWRITER_CORPUS_CHAT_COMPLETION_PROVIDER: openai
+      GHOSTWRITER_WRITER_TEMPERATURE: "0.7"
+  draft:
+    extends: default
+    research_depth: basic
+    llm:
+      resilience:
+        retries: 0
+    env:
+      GHOSTWRITER_WRITER_TEMPERATURE: "0.9"
+  quick:
+    extends: draft
+    llm:
+      model: google/gemini-2.5-flash-lite
+    max_review_rounds: 1
+    pricing:
+      google/gemini-2.5-flash-lite: {prompt: 0.1, completion: 0.4}
+`
+
+func TestProfileExtends(t *testing.T) {
+	file := loadFile(t, profilesFile)
+
+	testCases := []struct {
+		name            string
+		model           string
+		researchDepth   string
+		maxReviewRounds int
+		retries         int
+		temperature     string
+		prices          int
+	}{
+		// An empty name selects default_profile
+		{name: "", model: "google/gemini-2.5-flash", researchDepth: "basic", maxReviewRounds: 2, retries: 0, temperature: "0.9", prices: 1},
+		{name: "default", model: "google/gemini-2.5-flash", researchDepth: "deep", maxReviewRounds: 2, retries: 3, temperature: "0.7", prices: 1},
+		{name: "quick", model: "google/gemini-2.5-flash-lite", researchDepth: "basic", maxReviewRounds: 1, retries: 0, temperature: "0.9", prices: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			profile, found, err := file.Profile(tc.name)
+			if err != nil {
+				t.Fatalf("expected no error, got: %+v", err)
+			}
+			if !found {
+				t.Fatal("expected the profile to be found")
+			}
+
+			if profile.LLM.Provider != "openrouter" {
+				t.Errorf("expected the inherited provider, got %q", profile.LLM.Provider)
+			}
+			if profile.LLM.Model != tc.model {
+				t.Errorf("expected model %q, got %q", tc.model, profile.LLM.Model)
+			}
+			if profile.ResearchDepth != tc.researchDepth {
+				t.Errorf("expected research depth %q, got %q", tc.researchDepth, profile.ResearchDepth)
+			}
+			if profile.MaxReviewRounds != tc.maxReviewRounds {
+				t.Errorf("expected %d review rounds, got %d", tc.maxReviewRounds, profile.MaxReviewRounds)
+			}
+			if r := profile.LLM.Resilience.Retries; r == nil || *r != tc.retries {
+				t.Errorf("expected %d retries, got %v", tc.retries, r)
+			}
+			if r := profile.LLM.Resilience.ChatRate; r == nil || *r != 10 {
+				t.Errorf("expected the inherited chat rate, got %v", r)
+			}
+			if profile.Timeout != time.Hour {
+				t.Errorf("expected the inherited timeout, got %s", profile.Timeout)
+			}
+			if got := profile.Env["GHOSTWRITER_WRITER_TEMPERATURE"]; got != tc.temperature {
+				t.Errorf("expected temperature %q, got %q", tc.temperature, got)
+			}
+			if got := profile.Env["GHOSTWRITER_CORPUS_CHAT_COMPLETION_PROVIDER"]; got != "openai" {
+				t.Errorf("expected the inherited env, got %q", got)
+			}
+			if len(profile.Pricing) != tc.prices {
+				t.Errorf("expected %d prices, got %d", tc.prices, len(profile.Pricing))
+			}
+			if profile.Extends != "" {
+				t.Errorf("expected the resolved profile not to extend another, got %q", profile.Extends)
+			}
+		})
+	}
+
+	// Resolving a child leaves its parents untouched
+	if got := file.Profiles["default"].Env["GHOSTWRITER_WRITER_TEMPERATURE"]; got != "0.7" {
+		t.Errorf("expected the parent env to be left untouched, got %q", got)
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		profile  string
+		found    bool
+		notFound bool
+		err      string
+	}{
+		{
+			name:    "extends itself",
+			content: "profiles:\n  default:\n    extends: default\n",
+			err:     `profile "default" extends itself`,
+		},
+		{
+			name:    "cycle",
+			content: "profiles:\n  a:\n    extends: b\n  b:\n    extends: c\n  c:\n    extends: a\n",
+			profile: "a",
+			err:     `profile "a" extends itself`,
+		},
+		{
+			name:     "missing parent",
+			content:  "profiles:\n  default:\n    extends: base\n",
+			notFound: true,
+		},
+		{
+			name:     "missing explicit profile",
+			content:  "profiles:\n  default: {}\n",
+			profile:  "draft",
+			notFound: true,
+			err:      "available: default",
+		},
+		{
+			name:     "missing default_profile",
+			content:  "default_profile: draft\nprofiles:\n  default: {}\n",
+			notFound: true,
+		},
+		{
+			name:    "no default profile",
+			content: "profiles:\n  draft: {}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := loadFile(t, tc.content)
+
+			_, found, err := file.Profile(tc.profile)
+			if found != tc.found {
+				t.Errorf("expected found to be %v, got %v", tc.found, found)
+			}
+
+			if tc.err == "" && !tc.notFound {
+				if err != nil {
+					t.Errorf("expected no error, got: %+v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tc.notFound && !errors.Is(err, ErrProfileNotFound) {
+				t.Errorf("expected ErrProfileNotFound, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected the error to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestProfileResolvePaths(t *testing.T) {
+	file := loadFile(t, `
+profiles:
+  default:
+    style_guide: guides/house-style.md
+    additional_context: /etc/ghostwriter/context.md
+    corpus_storage_path: .corpus
+  inline:
+    style_guide: Write in plain English.
+`)
+	baseDir := filepath.Dir(file.path)
+
+	if err := os.MkdirAll(filepath.Join(baseDir, "guides"), 0755); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "guides/house-style.md"), []byte("Be concise."), 0644); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	profile, _, err := file.Profile("")
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	inline, _, err := file.Profile("inline")
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{name: "relative", got: profile.StyleGuide, expected: filepath.Join(baseDir, "guides/house-style.md")},
+		{name: "absolute", got: profile.AdditionalContext, expected: "/etc/ghostwriter/context.md"},
+		{name: "directory", got: profile.CorpusStoragePath, expected: filepath.Join(baseDir, ".corpus")},
+		{name: "inline style guide", got: inline.StyleGuide, expected: "Write in plain English."},
+	}
+
+	for _, tc := range testCases {
+		if tc.got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, tc.got)
+		}
+	}
+
+	if variables := profile.Variables(); variables["GHOSTWRITER_STYLE_GUIDE"] != profile.StyleGuide {
+		t.Errorf("expected the resolved style guide to be exported, got %q", variables["GHOSTWRITER_STYLE_GUIDE"])
+	}
+}
+
+func TestProfileApply(t *testing.T) {
+	retries := 0
+	profile := Profile{
+		LLM: LLMProfile{
+			Provider:   "openrouter",
+			Model:      "google/gemini-2.5-flash",
+			APIKey:     "${TEST_OPENROUTER_KEY}",
+			Resilience: ResilienceProfile{Retries: &retries},
+		},
+		ResearchDepth: "basic",
+		Locale:        "fr",
+	}
+
+	t.Setenv("TEST_OPENROUTER_KEY", "secret")
+	// An existing variable, even from a .env file, takes precedence
+	t.Setenv("GHOSTWRITER_RESEARCH_DEPTH", "deep")
+
+	testCases := []struct {
+		key      string
+		expected string
+	}{
+		{key: "GHOSTWRITER_CHAT_COMPLETION_PROVIDER", expected: "openrouter"},
+		{key: "GHOSTWRITER_CHAT_COMPLETION_OPENROUTER_MODEL", expected: "google/gemini-2.5-flash"},
+		{key: "GHOSTWRITER_CHAT_COMPLETION_OPENROUTER_API_KEY", expected: "secret"},
+		{key: "GHOSTWRITER_LLM_RETRIES", expected: "0"},
+		{key: "GHOSTWRITER_LOCALE", expected: "fr"},
+		{key: "GHOSTWRITER_RESEARCH_DEPTH", expected: "deep"},
+	}
+
+	for _, tc := range testCases {
+		if tc.key == "GHOSTWRITER_RESEARCH_DEPTH" {
+			continue
+		}
+		// Restored once the test ends
+		t.Setenv(tc.key, "")
+		os.Unsetenv(tc.key)
+	}
+
+	if err := profile.Apply(); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	for _, tc := range testCases {
+		if got := os.Getenv(tc.key); got != tc.expected {
+			t.Errorf("expected %s=%q, got %q", tc.key, tc.expected, got)
+		}
+	}
+
+	if _, exists := profile.Variables()["GHOSTWRITER_SCRAPER"]; exists {
+		t.Error("expected the unset settings not to be exported")
+	}
+}
@@ -10,8 +10,11 @@ import (
 
 	"github.com/bornholm/genai/agent"
 	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/output"
 	"github.com/bornholm/ghostwriter/internal/command/shared"
 	whitepaperui "github.com/bornholm/ghostwriter/internal/command/whitepaper"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
 	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
@@ -29,12 +32,7 @@ func Fix() *cli.Command {
 				Usage:    "Path to the whitepaper output directory",
 				EnvVars:  []string{"GHOSTWRITER_FIX_DIR"},
 			},
-			&cli.StringFlag{
-				Name:    "style-guide",
-				Value:   "",
-				Aliases: []string{"g"},
-				EnvVars: []string{"GHOSTWRITER_STYLE_GUIDE"},
-			},
+			shared.StyleGuideFlag(),
 			&cli.StringFlag{
 				Name:    "additional-context",
 				Value:   "",
@@ -47,14 +45,38 @@ func Fix() *cli.Command {
 				Usage:   "Path to Corpus data dir (defaults to <dir>/.corpus if it exists)",
 				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
 			},
+			shared.CollectionFlag(),
 			&cli.BoolFlag{
 				Name:    "enrich",
 				Value:   false,
 				Usage:   "Force the enrichment pass (citation links + Mermaid diagrams) even if no > EDITOR: annotations are found",
 				EnvVars: []string{"GHOSTWRITER_FIX_ENRICH"},
 			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Value:   2 * time.Hour,
+				Usage:   "Maximum duration of the run",
+				EnvVars: []string{"GHOSTWRITER_TIMEOUT"},
+			},
+			output.Flag(),
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
 		},
-		Action: func(cliCtx *cli.Context) error {
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "fix")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
 			dir := strings.TrimSpace(cliCtx.String("dir"))
 			styleGuide := cliCtx.String("style-guide")
 			additionalContext := cliCtx.String("additional-context")
@@ -69,9 +91,15 @@ func Fix() *cli.Command {
 				}
 			}
 
-			ctx, cancel := context.WithTimeout(cliCtx.Context, 2*time.Hour)
+			ctx, cancel := context.WithTimeout(cliCtx.Context, cliCtx.Duration("timeout"))
 			defer cancel()
 
+			tracker, err := shared.NewUsageTracker(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			ctx = usage.WithContextTracker(ctx, tracker)
+
 			resilientClient, err := llmclient.NewClient(ctx)
 			if err != nil {
 				return errors.Wrap(err, "failed to create llm client")
@@ -80,14 +108,15 @@ func Fix() *cli.Command {
 			fixOptions := []wppkg.FixOptionFunc{
 				wppkg.WithFixInputDir(dir),
 				wppkg.WithFixForceEnrichment(forceEnrich),
+				wppkg.WithFixLocale(l),
 			}
 
 			if styleGuide != "" {
-				data, err := os.ReadFile(styleGuide)
+				guidelines, err := shared.ReadStyleGuide(styleGuide)
 				if err != nil {
-					return errors.Wrap(err, "failed to read style guide")
+					return errors.WithStack(err)
 				}
-				fixOptions = append(fixOptions, wppkg.WithFixStyleGuidelines(string(data)))
+				fixOptions = append(fixOptions, wppkg.WithFixStyleGuidelines(guidelines))
 			}
 
 			if additionalContext != "" {
@@ -99,7 +128,7 @@ func Fix() *cli.Command {
 			}
 
 			if corpusStoragePath != "" {
-				kb, kbClose, err := shared.BuildKnowledgeBase(ctx, corpusStoragePath)
+				kb, kbClose, err := shared.BuildKnowledgeBase(ctx, corpusStoragePath, shared.Collection{Name: cliCtx.String("collection")})
 				if err != nil {
 					return errors.Wrap(err, "could not open knowledge base")
 				}
@@ -107,26 +136,37 @@ func Fix() *cli.Command {
 				fixOptions = append(fixOptions, wppkg.WithFixKnowledgeBase(kb))
 			}
 
-			fmt.Printf("\n%s\n\n", fmt.Sprintf("Corrections : %q", dir))
+			out.Printf("\n%s\n\n", l.T(locale.FixHeader, dir))
 
-			emit := func(evt agent.Event) error {
-				output := whitepaperui.RenderEvent(evt)
-				if output != "" {
-					fmt.Print(output)
+			emit := out.Emit(func(evt agent.Event) error {
+				if text := whitepaperui.RenderEvent(l, evt); text != "" {
+					fmt.Print(text)
 				}
 				return nil
+			})
+			tracker.EmitTo(emit)
+			defer func() { result.Usage = shared.ReportUsage(tracker, filepath.Join(dir, "usage-fix.json"), emit) }()
+
+			fixResult, err := wppkg.FixWhitePaperInDir(ctx, resilientClient, emit, fixOptions...)
+			if errors.Is(err, usage.ErrBudgetExceeded) {
+				result = output.Result{Files: fixResult.FixedFiles}
+				out.Printf("\n✗ %s\n", l.T(locale.FixOverBudget))
+				out.Printf("  %s\n", l.T(locale.FixesDone, len(fixResult.FixedFiles)))
 			}
-
-			result, err := wppkg.FixWhitePaperInDir(ctx, resilientClient, emit, fixOptions...)
 			if err != nil {
 				return errors.Wrap(err, "failed to fix white paper")
 			}
 
-			fmt.Printf("\n✓ %d fichier(s) corrigé(s)", len(result.FixedFiles))
-			if len(result.SkippedFiles) > 0 {
-				fmt.Printf(", %d ignoré(s)", len(result.SkippedFiles))
+			result = output.Result{
+				Entrypoint: filepath.Join(dir, "index.md"),
+				Files:      fixResult.FixedFiles,
+			}
+
+			out.Printf("\n✓ %s", l.T(locale.FixesDone, len(fixResult.FixedFiles)))
+			if len(fixResult.SkippedFiles) > 0 {
+				out.Printf(", %s", l.T(locale.FixesSkipped, len(fixResult.SkippedFiles)))
 			}
-			fmt.Println()
+			out.Printf("\n")
 
 			return nil
 		},
@@ -0,0 +1,406 @@
+package kb
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bornholm/ghostwriter/internal/command/output"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+// documentRemover is implemented by knowledge bases supporting document removal
+// (see corpusadapter.Adapter).
+type documentRemover interface {
+	RemoveDocument(url string) error
+}
+
+func Root() *cli.Command {
+	return &cli.Command{
+		Name:  "kb",
+		Usage: "Inspect and manage the Corpus knowledge base",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "corpus-storage-path",
+				Value:   ".corpus",
+				Usage:   "Path to the Corpus data directory",
+				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
+			},
+			shared.CollectionFlag(),
+			output.Flag(),
+			shared.LocaleFlag(),
+		},
+		Subcommands: []*cli.Command{
+			List(),
+			Search(),
+			Add(),
+			Remove(),
+			Stats(),
+		},
+	}
+}
+
+func List() *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "List the documents of the knowledge base",
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "kb list")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			kb, err := openKnowledgeBase(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer kb.Close()
+
+			docs := kb.GetAllDocuments()
+			sort.Slice(docs, func(i, j int) bool {
+				if docs[i].Title != docs[j].Title {
+					return docs[i].Title < docs[j].Title
+				}
+				return docs[i].URL < docs[j].URL
+			})
+
+			result = output.Result{Sources: toSources(docs)}
+
+			return printDocuments(cliCtx, out, docs)
+		},
+	}
+}
+
+func Search() *cli.Command {
+	return &cli.Command{
+		Name:      "search",
+		Usage:     "Search the knowledge base",
+		ArgsUsage: "<query>",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    "limit",
+				Value:   10,
+				Aliases: []string{"n"},
+				Usage:   "Maximum number of results",
+			},
+		},
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "kb search")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			query := strings.TrimSpace(strings.Join(cliCtx.Args().Slice(), " "))
+			if query == "" {
+				return errors.New("query is required")
+			}
+
+			kb, err := openKnowledgeBase(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer kb.Close()
+
+			docs, err := kb.Search(query, cliCtx.Int("limit"))
+			if err != nil {
+				return errors.Wrap(err, "failed to search knowledge base")
+			}
+
+			result = output.Result{Sources: toSources(docs)}
+
+			return printDocuments(cliCtx, out, docs)
+		},
+	}
+}
+
+func Add() *cli.Command {
+	return &cli.Command{
+		Name:      "add",
+		Usage:     "Add files (paths or glob patterns) and web pages (URLs) to the knowledge base",
+		ArgsUsage: "<file|glob|url>...",
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "kb add")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			args := cliCtx.Args().Slice()
+			if len(args) == 0 {
+				return errors.New("at least one file, glob pattern or URL is required")
+			}
+
+			webScraper, _, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			docs := make([]article.ResearchDocument, 0, len(args))
+			for _, arg := range args {
+				if isWebURL(arg) {
+					doc, err := article.ScrapeDocument(cliCtx.Context, webScraper, arg)
+					if err != nil {
+						return errors.Wrapf(err, "could not scrape '%s'", arg)
+					}
+					docs = append(docs, doc)
+					continue
+				}
+
+				fileDocs, err := shared.FileDocuments([]string{arg})
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				if len(fileDocs) == 0 {
+					return errors.Errorf("no file matches '%s'", arg)
+				}
+				docs = append(docs, fileDocs...)
+			}
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			kb, err := openKnowledgeBase(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer kb.Close()
+
+			added := make([]article.ResearchDocument, 0, len(docs))
+			for _, doc := range docs {
+				if kb.HasDocument(doc.URL) {
+					out.Printf("- %s\n", l.T(locale.KBAlreadyPresent, doc.URL))
+					continue
+				}
+
+				if err := kb.AddDocument(doc); err != nil {
+					return errors.Wrapf(err, "could not add '%s'", doc.URL)
+				}
+				added = append(added, doc)
+
+				out.Printf("✓ %s (%s)\n", doc.Title, doc.URL)
+			}
+
+			result = output.Result{Sources: toSources(added)}
+
+			out.Printf("\n%s\n", l.T(locale.KBAdded, len(added)))
+
+			return nil
+		},
+	}
+}
+
+func Remove() *cli.Command {
+	return &cli.Command{
+		Name:      "remove",
+		Usage:     "Remove documents from the knowledge base by URL or file path",
+		ArgsUsage: "<url|file>...",
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "kb remove")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			args := cliCtx.Args().Slice()
+			if len(args) == 0 {
+				return errors.New("at least one URL or file path is required")
+			}
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			kb, err := openKnowledgeBase(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer kb.Close()
+
+			remover, ok := kb.(documentRemover)
+			if !ok {
+				return errors.New("the knowledge base does not support document removal")
+			}
+
+			// The removed documents are reported as sources with their URL only
+			removed := make([]article.Source, 0, len(args))
+			for _, arg := range args {
+				u, err := documentURL(arg)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+
+				if err := remover.RemoveDocument(u); err != nil {
+					return errors.Wrapf(err, "could not remove '%s'", arg)
+				}
+				removed = append(removed, article.Source{URL: u})
+				result = output.Result{Sources: removed}
+
+				out.Printf("✓ %s\n", l.T(locale.KBRemoved, u))
+			}
+
+			return nil
+		},
+	}
+}
+
+func Stats() *cli.Command {
+	return &cli.Command{
+		Name:  "stats",
+		Usage: "Print statistics about the knowledge base",
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "kb stats")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			kb, err := openKnowledgeBase(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer kb.Close()
+
+			docs := kb.GetAllDocuments()
+
+			sourceTypes := make(map[string]int)
+			words := 0
+			for _, doc := range docs {
+				sourceTypes[doc.SourceType]++
+				words += len(strings.Fields(doc.Content))
+			}
+
+			result = output.Result{KnowledgeBase: &output.KnowledgeBaseStats{
+				StoragePath: cliCtx.String("corpus-storage-path"),
+				Collection:  collectionName(cliCtx),
+				Documents:   len(docs),
+				Words:       words,
+				SourceTypes: sourceTypes,
+			}}
+
+			types := make([]string, 0, len(sourceTypes))
+			for t := range sourceTypes {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+
+			out.Printf("%s\n", l.T(locale.ResearchKnowledgeBase, cliCtx.String("corpus-storage-path")))
+			out.Printf("  %s\n", l.T(locale.KBStats, len(docs), words))
+			for _, t := range types {
+				out.Printf("  - %s : %d\n", t, sourceTypes[t])
+			}
+
+			return nil
+		},
+	}
+}
+
+func openKnowledgeBase(cliCtx *cli.Context) (article.KnowledgeBase, error) {
+	storagePath := cliCtx.String("corpus-storage-path")
+
+	kb, _, err := shared.BuildKnowledgeBase(cliCtx.Context, storagePath, shared.Collection{Name: collectionName(cliCtx)})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open knowledge base")
+	}
+
+	return kb, nil
+}
+
+// collectionName returns the --collection flag, or the default collection.
+func collectionName(cliCtx *cli.Context) string {
+	if name := cliCtx.String("collection"); name != "" {
+		return name
+	}
+	return shared.DefaultCollection
+}
+
+func printDocuments(cliCtx *cli.Context, out *output.Output, docs []article.ResearchDocument) error {
+	if out.JSONL() {
+		return nil
+	}
+
+	l, err := shared.Locale(cliCtx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(docs) == 0 {
+		fmt.Println(l.T(locale.KBEmpty))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, l.T(locale.KBColumns))
+	for _, doc := range docs {
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\n", doc.Title, doc.SourceType, doc.Relevance, doc.URL)
+	}
+
+	return errors.WithStack(w.Flush())
+}
+
+// toSources returns the listing representation of the documents, without
+// their content.
+func toSources(docs []article.ResearchDocument) []article.Source {
+	sources := make([]article.Source, 0, len(docs))
+	for _, doc := range docs {
+		sources = append(sources, article.Source{
+			URL:        doc.URL,
+			Title:      doc.Title,
+			Keywords:   doc.Keywords,
+			SourceType: doc.SourceType,
+			Relevance:  doc.Relevance,
+		})
+	}
+	return sources
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// documentURL returns the knowledge base URL of the given argument: URLs are
+// used as is, file paths are converted to the file:// URL used when adding them.
+func documentURL(arg string) (string, error) {
+	if u, err := url.Parse(arg); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		return u.String(), nil
+	}
+
+	absPath, err := filepath.Abs(arg)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not retrieve absolute path for file '%s'", arg)
+	}
+
+	return (&url.URL{Scheme: "file", Path: absPath}).String(), nil
+}
@@ -0,0 +1,77 @@
+package llmclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/genai/llm/circuitbreaker"
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned, without calling the provider, while the circuit
+// breaker of a client is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// breakerClient protects a client with a circuit breaker. Unlike the client of
+// the circuitbreaker package, it returns ErrCircuitOpen when the breaker
+// refuses a call, so that the fallback chain tells it apart from a failed call.
+type breakerClient struct {
+	client  llm.Client
+	breaker *circuitbreaker.CircuitBreaker
+}
+
+func newBreakerClient(client llm.Client, maxFailures int, resetTimeout time.Duration) *breakerClient {
+	return &breakerClient{
+		client:  client,
+		breaker: circuitbreaker.NewCircuitBreaker(maxFailures, resetTimeout),
+	}
+}
+
+// ChatCompletion implements llm.Client.
+func (c *breakerClient) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	return protect(c.breaker, func() (llm.ChatCompletionResponse, error) {
+		return c.client.ChatCompletion(ctx, funcs...)
+	})
+}
+
+// ChatCompletionStream implements llm.Client.
+func (c *breakerClient) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	return protect(c.breaker, func() (<-chan llm.StreamChunk, error) {
+		return c.client.ChatCompletionStream(ctx, funcs...)
+	})
+}
+
+// Embeddings implements llm.Client.
+func (c *breakerClient) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	return protect(c.breaker, func() (llm.EmbeddingsResponse, error) {
+		return c.client.Embeddings(ctx, inputs, funcs...)
+	})
+}
+
+// protect runs call through breaker. The breaker does not type the error of
+// the calls it refuses: they are recognized as not being run.
+func protect[T any](breaker *circuitbreaker.CircuitBreaker, call func() (T, error)) (T, error) {
+	var (
+		res    T
+		err    error
+		called bool
+	)
+
+	_ = breaker.Execute(func() error {
+		called = true
+		res, err = call()
+		return err
+	})
+
+	if !called {
+		return res, errors.WithStack(ErrCircuitOpen)
+	}
+	if err != nil {
+		return res, errors.WithStack(err)
+	}
+
+	return res, nil
+}
+
+var _ llm.Client = &breakerClient{}
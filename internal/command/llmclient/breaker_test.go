@@ -0,0 +1,36 @@
+package llmclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestBreakerClient(t *testing.T) {
+	failing := &fakeClient{name: "failing", err: errors.New("provider down")}
+	client := newBreakerClient(failing, 2, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ChatCompletion(context.Background())
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected the error of the provider, got: %v", err)
+		}
+	}
+
+	_, err := client.ChatCompletion(context.Background())
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got: %v", err)
+	}
+	if !isUnavailable(err) {
+		t.Error("expected an open circuit breaker to make the client unavailable")
+	}
+	if failing.calls != 2 {
+		t.Errorf("expected the open circuit breaker not to call the provider, got %d calls", failing.calls)
+	}
+
+	if isUnavailable(errors.New("circuit breaker is open")) {
+		t.Error("expected an error only matching the message not to make the client unavailable")
+	}
+}
@@ -0,0 +1,27 @@
+package llmclient
+
+import (
+	"context"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/genai/llm/hook"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	"github.com/pkg/errors"
+)
+
+// newBudgetClient refuses the calls made once the budget of the usage.Tracker
+// of the context is used up. It must wrap the circuit breaker: the refused
+// calls would otherwise open it and hide usage.ErrBudgetExceeded.
+func newBudgetClient(client llm.Client) llm.Client {
+	checkBudget := func(ctx context.Context, funcs []llm.ChatCompletionOptionFunc) (context.Context, []llm.ChatCompletionOptionFunc, error) {
+		if err := usage.ContextTracker(ctx).CheckBudget(); err != nil {
+			return ctx, funcs, errors.WithStack(err)
+		}
+		return ctx, funcs, nil
+	}
+
+	return hook.NewClient(client,
+		hook.WithBeforeChatCompletionFunc(checkBudget),
+		hook.WithBeforeChatCompletionStreamFunc(checkBudget),
+	)
+}
@@ -0,0 +1,71 @@
+package llmclient
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/pkg/cassette"
+	"github.com/pkg/errors"
+)
+
+// Env vars of the response cache, set by the --llm-cache, --llm-cache-ttl and
+// --no-cache flags.
+const (
+	CacheEnvVar    = "GHOSTWRITER_LLM_CACHE"
+	CacheTTLEnvVar = "GHOSTWRITER_LLM_CACHE_TTL"
+	NoCacheEnvVar  = "GHOSTWRITER_NO_CACHE"
+)
+
+// DefaultCacheTTL is the lifetime of the cached responses when
+// GHOSTWRITER_LLM_CACHE_TTL is not set.
+const DefaultCacheTTL = 7 * 24 * time.Hour
+
+// CacheSettings returns the directory of the response cache and the lifetime
+// of its entries. The directory is empty when the cache is disabled: it is
+// opt-in, turned off by GHOSTWRITER_NO_CACHE, and never used along with a
+// cassette, whose recording would miss the cached calls.
+func CacheSettings() (dir string, ttl time.Duration, err error) {
+	dir = os.Getenv(CacheEnvVar)
+	if dir == "" || UsesCassette() {
+		return "", 0, nil
+	}
+
+	if raw := os.Getenv(NoCacheEnvVar); raw != "" {
+		noCache, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "", 0, errors.Wrapf(err, "invalid %s", NoCacheEnvVar)
+		}
+		if noCache {
+			return "", 0, nil
+		}
+	}
+
+	ttl = DefaultCacheTTL
+	if raw := os.Getenv(CacheTTLEnvVar); raw != "" {
+		ttl, err = time.ParseDuration(raw)
+		if err != nil {
+			return "", 0, errors.Wrapf(err, "invalid %s", CacheTTLEnvVar)
+		}
+	}
+
+	return dir, ttl, nil
+}
+
+// newCacheClient serves the chat completions already sent to the model
+// returned by model from the response cache, when it is enabled.
+func newCacheClient(client llm.Client, model func(ctx context.Context) string) llm.Client {
+	dir, ttl, err := CacheSettings()
+	if err != nil {
+		slog.Warn("llm response cache disabled", slog.Any("error", err))
+		return client
+	}
+	if dir == "" {
+		return client
+	}
+
+	return cassette.NewCache(client, dir, ttl, model)
+}
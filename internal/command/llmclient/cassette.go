@@ -0,0 +1,80 @@
+package llmclient
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/pkg/cassette"
+	"github.com/pkg/errors"
+)
+
+// Env vars of the cassette files, set by the --llm-record and --llm-replay
+// flags.
+const (
+	RecordEnvVar = "GHOSTWRITER_LLM_RECORD"
+	ReplayEnvVar = "GHOSTWRITER_LLM_REPLAY"
+)
+
+// CassettePaths returns the cassette the LLM calls are recorded to and the
+// cassette they are replayed from. At most one of them is set.
+func CassettePaths() (record string, replay string, err error) {
+	record, replay = os.Getenv(RecordEnvVar), os.Getenv(ReplayEnvVar)
+	if record != "" && replay != "" {
+		return "", "", errors.New("--llm-record and --llm-replay cannot be used together")
+	}
+	return record, replay, nil
+}
+
+// UsesCassette returns true when the LLM calls are recorded or replayed.
+func UsesCassette() bool {
+	return os.Getenv(RecordEnvVar) != "" || os.Getenv(ReplayEnvVar) != ""
+}
+
+var (
+	recorders   = make(map[string]*cassette.Recorder)
+	recordersMu sync.Mutex
+)
+
+// newRecorder returns a recorder of client to the cassette at path. The
+// clients of a run, e.g. the main client and the client of the knowledge
+// base, record to the same cassette: only the first one truncates it.
+func newRecorder(client llm.Client, path string) (llm.Client, error) {
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+
+	if recorder, exists := recorders[path]; exists {
+		return recorder.Wrap(client), nil
+	}
+
+	recorder, err := cassette.NewRecorder(client, path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create llm cassette")
+	}
+
+	recorders[path] = recorder
+
+	return recorder, nil
+}
+
+// newReplayClient serves the chat completions recorded in the cassette at
+// path. The role router is kept, every role being served by the cassette, so
+// that the role temperatures are applied as when recording. Only the usage
+// and budget middleware are kept: replayed calls neither fail transiently nor
+// need to be rate limited.
+func newReplayClient(ctx context.Context, path string) (llm.Client, error) {
+	player, err := cassette.NewPlayer(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load llm cassette")
+	}
+
+	router, err := newRoleRouter(ctx, player, ModelName(), func(context.Context, string) (llm.Client, error) {
+		return player, nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return router.withTemperatures(newBudgetClient(newUsageClient(router, router.model))), nil
+}
@@ -2,47 +2,180 @@ package llmclient
 
 import (
 	"context"
-	"time"
+	"log/slog"
+	"os"
+	"strings"
 
 	"github.com/bornholm/genai/llm"
-	"github.com/bornholm/genai/llm/circuitbreaker"
 	"github.com/bornholm/genai/llm/hook"
 	"github.com/bornholm/genai/llm/provider"
 	"github.com/bornholm/genai/llm/provider/env"
 	"github.com/bornholm/genai/llm/provider/openrouter"
 	"github.com/bornholm/genai/llm/ratelimit"
 	"github.com/bornholm/genai/llm/retry"
+	"github.com/bornholm/ghostwriter/pkg/cassette"
 	"github.com/pkg/errors"
 )
 
 // NewClient creates a resilient LLM client with retry, rate limiting and circuit breaker.
+// The chat completions of the agent roles configured through GHOSTWRITER_<ROLE>_*
+// env vars are sent to their dedicated client, with its own middleware.
+// The chat completions are recorded to the cassette of GHOSTWRITER_LLM_RECORD,
+// or replayed from the cassette of GHOSTWRITER_LLM_REPLAY without any provider.
+// The responses are cached in the directory of GHOSTWRITER_LLM_CACHE, if set.
+// The clients of GHOSTWRITER_FALLBACK_<N>_* env vars take over, in order, when
+// the main client is unavailable.
 func NewClient(ctx context.Context) (llm.Client, error) {
-	baseClient, err := provider.Create(ctx, env.With("GHOSTWRITER_", ".env"))
+	record, replay, err := CassettePaths()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if replay != "" {
+		return newReplayClient(ctx, replay)
+	}
+
+	resilience, err := ResilienceSettings()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	logSettings(ctx, resilience)
+
+	baseClient, err := createProviderClient(ctx, "GHOSTWRITER_")
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create llm client")
 	}
 
-	return Wrap(baseClient), nil
+	router, err := newRoleRouter(ctx, baseClient, ModelName(), createProviderClient)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Every provider gets its own retry, rate limiting and circuit breaker so
+	// that a failing role does not block the others
+	err = router.wrapClients(func(client llm.Client, model string) (llm.Client, error) {
+		if record != "" {
+			// Record below the role temperatures so that they are part of the
+			// recorded requests, and below the retry middleware so that only
+			// successful calls are recorded
+			var err error
+			client, err = newRecorder(client, record)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		return newResilientClient(client, func(context.Context) string { return model }, resilience), nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var client llm.Client = router
+	if record == "" {
+		client, err = newFallbackChain(ctx, client, router.model, resilience)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		// The calls served by a fallback client would not be recorded
+		slog.DebugContext(ctx, "fallback llm clients disabled while recording")
+	}
+
+	return router.withTemperatures(wrap(client, router.model)), nil
+}
+
+func createProviderClient(ctx context.Context, prefix string) (llm.Client, error) {
+	client, err := provider.Create(ctx, env.With(prefix, ".env"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return client, nil
+}
+
+// ModelName returns a "provider/model" identifier of the main chat completion
+// client, as configured through GHOSTWRITER_* env vars. It is only meaningful
+// once NewClient has loaded the .env file.
+func ModelName() string {
+	return PrefixedModelName("GHOSTWRITER_")
+}
+
+// PrefixedModelName returns the "provider/model" identifier of a client
+// configured through env vars with the given prefix, e.g. "GHOSTWRITER_CORPUS_".
+func PrefixedModelName(prefix string) string {
+	providerName := os.Getenv(prefix + "CHAT_COMPLETION_PROVIDER")
+	if providerName == "" {
+		return ""
+	}
+	key := strings.ReplaceAll(strings.ToUpper(providerName), "-", "_")
+	model := os.Getenv(prefix + "CHAT_COMPLETION_" + key + "_MODEL")
+	return providerName + "/" + model
 }
 
-// Wrap adds retry, rate-limiting and circuit-breaker middleware to an existing client.
+// Wrap adds usage accounting, retry, rate-limiting, circuit-breaker, budget and response cache middleware to an existing client.
 // Use this to apply the same resilience stack to secondary clients (e.g. the Corpus LLM client).
-func Wrap(baseClient llm.Client) llm.Client {
+// model is the "provider/model" identifier under which the usage is recorded.
+// Its calls are recorded to the cassette of the main client, or served from
+// the cassette being replayed, baseClient being then unused and possibly nil.
+func Wrap(baseClient llm.Client, model string) (llm.Client, error) {
+	record, replay, err := CassettePaths()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	modelFunc := func(context.Context) string { return model }
+
+	if replay != "" {
+		player, err := cassette.NewPlayer(replay)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load llm cassette")
+		}
+		return newBudgetClient(newUsageClient(player, modelFunc)), nil
+	}
+
+	resilience, err := ResilienceSettings()
+	if err != nil {
+		slog.Warn("invalid llm resilience settings, using the defaults", slog.Any("error", err))
+		resilience = DefaultResilience
+	}
+
+	if record != "" {
+		baseClient, err = newRecorder(baseClient, record)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return wrap(newResilientClient(baseClient, modelFunc, resilience), modelFunc), nil
+}
+
+// wrap adds the budget and response cache middleware to a resilient client,
+// with the model identifier resolved for each call.
+func wrap(client llm.Client, model func(ctx context.Context) string) llm.Client {
+	// Cached responses are served above the budget and the rate limiter: they
+	// consume neither tokens nor quota
+	return newCacheClient(newBudgetClient(client), model)
+}
+
+// newResilientClient adds usage accounting, retry, rate-limiting and
+// circuit-breaker middleware to baseClient.
+func newResilientClient(baseClient llm.Client, model func(ctx context.Context) string, r Resilience) llm.Client {
 	// Force middle-out transform for OpenRouter
 	middleOutClient := hook.NewClient(baseClient, hook.WithBeforeChatCompletionFunc(func(ctx context.Context, funcs []llm.ChatCompletionOptionFunc) (context.Context, []llm.ChatCompletionOptionFunc, error) {
 		ctx = openrouter.WithTransforms(ctx, []string{openrouter.TransformMiddleOut})
 		return ctx, funcs, nil
 	}))
 
-	// 6 retries with 5s base delay (5s → 10s → 20s → 40s → 80s → 160s)
-	retryClient := retry.NewClient(middleOutClient, 5*time.Second, 6)
+	// Usage is recorded below the retry middleware: every attempt is billed
+	usageClient := newUsageClient(middleOutClient, model)
+
+	// The retry delay doubles at each retry
+	retryClient := retry.NewClient(usageClient, r.RetryDelay, r.Retries)
 
-	// Chat completion: 30 req/min — Embeddings: 60 req/min
 	rateLimitedClient := ratelimit.NewClient(retryClient,
-		ratelimit.WithChatLimit(time.Minute/30, 1),
-		ratelimit.WithEmbeddingsLimit(time.Minute/60, 1),
+		ratelimit.WithChatLimit(interval(r.ChatRate), r.Burst),
+		ratelimit.WithEmbeddingsLimit(interval(r.EmbeddingsRate), r.Burst),
 	)
 
-	// Circuit breaker: 5 failures max, 5s reset
-	return circuitbreaker.NewClient(rateLimitedClient, 5, 5*time.Second)
+	return newBreakerClient(rateLimitedClient, r.BreakerThreshold, r.BreakerReset)
 }
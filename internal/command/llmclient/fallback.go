@@ -0,0 +1,156 @@
+package llmclient
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	"github.com/pkg/errors"
+)
+
+// FallbackPrefix returns the prefix of the env vars configuring the nth
+// fallback client, starting at 1, e.g. "GHOSTWRITER_FALLBACK_1_".
+func FallbackPrefix(n int) string {
+	return fmt.Sprintf("GHOSTWRITER_FALLBACK_%d_", n)
+}
+
+type fallbackLink struct {
+	client llm.Client
+	model  func(ctx context.Context) string
+}
+
+// fallbackClient sends the calls to the first client of its chain able to
+// serve them: a client is skipped when it is unavailable, see isUnavailable.
+// Errors returned by a stream once opened are not recovered.
+type fallbackClient struct {
+	links []fallbackLink
+
+	mu sync.Mutex
+	// active is the index of the link that served the last call.
+	active int
+}
+
+// newFallbackChain appends to primary the clients configured through the
+// GHOSTWRITER_FALLBACK_<N>_* env vars, from N = 1 to the first missing
+// provider. Every fallback client gets its own retry, rate limiting and
+// circuit breaker, configured by r. primary is returned as is when no fallback
+// is configured.
+func newFallbackChain(ctx context.Context, primary llm.Client, model func(ctx context.Context) string, r Resilience) (llm.Client, error) {
+	links := []fallbackLink{{client: primary, model: model}}
+
+	for n := 1; os.Getenv(FallbackPrefix(n)+"CHAT_COMPLETION_PROVIDER") != ""; n++ {
+		prefix := FallbackPrefix(n)
+
+		client, err := createProviderClient(ctx, prefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create fallback llm client %d", n)
+		}
+
+		fallbackModel := PrefixedModelName(prefix)
+		modelFunc := func(context.Context) string { return fallbackModel }
+
+		links = append(links, fallbackLink{client: newResilientClient(client, modelFunc, r), model: modelFunc})
+
+		slog.DebugContext(ctx, "fallback llm client", slog.Int("rank", n), slog.String("model", fallbackModel))
+	}
+
+	if len(links) == 1 {
+		return primary, nil
+	}
+
+	return &fallbackClient{links: links}, nil
+}
+
+// ChatCompletion implements llm.Client.
+func (c *fallbackClient) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	return fallback(ctx, c, func(client llm.Client) (llm.ChatCompletionResponse, error) {
+		return client.ChatCompletion(ctx, funcs...)
+	})
+}
+
+// ChatCompletionStream implements llm.Client.
+func (c *fallbackClient) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	return fallback(ctx, c, func(client llm.Client) (<-chan llm.StreamChunk, error) {
+		return client.ChatCompletionStream(ctx, funcs...)
+	})
+}
+
+// Embeddings implements llm.Client.
+func (c *fallbackClient) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	return fallback(ctx, c, func(client llm.Client) (llm.EmbeddingsResponse, error) {
+		return client.Embeddings(ctx, inputs, funcs...)
+	})
+}
+
+// fallback calls the links of c in order until one of them is available.
+func fallback[T any](ctx context.Context, c *fallbackClient, call func(client llm.Client) (T, error)) (T, error) {
+	var reason error
+	for i, link := range c.links {
+		res, err := call(link.client)
+		if err == nil {
+			c.served(ctx, i, reason)
+			return res, nil
+		}
+
+		if i == len(c.links)-1 || ctx.Err() != nil || !isUnavailable(err) {
+			return res, errors.WithStack(err)
+		}
+
+		reason = err
+	}
+
+	panic("unreachable")
+}
+
+// served records that the call of ctx was served by the link at index i, and
+// reports the switch to a fallback link.
+func (c *fallbackClient) served(ctx context.Context, i int, reason error) {
+	c.mu.Lock()
+	previous := c.active
+	c.active = i
+	c.mu.Unlock()
+
+	switch {
+	case i < previous:
+		slog.InfoContext(ctx, "llm client available again", slog.String("model", c.links[i].model(ctx)))
+
+	case i > previous:
+		from, to := c.links[previous].model(ctx), c.links[i].model(ctx)
+
+		slog.WarnContext(ctx, "llm client unavailable, switching to fallback", slog.String("from", from), slog.String("to", to), slog.Any("error", reason))
+
+		usage.ContextTracker(ctx).RecordFallback(usage.Fallback{
+			Time:    time.Now(),
+			Role:    string(article.ContextAgentRole(ctx, "")),
+			Chapter: usage.ContextChapter(ctx),
+			From:    from,
+			To:      to,
+			Reason:  reason.Error(),
+		})
+	}
+}
+
+// isUnavailable reports whether err means that a client cannot serve calls for
+// now: it is unavailable or unreachable, it still fails once its retries are
+// exhausted, or its circuit breaker is open.
+func isUnavailable(err error) bool {
+	if errors.Is(err, llm.ErrUnavailable) || llm.IsRetryable(err) {
+		return true
+	}
+
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+var _ llm.Client = &fallbackClient{}
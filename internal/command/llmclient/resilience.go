@@ -0,0 +1,156 @@
+package llmclient
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Env vars of the resilience settings, set by the --llm-* flags of the same
+// name or the llm.resilience section of a profile.
+const (
+	RetriesEnvVar          = "GHOSTWRITER_LLM_RETRIES"
+	RetryDelayEnvVar       = "GHOSTWRITER_LLM_RETRY_DELAY"
+	ChatRateEnvVar         = "GHOSTWRITER_LLM_CHAT_RATE"
+	EmbeddingsRateEnvVar   = "GHOSTWRITER_LLM_EMBEDDINGS_RATE"
+	BurstEnvVar            = "GHOSTWRITER_LLM_BURST"
+	BreakerThresholdEnvVar = "GHOSTWRITER_LLM_BREAKER_THRESHOLD"
+	BreakerResetEnvVar     = "GHOSTWRITER_LLM_BREAKER_RESET"
+)
+
+// Resilience configures the retry, rate limiting and circuit breaker
+// middleware of the LLM clients.
+type Resilience struct {
+	// Retries is the number of retries of a call failing with a rate limit or
+	// a server error.
+	Retries int
+	// RetryDelay is the delay before the first retry, doubled at each retry.
+	RetryDelay time.Duration
+	// ChatRate and EmbeddingsRate are the maximum number of calls per minute,
+	// 0 for no limit.
+	ChatRate       float64
+	EmbeddingsRate float64
+	// Burst is the number of calls that may be sent at once within the rates.
+	Burst int
+	// BreakerThreshold is the number of consecutive failures opening the
+	// circuit breaker, which refuses the calls until BreakerReset has elapsed.
+	BreakerThreshold int
+	BreakerReset     time.Duration
+}
+
+// DefaultResilience suits a paid OpenRouter key.
+var DefaultResilience = Resilience{
+	Retries:          6, // 5s → 10s → 20s → 40s → 80s → 160s
+	RetryDelay:       5 * time.Second,
+	ChatRate:         30,
+	EmbeddingsRate:   60,
+	Burst:            1,
+	BreakerThreshold: 5,
+	BreakerReset:     5 * time.Second,
+}
+
+// ResilienceSettings returns DefaultResilience overridden by the env vars
+// that are set.
+func ResilienceSettings() (Resilience, error) {
+	r := DefaultResilience
+
+	ints := map[string]*int{
+		RetriesEnvVar:          &r.Retries,
+		BurstEnvVar:            &r.Burst,
+		BreakerThresholdEnvVar: &r.BreakerThreshold,
+	}
+	for envVar, dst := range ints {
+		if raw := os.Getenv(envVar); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				return Resilience{}, errors.Wrapf(err, "invalid %s", envVar)
+			}
+			*dst = value
+		}
+	}
+
+	rates := map[string]*float64{
+		ChatRateEnvVar:       &r.ChatRate,
+		EmbeddingsRateEnvVar: &r.EmbeddingsRate,
+	}
+	for envVar, dst := range rates {
+		if raw := os.Getenv(envVar); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return Resilience{}, errors.Wrapf(err, "invalid %s", envVar)
+			}
+			*dst = value
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		RetryDelayEnvVar:   &r.RetryDelay,
+		BreakerResetEnvVar: &r.BreakerReset,
+	}
+	for envVar, dst := range durations {
+		if raw := os.Getenv(envVar); raw != "" {
+			value, err := time.ParseDuration(raw)
+			if err != nil {
+				return Resilience{}, errors.Wrapf(err, "invalid %s", envVar)
+			}
+			*dst = value
+		}
+	}
+
+	if err := r.validate(); err != nil {
+		return Resilience{}, errors.WithStack(err)
+	}
+
+	return r, nil
+}
+
+func (r Resilience) validate() error {
+	switch {
+	case r.Retries < 0:
+		return errors.New("the number of llm retries must not be negative")
+	case r.RetryDelay < 0:
+		return errors.New("the llm retry delay must not be negative")
+	case r.ChatRate < 0 || r.EmbeddingsRate < 0:
+		return errors.New("the llm rates must not be negative")
+	case r.Burst < 1:
+		return errors.New("the llm burst must be at least 1")
+	case r.BreakerThreshold < 1:
+		return errors.New("the llm circuit breaker threshold must be at least 1")
+	case r.BreakerReset < 0:
+		return errors.New("the llm circuit breaker reset must not be negative")
+	}
+	return nil
+}
+
+// interval returns the minimum interval between two calls at rate calls per
+// minute, 0 for no limit.
+func interval(rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Minute) / rate)
+}
+
+// logSettings logs the effective settings of the LLM clients, and the
+// deadline of the run if any.
+func logSettings(ctx context.Context, r Resilience) {
+	attrs := []any{
+		slog.Int("retries", r.Retries),
+		slog.Duration("retry_delay", r.RetryDelay),
+		slog.Float64("chat_rate", r.ChatRate),
+		slog.Float64("embeddings_rate", r.EmbeddingsRate),
+		slog.Int("burst", r.Burst),
+		slog.Int("breaker_threshold", r.BreakerThreshold),
+		slog.Duration("breaker_reset", r.BreakerReset),
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		attrs = append(attrs, slog.Duration("timeout", time.Until(deadline).Round(time.Second)))
+	}
+
+	slog.InfoContext(ctx, "llm client settings", attrs...)
+}
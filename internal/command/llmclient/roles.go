@@ -0,0 +1,210 @@
+package llmclient
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/genai/llm/hook"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/pkg/errors"
+)
+
+// Roles are the agent roles whose chat completions can be served by a
+// dedicated client, configured through the env vars of their RolePrefix.
+var Roles = []article.AgentRole{
+	article.RoleResearcher,
+	article.RolePlanner,
+	article.RoleWriter,
+	article.RoleEditor,
+	article.RoleCoherenceEditor,
+	article.RoleCitationLinker,
+	article.RoleDiagramInserter,
+}
+
+// RolePrefix returns the prefix of the env vars configuring the client of a
+// role, e.g. "GHOSTWRITER_WRITER_" or "GHOSTWRITER_CITATION_LINKER_".
+func RolePrefix(role article.AgentRole) string {
+	return "GHOSTWRITER_" + strings.ToUpper(string(role)) + "_"
+}
+
+// PrefixedTemperature returns the temperature set with the TEMPERATURE env
+// var of prefix, or nil if it is not set.
+func PrefixedTemperature(prefix string) (*float64, error) {
+	raw := os.Getenv(prefix + "TEMPERATURE")
+	if raw == "" {
+		return nil, nil
+	}
+
+	temperature, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %sTEMPERATURE", prefix)
+	}
+
+	return &temperature, nil
+}
+
+// WithTemperature forces the temperature of the chat completions of client.
+// A nil temperature leaves client unchanged.
+func WithTemperature(client llm.Client, temperature *float64) llm.Client {
+	if temperature == nil {
+		return client
+	}
+
+	force := func(ctx context.Context, funcs []llm.ChatCompletionOptionFunc) (context.Context, []llm.ChatCompletionOptionFunc, error) {
+		return ctx, append(funcs, llm.WithTemperature(*temperature)), nil
+	}
+
+	return hook.NewClient(client,
+		hook.WithBeforeChatCompletionFunc(force),
+		hook.WithBeforeChatCompletionStreamFunc(force),
+	)
+}
+
+type roleClient struct {
+	client      llm.Client
+	model       string
+	temperature *float64
+	// dedicated is true when the role has its own provider, false when it
+	// only overrides the temperature of the main client.
+	dedicated bool
+}
+
+// roleRouter sends the chat completions to the client of the agent role of
+// the context, or to the main client. Embeddings always use the main client.
+// The role temperatures are applied by withTemperatures, above the middleware
+// stack, so that the recorded and replayed requests carry them. The
+// middleware of each client is added by wrapClients, below the router, so that
+// the roles served by another provider have their own rate limit and circuit
+// breaker.
+type roleRouter struct {
+	main  roleClient
+	roles map[article.AgentRole]roleClient
+}
+
+// newRoleRouter reads the GHOSTWRITER_<ROLE>_* env vars of every role. A role
+// may set a provider and model (GHOSTWRITER_<ROLE>_CHAT_COMPLETION_*), a
+// temperature (GHOSTWRITER_<ROLE>_TEMPERATURE) or both; unset settings fall
+// back to the main client. create returns the client of a provider configured
+// with the env vars of a prefix.
+func newRoleRouter(ctx context.Context, main llm.Client, mainModel string, create func(ctx context.Context, prefix string) (llm.Client, error)) (*roleRouter, error) {
+	router := &roleRouter{
+		main:  roleClient{client: main, model: mainModel},
+		roles: make(map[article.AgentRole]roleClient),
+	}
+
+	for _, role := range Roles {
+		prefix := RolePrefix(role)
+
+		temperature, err := PrefixedTemperature(prefix)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		hasProvider := os.Getenv(prefix+"CHAT_COMPLETION_PROVIDER") != ""
+		if !hasProvider && temperature == nil {
+			continue
+		}
+
+		rc := router.main
+		if hasProvider {
+			client, err := create(ctx, prefix)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to create llm client of role %q", role)
+			}
+			rc = roleClient{client: client, model: PrefixedModelName(prefix), dedicated: true}
+		}
+		rc.temperature = temperature
+
+		slog.DebugContext(ctx, "dedicated llm client", slog.String("role", string(role)), slog.String("model", rc.model), slog.Any("temperature", temperature))
+
+		router.roles[role] = rc
+	}
+
+	return router, nil
+}
+
+// wrapClients replaces the main client and the dedicated clients of the roles
+// with the client returned by wrap for each of them. The roles without a
+// dedicated client keep sharing the main client.
+func (r *roleRouter) wrapClients(wrap func(client llm.Client, model string) (llm.Client, error)) error {
+	main, err := wrap(r.main.client, r.main.model)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.main.client = main
+
+	for role, rc := range r.roles {
+		if rc.dedicated {
+			rc.client, err = wrap(rc.client, rc.model)
+			if err != nil {
+				return errors.Wrapf(err, "failed to wrap llm client of role %q", role)
+			}
+		} else {
+			rc.client = main
+		}
+		r.roles[role] = rc
+	}
+
+	return nil
+}
+
+func (r *roleRouter) resolve(ctx context.Context) roleClient {
+	if rc, exists := r.roles[article.ContextAgentRole(ctx, "")]; exists {
+		return rc
+	}
+	return r.main
+}
+
+// model returns the "provider/model" identifier of the client serving ctx.
+func (r *roleRouter) model(ctx context.Context) string {
+	return r.resolve(ctx).model
+}
+
+// withTemperatures forces the temperature of the role of the context on the
+// chat completions of client.
+func (r *roleRouter) withTemperatures(client llm.Client) llm.Client {
+	force := func(ctx context.Context, funcs []llm.ChatCompletionOptionFunc) (context.Context, []llm.ChatCompletionOptionFunc, error) {
+		if temperature := r.resolve(ctx).temperature; temperature != nil {
+			funcs = append(funcs, llm.WithTemperature(*temperature))
+		}
+		return ctx, funcs, nil
+	}
+
+	return hook.NewClient(client,
+		hook.WithBeforeChatCompletionFunc(force),
+		hook.WithBeforeChatCompletionStreamFunc(force),
+	)
+}
+
+// ChatCompletion implements llm.Client.
+func (r *roleRouter) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	res, err := r.resolve(ctx).client.ChatCompletion(ctx, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+// ChatCompletionStream implements llm.Client.
+func (r *roleRouter) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	stream, err := r.resolve(ctx).client.ChatCompletionStream(ctx, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return stream, nil
+}
+
+// Embeddings implements llm.Client.
+func (r *roleRouter) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	res, err := r.main.client.Embeddings(ctx, inputs, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+var _ llm.Client = &roleRouter{}
@@ -0,0 +1,183 @@
+package llmclient
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/pkg/errors"
+)
+
+// fakeClient answers the chat completions with its name, or fails with err.
+type fakeClient struct {
+	name string
+	err  error
+
+	mu           sync.Mutex
+	calls        int
+	embeddings   int
+	temperatures []float64
+}
+
+func (c *fakeClient) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	opts := llm.NewChatCompletionOptions(funcs...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	c.temperatures = append(c.temperatures, opts.Temperature)
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return llm.NewChatCompletionResponse(llm.NewMessage(llm.RoleAssistant, c.name), llm.NewChatCompletionUsage(1, 1, 2)), nil
+}
+
+func (c *fakeClient) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeClient) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.embeddings++
+
+	return nil, nil
+}
+
+func (c *fakeClient) lastTemperature() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.temperatures[len(c.temperatures)-1]
+}
+
+// newTestRouter returns a router whose writer has a dedicated client and
+// whose editor only overrides the temperature of the main client.
+func newTestRouter(t *testing.T, main *fakeClient, writer *fakeClient) *roleRouter {
+	t.Helper()
+
+	t.Setenv("GHOSTWRITER_WRITER_CHAT_COMPLETION_PROVIDER", "fake")
+	t.Setenv("GHOSTWRITER_WRITER_CHAT_COMPLETION_FAKE_MODEL", "writer-model")
+	t.Setenv("GHOSTWRITER_WRITER_TEMPERATURE", "0.9")
+	t.Setenv("GHOSTWRITER_EDITOR_TEMPERATURE", "0.2")
+
+	router, err := newRoleRouter(context.Background(), main, "main/model", func(ctx context.Context, prefix string) (llm.Client, error) {
+		if prefix != RolePrefix(article.RoleWriter) {
+			return nil, errors.Errorf("unexpected prefix %q", prefix)
+		}
+		return writer, nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	return router
+}
+
+func roleContext(role article.AgentRole) context.Context {
+	return article.WithContextAgentRole(context.Background(), role)
+}
+
+func TestRoleRouter(t *testing.T) {
+	main := &fakeClient{name: "main"}
+	writer := &fakeClient{name: "writer"}
+
+	router := newTestRouter(t, main, writer)
+	client := router.withTemperatures(router)
+
+	testCases := []struct {
+		role        article.AgentRole
+		client      string
+		model       string
+		temperature float64
+	}{
+		{role: article.RoleWriter, client: "writer", model: "fake/writer-model", temperature: 0.9},
+		{role: article.RoleEditor, client: "main", model: "main/model", temperature: 0.2},
+		{role: article.RolePlanner, client: "main", model: "main/model", temperature: 0.5},
+		{role: "", client: "main", model: "main/model", temperature: 0.5},
+	}
+
+	for _, tc := range testCases {
+		ctx := roleContext(tc.role)
+
+		res, err := client.ChatCompletion(ctx, llm.WithTemperature(0.5))
+		if err != nil {
+			t.Fatalf("role %q: expected no error, got: %+v", tc.role, err)
+		}
+
+		if got := res.Message().Content(); got != tc.client {
+			t.Errorf("role %q: expected the %s client, got %s", tc.role, tc.client, got)
+		}
+		if got := router.model(ctx); got != tc.model {
+			t.Errorf("role %q: expected model %q, got %q", tc.role, tc.model, got)
+		}
+
+		served := main
+		if tc.client == "writer" {
+			served = writer
+		}
+		if got := served.lastTemperature(); got != tc.temperature {
+			t.Errorf("role %q: expected temperature %v, got %v", tc.role, tc.temperature, got)
+		}
+	}
+
+	if _, err := client.Embeddings(roleContext(article.RoleWriter), []string{"solar"}); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+	if main.embeddings != 1 || writer.embeddings != 0 {
+		t.Errorf("expected the embeddings to be served by the main client, got %d and %d calls", main.embeddings, writer.embeddings)
+	}
+}
+
+func TestRoleRouterSeparateCircuitBreakers(t *testing.T) {
+	main := &fakeClient{name: "main"}
+	writer := &fakeClient{name: "writer", err: errors.New("provider down")}
+
+	router := newTestRouter(t, main, writer)
+
+	resilience := Resilience{
+		RetryDelay:       time.Millisecond,
+		Burst:            1,
+		BreakerThreshold: 1,
+		BreakerReset:     time.Minute,
+	}
+
+	wrapped := make([]string, 0)
+	err := router.wrapClients(func(client llm.Client, model string) (llm.Client, error) {
+		wrapped = append(wrapped, model)
+		return newResilientClient(client, func(context.Context) string { return model }, resilience), nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	if len(wrapped) != 2 {
+		t.Errorf("expected the main and the writer clients to be wrapped, got %v", wrapped)
+	}
+
+	// The first failure opens the circuit breaker of the writer
+	for i := 0; i < 3; i++ {
+		if _, err := router.ChatCompletion(roleContext(article.RoleWriter)); err == nil {
+			t.Fatal("expected the writer to fail")
+		}
+	}
+	if writer.calls != 1 {
+		t.Errorf("expected the open circuit breaker to refuse the calls, got %d calls", writer.calls)
+	}
+
+	// The other roles are still served by the main client
+	for _, role := range []article.AgentRole{article.RoleEditor, article.RolePlanner} {
+		if _, err := router.ChatCompletion(roleContext(role)); err != nil {
+			t.Errorf("role %q: expected no error, got: %+v", role, err)
+		}
+	}
+	if main.calls != 2 {
+		t.Errorf("expected 2 calls to the main client, got %d", main.calls)
+	}
+}
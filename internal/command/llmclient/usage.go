@@ -0,0 +1,63 @@
+package llmclient
+
+import (
+	"context"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/genai/llm/hook"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+)
+
+// newUsageClient records the tokens of every chat completion in the
+// usage.Tracker of the context, tagged with the agent role and the chapter of
+// the context, and with the model returned by model.
+func newUsageClient(client llm.Client, model func(ctx context.Context) string) llm.Client {
+	return hook.NewClient(client,
+		hook.WithAfterChatCompletionFunc(func(ctx context.Context, funcs []llm.ChatCompletionOptionFunc, res llm.ChatCompletionResponse) (llm.ChatCompletionResponse, error) {
+			recordUsage(ctx, model(ctx), res.Usage())
+			return res, nil
+		}),
+		hook.WithAfterChatCompletionStreamFunc(func(ctx context.Context, funcs []llm.ChatCompletionOptionFunc, stream <-chan llm.StreamChunk) (<-chan llm.StreamChunk, error) {
+			if usage.ContextTracker(ctx) == nil {
+				return stream, nil
+			}
+
+			forwarded := make(chan llm.StreamChunk)
+			go func() {
+				defer close(forwarded)
+				for chunk := range stream {
+					// Providers send the usage with the final chunk of the stream
+					if chunk.Type() == llm.StreamChunkTypeUsage || chunk.IsComplete() {
+						recordUsage(ctx, model(ctx), chunk.Usage())
+					}
+					select {
+					case forwarded <- chunk:
+					case <-ctx.Done():
+						// Drain the stream so that the provider goroutine can exit
+						for range stream {
+						}
+						return
+					}
+				}
+			}()
+
+			return forwarded, nil
+		}),
+	)
+}
+
+func recordUsage(ctx context.Context, model string, u llm.ChatCompletionUsage) {
+	tracker := usage.ContextTracker(ctx)
+	if tracker == nil || u == nil {
+		return
+	}
+
+	tracker.Record(usage.Call{
+		Role:             string(article.ContextAgentRole(ctx, "")),
+		Chapter:          usage.ContextChapter(ctx),
+		Model:            model,
+		PromptTokens:     u.PromptTokens(),
+		CompletionTokens: u.CompletionTokens(),
+	})
+}
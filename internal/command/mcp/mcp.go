@@ -0,0 +1,199 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	transportStdio = "stdio"
+	transportHTTP  = "http"
+)
+
+func MCP() *cli.Command {
+	return &cli.Command{
+		Name:  "mcp",
+		Usage: "Run an MCP server exposing ghostwriter's tools over stdio or streamable HTTP",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "transport",
+				Value:   transportStdio,
+				Usage:   "MCP transport: stdio or http (streamable HTTP)",
+				EnvVars: []string{"GHOSTWRITER_MCP_TRANSPORT"},
+			},
+			&cli.StringFlag{
+				Name:    "address",
+				Value:   "127.0.0.1:8081",
+				Aliases: []string{"a"},
+				Usage:   "Address the streamable HTTP transport listens on (a --token is required unless it is a loopback address)",
+				EnvVars: []string{"GHOSTWRITER_MCP_ADDRESS"},
+			},
+			&cli.StringFlag{
+				Name:    "token",
+				Value:   "",
+				Usage:   "Bearer token required to call the streamable HTTP transport (disabled when empty, only allowed on a loopback address)",
+				EnvVars: []string{"GHOSTWRITER_MCP_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:    "output-root",
+				Value:   ".",
+				Usage:   "Directory the tools read and write white papers in: the paths given by the clients are resolved against it and cannot leave it",
+				EnvVars: []string{"GHOSTWRITER_MCP_OUTPUT_ROOT"},
+			},
+			&cli.StringFlag{
+				Name:    "corpus-storage-path",
+				Value:   ".corpus",
+				Usage:   "Path to the Corpus data directory",
+				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
+			},
+			shared.CollectionFlag(),
+			&cli.StringFlag{
+				Name:    "chromium-path",
+				Value:   "",
+				EnvVars: []string{"GHOSTWRITER_CHROMIUM_PATH"},
+			},
+			&cli.BoolFlag{
+				Name:    "no-sandbox",
+				Value:   false,
+				EnvVars: []string{"GHOSTWRITER_NO_SANDBOX"},
+			},
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
+		},
+		Action: func(cliCtx *cli.Context) error {
+			transport := cliCtx.String("transport")
+			if transport != transportStdio && transport != transportHTTP {
+				return errors.Errorf("unknown transport %q (expected %q or %q)", transport, transportStdio, transportHTTP)
+			}
+
+			address := cliCtx.String("address")
+			token := cliCtx.String("token")
+
+			if transport == transportHTTP && token == "" && !shared.IsLoopback(address) {
+				return errors.Errorf("refusing to serve mcp on %q without authentication: set a --token or listen on a loopback address", address)
+			}
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			ctx, stop := signal.NotifyContext(cliCtx.Context, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			// The search and scraping tools do not need an LLM: the generation
+			// tools are only exposed when a client can be created.
+			resilientClient, err := llmclient.NewClient(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "no llm client configured, generation tools disabled", slog.Any("error", err))
+			}
+
+			kb, kbClose, err := shared.BuildKnowledgeBase(ctx, cliCtx.String("corpus-storage-path"), shared.Collection{Name: cliCtx.String("collection")})
+			if err != nil {
+				return errors.Wrap(err, "could not create knowledge base")
+			}
+			defer func() { _ = kbClose() }()
+
+			webScraper, searchClient, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			pricing, budget, err := shared.UsageSettings(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			server := NewServer(ServerOptions{
+				Client:        resilientClient,
+				KnowledgeBase: kb,
+				Scraper:       webScraper,
+				SearchClient:  searchClient,
+				OutputRoot:    cliCtx.String("output-root"),
+				ChromiumPath:  cliCtx.String("chromium-path"),
+				NoSandbox:     cliCtx.Bool("no-sandbox"),
+				Locale:        l,
+				Pricing:       pricing,
+				Budget:        budget,
+			})
+
+			if transport == transportStdio {
+				if err := server.Run(ctx, &mcpsdk.StdioTransport{}); err != nil && !errors.Is(err, context.Canceled) {
+					return errors.Wrap(err, "mcp server failed")
+				}
+				return nil
+			}
+
+			return serveHTTP(ctx, address, token, server)
+		},
+	}
+}
+
+// serveHTTP serves the MCP server with the streamable HTTP transport. When
+// token is not empty, requests must provide it as a bearer token.
+func serveHTTP(ctx context.Context, address string, token string, server *mcpsdk.Server) error {
+	var handler http.Handler = mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server {
+		return server
+	}, nil)
+
+	if token != "" {
+		handler = requireToken(handler, token)
+	}
+
+	httpServer := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	slog.InfoContext(ctx, "mcp server listening", slog.String("address", address))
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return errors.Wrap(err, "http server failed")
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return errors.WithStack(httpServer.Shutdown(shutdownCtx))
+}
+
+func requireToken(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func Root() *cli.Command {
+	return MCP()
+}
@@ -0,0 +1,457 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/internal/build"
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	whitepaperui "github.com/bornholm/ghostwriter/internal/command/whitepaper"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/scraper"
+	"github.com/bornholm/ghostwriter/pkg/search"
+	"github.com/bornholm/ghostwriter/pkg/tool"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
+	"github.com/gosimple/slug"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pkg/errors"
+)
+
+// ServerOptions configures the tools exposed by the MCP server.
+type ServerOptions struct {
+	// Client is used by the generation tools. They are not exposed when nil.
+	Client        llm.Client
+	KnowledgeBase article.KnowledgeBase
+	Scraper       scraper.Scraper
+	SearchClient  search.Client
+	// OutputRoot is the directory the tools read and write white papers in.
+	// The paths given by the clients are resolved against it and cannot
+	// leave it.
+	OutputRoot   string
+	ChromiumPath string
+	NoSandbox    bool
+	// Locale is the language of the progress notifications and of the
+	// generated document headings.
+	Locale locale.Locale
+	// Pricing estimates the cost of the tokens used by each tool call.
+	Pricing usage.Pricing
+	// Budget limits the usage of each tool call.
+	Budget usage.Budget
+}
+
+// NewServer returns an MCP server exposing ghostwriter's tools.
+func NewServer(opts ServerOptions) *mcpsdk.Server {
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "ghostwriter", Version: build.Version}, nil)
+
+	addLLMTool(server, tool.NewWebSearchTool(opts.SearchClient))
+	addLLMTool(server, tool.NewScrapeWebpageTool(opts.Scraper))
+	addLLMTool(server, article.NewSearchKnowledgeBaseTool(opts.KnowledgeBase))
+	addLLMTool(server, newQueryDocumentTool(opts.resolvePath))
+
+	if opts.Client != nil {
+		server.AddTool(writeWhitepaperTool(), opts.writeWhitepaper)
+		server.AddTool(fixWhitepaperTool(), opts.fixWhitepaper)
+	}
+
+	return server
+}
+
+// addLLMTool exposes a tool written for the agents as an MCP tool.
+func addLLMTool(server *mcpsdk.Server, t llm.Tool) {
+	server.AddTool(&mcpsdk.Tool{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: t.Parameters(),
+	}, func(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+		params, err := toolParams(req)
+		if err != nil {
+			return toolError(err), nil
+		}
+
+		result, err := t.Execute(ctx, params)
+		if err != nil {
+			return toolError(err), nil
+		}
+
+		return toolText(result.Text()), nil
+	})
+}
+
+// newQueryDocumentTool wraps tool.NewQueryDocumentTool, whose document is
+// fixed at creation, into a tool reading the document from the given path,
+// resolved with resolvePath.
+func newQueryDocumentTool(resolvePath func(path string) (string, error)) llm.Tool {
+	return llm.NewFuncTool(
+		"query_document",
+		"Query a Markdown document (or every Markdown file of a white paper output directory) using one or more CSS-like selectors (e.g. `h2`, `h2:contains(\"foo *\")`, `code[lang=\"go\"]`, `table`). "+
+			"Returns the matching content as plain text.",
+		llm.NewJSONSchema().
+			RequiredProperty("path", "Path to a Markdown file or to a white paper output directory, relative to the output root", "string").
+			RequiredProperty("selectors", "Array of CSS-like selector strings (e.g. [\"h2\", \"code[lang=\\\"mermaid\\\"]\", \"table\"])", "array"),
+		func(ctx context.Context, params map[string]any) (llm.ToolResult, error) {
+			path, err := llm.ToolParam[string](params, "path")
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			path, err = resolvePath(path)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			document, err := readDocument(path)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			return tool.NewQueryDocumentTool(document).Execute(ctx, map[string]any{
+				"selectors": params["selectors"],
+			})
+		},
+	)
+}
+
+// resolvePath resolves a path given by a client against the output root. It
+// refuses the paths leaving the output root, including through symbolic links.
+func (o ServerOptions) resolvePath(path string) (string, error) {
+	root, err := filepath.Abs(o.OutputRoot)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+
+	if !isWithin(root, path) {
+		return "", errors.Errorf("path %q is outside of the output root", path)
+	}
+
+	// Without an output root, there is no symbolic link to follow
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return path, nil
+	}
+
+	// The path may not exist yet: its nearest existing parent is resolved
+	existing := path
+	for existing != root {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if !isWithin(resolvedRoot, resolved) {
+		return "", errors.Errorf("path %q is outside of the output root", path)
+	}
+
+	return path, nil
+}
+
+func isWithin(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func readDocument(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return string(data), nil
+	}
+
+	// Chapter files are numbered: lexical order is the reading order
+	files, err := filepath.Glob(filepath.Join(path, "*.md"))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	sort.Strings(files)
+
+	var b strings.Builder
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		b.Write(data)
+		b.WriteString("\n\n")
+	}
+
+	return b.String(), nil
+}
+
+type writeWhitepaperParams struct {
+	Subject           string `json:"subject"`
+	TargetWords       int    `json:"target_words"`
+	ResearchDepth     string `json:"research_depth"`
+	OutputDir         string `json:"output_dir"`
+	StyleGuide        string `json:"style_guide"`
+	AdditionalContext string `json:"additional_context"`
+	MaxReviewRounds   int    `json:"max_review_rounds"`
+	SkipResearch      bool   `json:"skip_research"`
+	Resume            bool   `json:"resume"`
+	HTML              bool   `json:"html"`
+	PDF               bool   `json:"pdf"`
+}
+
+func writeWhitepaperTool() *mcpsdk.Tool {
+	return &mcpsdk.Tool{
+		Name: "write_whitepaper",
+		Description: "Research, plan and write a complete white paper about the given subject. " +
+			"This is a long-running operation (up to hours): progress notifications are sent if a progress token is provided.",
+		InputSchema: llm.NewJSONSchema().
+			RequiredProperty("subject", "The subject of the white paper", "string").
+			Property("target_words", "Target word count (default: 10000)", "integer").
+			Property("research_depth", "Research depth: basic, deep, deep_web or academic (default: deep)", "string").
+			Property("output_dir", "Output directory, relative to the output root (default: a directory named after the subject)", "string").
+			Property("style_guide", "Style guidelines to follow", "string").
+			Property("additional_context", "Additional context about the subject", "string").
+			Property("max_review_rounds", "Maximum number of write→review rounds per chapter (default: 2)", "integer").
+			Property("skip_research", "Rely on the documents already in the knowledge base", "boolean").
+			Property("resume", "Resume an interrupted run from the checkpoint of the output directory", "boolean").
+			Property("html", "Also render the white paper as HTML", "boolean").
+			Property("pdf", "Also render the white paper as PDF", "boolean"),
+	}
+}
+
+func (o ServerOptions) writeWhitepaper(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+	params := writeWhitepaperParams{
+		TargetWords:     10000,
+		ResearchDepth:   string(article.ResearchDeep),
+		MaxReviewRounds: 2,
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &params); err != nil {
+		return toolError(errors.Wrap(err, "invalid arguments")), nil
+	}
+
+	subject := strings.TrimSpace(params.Subject)
+	if subject == "" {
+		return toolError(errors.New("subject is required")), nil
+	}
+
+	outputDir := params.OutputDir
+	if outputDir == "" {
+		outputDir = slug.Make(subject)
+	}
+
+	outputDir, err := o.resolvePath(outputDir)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	opts := []wppkg.OrchestratorOptionFunc{
+		wppkg.WithTargetWordCount(params.TargetWords),
+		wppkg.WithResearchDepth(article.ResearchDepth(params.ResearchDepth)),
+		wppkg.WithOutputDir(outputDir),
+		wppkg.WithChromiumPath(o.ChromiumPath),
+		wppkg.WithNoSandbox(o.NoSandbox),
+		wppkg.WithMaxReviewRounds(params.MaxReviewRounds),
+		wppkg.WithSkipResearch(params.SkipResearch),
+		wppkg.WithResume(params.Resume),
+		wppkg.WithModelName(llmclient.ModelName()),
+		wppkg.WithKnowledgeBase(o.KnowledgeBase),
+		wppkg.WithScraper(o.Scraper),
+		wppkg.WithSearchClient(o.SearchClient),
+		wppkg.WithLocale(o.Locale),
+	}
+
+	files := []string{}
+	if params.HTML {
+		htmlPath := filepath.Join(outputDir, "whitepaper.html")
+		opts = append(opts, wppkg.WithRenderHTML(htmlPath))
+		files = append(files, htmlPath)
+	}
+	if params.PDF {
+		pdfPath := filepath.Join(outputDir, "whitepaper.pdf")
+		opts = append(opts, wppkg.WithRenderPDF(pdfPath))
+		files = append(files, pdfPath)
+	}
+	if params.StyleGuide != "" {
+		opts = append(opts, wppkg.WithStyleGuidelines(params.StyleGuide))
+	}
+	if params.AdditionalContext != "" {
+		opts = append(opts, wppkg.WithAdditionalContext(params.AdditionalContext))
+	}
+
+	tracker := usage.NewTracker(o.Pricing, usage.WithBudget(o.Budget))
+	ctx = usage.WithContextTracker(ctx, tracker)
+	emit := progressEmitter(ctx, req, o.Locale)
+	tracker.EmitTo(emit)
+
+	result, err := wppkg.WriteWhitePaper(ctx, o.Client, subject, emit, opts...)
+	usagePath := filepath.Join(outputDir, usage.FileName)
+	usageReport := shared.ReportUsage(tracker, usagePath, emit)
+	if err != nil {
+		return toolError(errors.Wrapf(err, "failed to generate white paper (run again with resume to continue from %s)", outputDir)), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "White paper %q written in %s\n\n", result.Metadata.Title, outputDir)
+	fmt.Fprintf(&b, "- Index: %s\n", result.Entrypoint)
+	for _, f := range files {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+	writeUsage(&b, usageReport, usagePath)
+
+	return toolText(b.String()), nil
+}
+
+type fixWhitepaperParams struct {
+	Dir               string `json:"dir"`
+	StyleGuide        string `json:"style_guide"`
+	AdditionalContext string `json:"additional_context"`
+	Enrich            bool   `json:"enrich"`
+}
+
+func fixWhitepaperTool() *mcpsdk.Tool {
+	return &mcpsdk.Tool{
+		Name: "fix_whitepaper",
+		Description: "Apply the '> EDITOR: ...' annotations of a white paper output directory. " +
+			"This is a long-running operation: progress notifications are sent if a progress token is provided.",
+		InputSchema: llm.NewJSONSchema().
+			RequiredProperty("dir", "Path to the white paper output directory, relative to the output root", "string").
+			Property("style_guide", "Style guidelines to follow", "string").
+			Property("additional_context", "Additional context about the subject", "string").
+			Property("enrich", "Force the enrichment pass (citation links + Mermaid diagrams) even if no annotations are found", "boolean"),
+	}
+}
+
+func (o ServerOptions) fixWhitepaper(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+	var params fixWhitepaperParams
+	if err := json.Unmarshal(req.Params.Arguments, &params); err != nil {
+		return toolError(errors.Wrap(err, "invalid arguments")), nil
+	}
+
+	if params.Dir == "" {
+		return toolError(errors.New("dir is required")), nil
+	}
+
+	dir, err := o.resolvePath(params.Dir)
+	if err != nil {
+		return toolError(err), nil
+	}
+
+	opts := []wppkg.FixOptionFunc{
+		wppkg.WithFixInputDir(dir),
+		wppkg.WithFixForceEnrichment(params.Enrich),
+		wppkg.WithFixKnowledgeBase(o.KnowledgeBase),
+		wppkg.WithFixLocale(o.Locale),
+	}
+	if params.StyleGuide != "" {
+		opts = append(opts, wppkg.WithFixStyleGuidelines(params.StyleGuide))
+	}
+	if params.AdditionalContext != "" {
+		opts = append(opts, wppkg.WithFixAdditionalContext(params.AdditionalContext))
+	}
+
+	tracker := usage.NewTracker(o.Pricing, usage.WithBudget(o.Budget))
+	ctx = usage.WithContextTracker(ctx, tracker)
+	emit := progressEmitter(ctx, req, o.Locale)
+	tracker.EmitTo(emit)
+
+	result, err := wppkg.FixWhitePaperInDir(ctx, o.Client, emit, opts...)
+	usagePath := filepath.Join(dir, "usage-fix.json")
+	usageReport := shared.ReportUsage(tracker, usagePath, emit)
+	if err != nil {
+		return toolError(errors.Wrap(err, "failed to fix white paper")), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d file(s) fixed, %d skipped\n\n", len(result.FixedFiles), len(result.SkippedFiles))
+	for _, f := range result.FixedFiles {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+	writeUsage(&b, usageReport, usagePath)
+
+	return toolText(b.String()), nil
+}
+
+// writeUsage appends the token usage of the tool call to its result.
+func writeUsage(b *strings.Builder, report *usage.Report, path string) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintf(b, "\nToken usage: %d prompt + %d completion = %d tokens in %d call(s)", report.PromptTokens, report.CompletionTokens, report.TotalTokens, report.Calls)
+	if report.Cost != nil {
+		fmt.Fprintf(b, ", estimated cost %.4f", *report.Cost)
+	}
+	fmt.Fprintf(b, " (%s)\n", path)
+}
+
+// progressEmitter returns an agent.EmitFunc forwarding the pipeline events as
+// progress notifications, if the client asked for them.
+func progressEmitter(ctx context.Context, req *mcpsdk.CallToolRequest, l locale.Locale) agent.EmitFunc {
+	token := req.Params.GetProgressToken()
+	progress := 0
+
+	return func(evt agent.Event) error {
+		if token == nil {
+			return nil
+		}
+
+		message := whitepaperui.SummarizeEvent(l, evt)
+		if message == "" {
+			return nil
+		}
+
+		progress++
+		err := req.Session.NotifyProgress(ctx, &mcpsdk.ProgressNotificationParams{
+			ProgressToken: token,
+			Message:       message,
+			Progress:      float64(progress),
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "could not send progress notification", slog.Any("error", err))
+		}
+
+		return nil
+	}
+}
+
+func toolParams(req *mcpsdk.CallToolRequest) (map[string]any, error) {
+	params := map[string]any{}
+	if len(req.Params.Arguments) == 0 {
+		return params, nil
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &params); err != nil {
+		return nil, errors.Wrap(err, "invalid arguments")
+	}
+	return params, nil
+}
+
+func toolText(text string) *mcpsdk.CallToolResult {
+	return &mcpsdk.CallToolResult{
+		Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: text}},
+	}
+}
+
+func toolError(err error) *mcpsdk.CallToolResult {
+	result := toolText(err.Error())
+	result.IsError = true
+	return result
+}
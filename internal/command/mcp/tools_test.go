@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	opts := ServerOptions{OutputRoot: root}
+
+	testCases := []struct {
+		path     string
+		expected string
+		refused  bool
+	}{
+		{path: "solar", expected: filepath.Join(root, "solar")},
+		{path: "solar/index.md", expected: filepath.Join(root, "solar/index.md")},
+		{path: filepath.Join(root, "solar"), expected: filepath.Join(root, "solar")},
+		{path: ".", expected: root},
+		{path: "../solar", refused: true},
+		{path: "solar/../../solar", refused: true},
+		{path: "/etc/passwd", refused: true},
+		{path: "link/secret.md", refused: true},
+	}
+
+	for _, tc := range testCases {
+		path, err := opts.resolvePath(tc.path)
+		if tc.refused {
+			if err == nil {
+				t.Errorf("%s: expected the path to be refused, got %q", tc.path, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got: %+v", tc.path, err)
+			continue
+		}
+		if path != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.path, tc.expected, path)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
+	"github.com/pkg/errors"
+)
+
+// EventTypeArticleProgress is emitted for the progress steps of articles.
+const EventTypeArticleProgress agent.EventType = "article.progress"
+
+// ArticleProgressData carries an article.ProgressEvent.
+type ArticleProgressData struct {
+	Phase    article.ProgressPhase `json:"phase"`
+	Step     string                `json:"step"`
+	Progress float64               `json:"progress"`
+}
+
+// NewArticleProgressEvent converts an article.ProgressEvent to an agent.Event.
+func NewArticleProgressEvent(evt article.ProgressEvent) agent.Event {
+	return agent.NewEvent(EventTypeArticleProgress, &ArticleProgressData{
+		Phase:    evt.Phase(),
+		Step:     evt.Step(),
+		Progress: evt.Progress(),
+	})
+}
+
+type RecordType string
+
+const (
+	RecordTypeEvent  RecordType = "event"
+	RecordTypeResult RecordType = "result"
+)
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// EventRecord is the JSON line written for an agent.Event.
+type EventRecord struct {
+	Type  RecordType      `json:"type"`
+	Time  time.Time       `json:"time"`
+	Job   int             `json:"job,omitempty"`
+	Event agent.EventType `json:"event"`
+	Data  any             `json:"data,omitempty"`
+}
+
+// Result is the outcome of a command, filled by the command on success.
+type Result struct {
+	// Entrypoint is the main file produced: index.md, the article, the plan…
+	Entrypoint string `json:"entrypoint,omitempty"`
+	// Files lists the other files produced or modified.
+	Files   []string         `json:"files,omitempty"`
+	Sources []article.Source `json:"sources,omitempty"`
+	// Usage is the token usage of the run, when it made LLM calls.
+	Usage *usage.Report `json:"usage,omitempty"`
+	// KnowledgeBase is filled by the kb stats command.
+	KnowledgeBase *KnowledgeBaseStats `json:"knowledge_base,omitempty"`
+}
+
+// KnowledgeBaseStats describes the content of a knowledge base.
+type KnowledgeBaseStats struct {
+	StoragePath string         `json:"storage_path"`
+	Collection  string         `json:"collection"`
+	Documents   int            `json:"documents"`
+	Words       int            `json:"words"`
+	SourceTypes map[string]int `json:"source_types"`
+}
+
+// ResultRecord is the last JSON line of a run.
+type ResultRecord struct {
+	Type    RecordType `json:"type"`
+	Time    time.Time  `json:"time"`
+	Job     int        `json:"job,omitempty"`
+	Command string     `json:"command"`
+	Status  Status     `json:"status"`
+	Error   string     `json:"error,omitempty"`
+	Result
+	Timings Timings `json:"timings"`
+}
+
+type Timings struct {
+	StartedAt  time.Time     `json:"started_at"`
+	DurationMS int64         `json:"duration_ms"`
+	Phases     []PhaseTiming `json:"phases,omitempty"`
+}
+
+// PhaseTiming is the duration of a white paper phase or an article progress
+// phase. Unfinished phases have no duration.
+type PhaseTiming struct {
+	Name       string `json:"name"`
+	DurationMS *int64 `json:"duration_ms,omitempty"`
+
+	startedAt time.Time
+}
+
+// Reporter writes the events and the result of a command as JSON Lines.
+type Reporter struct {
+	command string
+	job     int
+	start   time.Time
+	writer  *lockedEncoder
+
+	mu     sync.Mutex
+	phases []PhaseTiming
+}
+
+type lockedEncoder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func (e *lockedEncoder) encode(v any) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.encoder.Encode(v)
+}
+
+func NewReporter(w io.Writer, command string) *Reporter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return &Reporter{
+		command: command,
+		start:   time.Now(),
+		writer:  &lockedEncoder{encoder: encoder},
+		phases:  make([]PhaseTiming, 0),
+	}
+}
+
+// ForJob returns a reporter sharing the output of r whose lines carry the
+// given job number. It is used by the batch command.
+func (r *Reporter) ForJob(number int, command string) *Reporter {
+	return &Reporter{
+		command: command,
+		job:     number,
+		start:   time.Now(),
+		writer:  r.writer,
+		phases:  make([]PhaseTiming, 0),
+	}
+}
+
+// Emit writes the event. It implements agent.EmitFunc.
+func (r *Reporter) Emit(evt agent.Event) error {
+	now := time.Now()
+
+	r.track(evt, now)
+
+	record := EventRecord{
+		Type:  RecordTypeEvent,
+		Time:  now,
+		Job:   r.job,
+		Event: evt.Type(),
+		Data:  eventData(evt),
+	}
+
+	if err := r.writer.encode(record); err != nil {
+		return errors.Wrap(err, "could not write event")
+	}
+
+	return nil
+}
+
+// Progress writes an article progress event.
+func (r *Reporter) Progress(evt article.ProgressEvent) {
+	_ = r.Emit(NewArticleProgressEvent(evt))
+}
+
+// Result writes the result line. A non-nil err marks the run as failed.
+func (r *Reporter) Result(result Result, err error) error {
+	now := time.Now()
+
+	record := ResultRecord{
+		Type:    RecordTypeResult,
+		Time:    now,
+		Job:     r.job,
+		Command: r.command,
+		Status:  StatusSucceeded,
+		Result:  result,
+		Timings: Timings{
+			StartedAt:  r.start,
+			DurationMS: now.Sub(r.start).Milliseconds(),
+			Phases:     r.timings(now, err == nil),
+		},
+	}
+
+	if err != nil {
+		record.Status = StatusFailed
+		record.Error = err.Error()
+	}
+
+	if err := r.writer.encode(record); err != nil {
+		return errors.Wrap(err, "could not write result")
+	}
+
+	return nil
+}
+
+// track records the phase transitions. White paper phases have explicit
+// start and done events; an article phase ends when the next one starts.
+func (r *Reporter) track(evt agent.Event, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch data := evt.Data().(type) {
+	case *wppkg.PhaseData:
+		if !data.Done {
+			r.phases = append(r.phases, PhaseTiming{Name: data.Name, startedAt: now})
+			return
+		}
+		for i := len(r.phases) - 1; i >= 0; i-- {
+			if r.phases[i].Name == data.Name && r.phases[i].DurationMS == nil {
+				r.phases[i].end(now)
+				return
+			}
+		}
+		// Phases skipped by a resumed run only emit their done event
+		r.phases = append(r.phases, PhaseTiming{Name: data.Name, DurationMS: new(int64)})
+	case *ArticleProgressData:
+		name := string(data.Phase)
+		if n := len(r.phases); n > 0 && r.phases[n-1].Name == name {
+			return
+		}
+		r.endPhases(now)
+		if data.Phase != article.PhaseCompleted {
+			r.phases = append(r.phases, PhaseTiming{Name: name, startedAt: now})
+		}
+	}
+}
+
+// endPhases ends the phases still running.
+func (r *Reporter) endPhases(now time.Time) {
+	for i := range r.phases {
+		if r.phases[i].DurationMS == nil {
+			r.phases[i].end(now)
+		}
+	}
+}
+
+// timings returns the phase timings. The phases still running end now when
+// the run succeeded, and have no duration otherwise.
+func (r *Reporter) timings(now time.Time, succeeded bool) []PhaseTiming {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if succeeded {
+		r.endPhases(now)
+	}
+
+	phases := make([]PhaseTiming, len(r.phases))
+	copy(phases, r.phases)
+
+	return phases
+}
+
+func (p *PhaseTiming) end(now time.Time) {
+	duration := now.Sub(p.startedAt).Milliseconds()
+	p.DurationMS = &duration
+}
+
+// eventData maps the genai event payloads, which have no JSON tags, to the
+// documented snake_case objects.
+func eventData(evt agent.Event) any {
+	switch data := evt.Data().(type) {
+	case *agent.TextDeltaData:
+		return map[string]any{"delta": data.Delta}
+	case *agent.ReasoningData:
+		return map[string]any{"reasoning": data.Reasoning}
+	case *agent.ToolCallStartData:
+		return map[string]any{"id": data.ID, "name": data.Name, "parameters": jsonValue(data.Parameters)}
+	case *agent.ToolCallDoneData:
+		return map[string]any{"id": data.ID, "name": data.Name, "result": data.Result}
+	case *agent.TodoUpdatedData:
+		items := make([]map[string]any, 0, len(data.Items))
+		for _, item := range data.Items {
+			items = append(items, map[string]any{"id": item.ID, "content": item.Content, "status": item.Status})
+		}
+		return map[string]any{"items": items}
+	case *agent.CompleteData:
+		return map[string]any{"message": data.Message}
+	case *agent.ErrorData:
+		return map[string]any{"message": data.Message}
+	default:
+		return jsonValue(data)
+	}
+}
+
+// jsonValue returns v, or its textual representation when it cannot be
+// serialized.
+func jsonValue(v any) any {
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return v
+}
@@ -0,0 +1,126 @@
+// Package output selects how commands report their progress and result on
+// stdout: colored text for humans, or JSON Lines for scripts.
+//
+// In the jsonl format, every line is a JSON object with a "type" field:
+//
+//	{"type":"event","time":"2026-01-02T15:04:05Z","event":"whitepaper.phase","data":{"name":"Recherche","done":false}}
+//	{"type":"result","time":"2026-01-02T15:34:05Z","command":"whitepaper","status":"succeeded","entrypoint":"out/index.md",...}
+//
+// Event lines carry one agent.Event each. The "job" field is set by the batch
+// command only. The "data" object depends on the "event" field:
+//
+//	text_delta                 {"delta"}
+//	reasoning                  {"reasoning"}
+//	tool_call_start            {"id", "name", "parameters"}
+//	tool_call_done             {"id", "name", "result"}
+//	todo_updated               {"items": [{"id", "content", "status"}]}
+//	complete                   {"message"}
+//	error                      {"message"}
+//	whitepaper.phase           {"name", "done", "info"}
+//	whitepaper.chapter_start   {"number", "total", "title", "target"}
+//	whitepaper.chapter_done    {"number", "total", "title", "word_count"}
+//	article.progress           {"phase", "step", "progress"}
+//	usage.fallback             {"time", "role", "chapter", "from", "to", "reason"}
+//	usage.summary              {"calls", "prompt_tokens", "completion_tokens", "total_tokens", "cost", "models", "roles", "chapters", "unpriced_models", "fallbacks", "file"}
+//
+// Events of other types are serialized as is. The last line of a run is a
+// result line (see Result), written on success as well as on failure.
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+type Format string
+
+const (
+	FormatText  Format = "text"
+	FormatJSONL Format = "jsonl"
+)
+
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// Flag returns the --output-format flag shared by the commands.
+func Flag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:    "output-format",
+		Value:   string(FormatText),
+		Usage:   "Format of the progress and result written to stdout: text or jsonl (one JSON object per line)",
+		EnvVars: []string{"GHOSTWRITER_OUTPUT_FORMAT"},
+	}
+}
+
+// Output writes the progress and the result of a command in the selected
+// format. In the jsonl format, the human-readable text is discarded.
+type Output struct {
+	reporter *Reporter
+}
+
+// New returns the output of the given command for the format value of the
+// --output-format flag.
+func New(format string, command string) (*Output, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(format))) {
+	case "", FormatText:
+		return &Output{}, nil
+	case FormatJSONL:
+		return &Output{reporter: NewReporter(os.Stdout, command)}, nil
+	default:
+		return nil, errors.Wrapf(ErrUnknownFormat, "%q (expected %q or %q)", format, FormatText, FormatJSONL)
+	}
+}
+
+// JSONL reports whether the output is machine-readable.
+func (o *Output) JSONL() bool {
+	return o.reporter != nil
+}
+
+// Reporter returns the JSONL reporter, or nil in the text format.
+func (o *Output) Reporter() *Reporter {
+	return o.reporter
+}
+
+// Printf prints human-readable text. It does nothing in the jsonl format.
+func (o *Output) Printf(format string, args ...any) {
+	if o.reporter != nil {
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+// Emit returns the agent.EmitFunc of the command: text renders the events in
+// the text format, every event is serialized in the jsonl format.
+func (o *Output) Emit(text agent.EmitFunc) agent.EmitFunc {
+	if o.reporter != nil {
+		return o.reporter.Emit
+	}
+	return text
+}
+
+// Progress returns the article progress callback of the command, see Emit.
+func (o *Output) Progress(text func(article.ProgressEvent)) func(article.ProgressEvent) {
+	if o.reporter != nil {
+		return o.reporter.Progress
+	}
+	return text
+}
+
+// Finish writes the result line in the jsonl format and returns err
+// unchanged, so that it can be deferred by the command action:
+//
+//	defer func() { err = out.Finish(result, err) }()
+func (o *Output) Finish(result Result, err error) error {
+	if o.reporter == nil {
+		return err
+	}
+	if writeErr := o.reporter.Result(result, err); writeErr != nil && err == nil {
+		return errors.WithStack(writeErr)
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/output"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	whitepaperui "github.com/bornholm/ghostwriter/internal/command/whitepaper"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
+	"github.com/gosimple/slug"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func Plan() *cli.Command {
+	return &cli.Command{
+		Name:  "plan",
+		Usage: "Research the given subject and write an editable white paper plan (plan.json and plan.md)",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "subject",
+				Aliases: []string{"s"},
+				EnvVars: []string{"GHOSTWRITER_SUBJECT"},
+			},
+			&cli.StringFlag{
+				Name:      "subject-file",
+				Aliases:   []string{"sf"},
+				Usage:     "Path to a file whose content will be used as the subject",
+				TakesFile: true,
+				EnvVars:   []string{"GHOSTWRITER_SUBJECT_FILE"},
+			},
+			&cli.IntFlag{
+				Name:    "target-words",
+				Value:   10000,
+				Aliases: []string{"t"},
+				EnvVars: []string{"GHOSTWRITER_TARGET_WORDS"},
+			},
+			&cli.StringFlag{
+				Name:    "output-dir",
+				Value:   "",
+				Aliases: []string{"o"},
+				EnvVars: []string{"GHOSTWRITER_OUTPUT_DIR"},
+			},
+			shared.StyleGuideFlag(),
+			&cli.StringFlag{
+				Name:    "research-depth",
+				Value:   string(article.ResearchDeep),
+				Aliases: []string{"d"},
+				EnvVars: []string{"GHOSTWRITER_RESEARCH_DEPTH"},
+			},
+			&cli.StringSliceFlag{
+				Name:      "files",
+				Aliases:   []string{"f"},
+				EnvVars:   []string{"GHOSTWRITER_FILES"},
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:    "additional-context",
+				Value:   "",
+				Aliases: []string{"c"},
+				EnvVars: []string{"GHOSTWRITER_ADDITIONAL_CONTEXT"},
+			},
+			&cli.StringFlag{
+				Name:    "corpus-storage-path",
+				Value:   ".corpus",
+				Usage:   "Path to the Corpus data directory (activates the Corpus knowledge base backend)",
+				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
+			},
+			shared.CollectionFlag(),
+			&cli.BoolFlag{
+				Name:    "skip-research",
+				Value:   false,
+				Usage:   "Skip the research phase and rely on the documents already in the knowledge base (see the research command)",
+				EnvVars: []string{"GHOSTWRITER_SKIP_RESEARCH"},
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Value:   2 * time.Hour,
+				Usage:   "Maximum duration of the run",
+				EnvVars: []string{"GHOSTWRITER_TIMEOUT"},
+			},
+			output.Flag(),
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
+		},
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "plan")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			subject := strings.TrimSpace(cliCtx.String("subject"))
+			if subjectFile := cliCtx.String("subject-file"); subjectFile != "" {
+				data, err := os.ReadFile(subjectFile)
+				if err != nil {
+					return errors.Wrap(err, "failed to read subject file")
+				}
+				subject = strings.TrimSpace(string(data))
+			}
+			if subject == "" {
+				return errors.New("subject is required: use --subject or --subject-file")
+			}
+			targetWords := cliCtx.Int("target-words")
+			outputDir := cliCtx.String("output-dir")
+			styleGuide := cliCtx.String("style-guide")
+			researchDepth := cliCtx.String("research-depth")
+			files := cliCtx.StringSlice("files")
+			additionalContext := cliCtx.String("additional-context")
+			corpusStoragePath := cliCtx.String("corpus-storage-path")
+			skipResearch := cliCtx.Bool("skip-research")
+
+			if outputDir == "" {
+				outputDir = slug.Make(subject)
+			}
+
+			ctx, cancel := context.WithTimeout(cliCtx.Context, cliCtx.Duration("timeout"))
+			defer cancel()
+
+			tracker, err := shared.NewUsageTracker(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			ctx = usage.WithContextTracker(ctx, tracker)
+
+			resilientClient, err := llmclient.NewClient(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to create llm client")
+			}
+
+			orchestratorOptions := []wppkg.OrchestratorOptionFunc{
+				wppkg.WithTargetWordCount(targetWords),
+				wppkg.WithResearchDepth(article.ResearchDepth(researchDepth)),
+				wppkg.WithSkipResearch(skipResearch),
+				wppkg.WithLocale(l),
+			}
+
+			if styleGuide != "" {
+				guidelines, err := shared.ReadStyleGuide(styleGuide)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				orchestratorOptions = append(orchestratorOptions, wppkg.WithStyleGuidelines(guidelines))
+			}
+
+			if additionalContext != "" {
+				data, err := os.ReadFile(additionalContext)
+				if err != nil {
+					return errors.Wrap(err, "failed to read additional context file")
+				}
+				orchestratorOptions = append(orchestratorOptions, wppkg.WithAdditionalContext(string(data)))
+			}
+
+			kb, kbClose, err := shared.BuildKnowledgeBase(ctx, corpusStoragePath, shared.RunCollection(cliCtx, subject, skipResearch))
+			if err != nil {
+				return errors.Wrap(err, "could not create knowledge base")
+			}
+			defer func() { _ = kbClose() }()
+
+			orchestratorOptions = append(orchestratorOptions, wppkg.WithKnowledgeBase(kb))
+
+			webScraper, searchClient, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			orchestratorOptions = append(orchestratorOptions, wppkg.WithScraper(webScraper), wppkg.WithSearchClient(searchClient))
+
+			if len(files) > 0 {
+				if err := shared.BootstrapKnowledgeBase(kb, files); err != nil {
+					return errors.Wrap(err, "could not bootstrap knowledge base")
+				}
+			}
+
+			out.Printf("\n%s\n\n", l.T(locale.PlanHeader, subject))
+
+			emit := out.Emit(func(evt agent.Event) error {
+				if text := whitepaperui.RenderEvent(l, evt); text != "" {
+					fmt.Print(text)
+				}
+				return nil
+			})
+			tracker.EmitTo(emit)
+			defer func() { result.Usage = shared.ReportUsage(tracker, filepath.Join(outputDir, "usage-plan.json"), emit) }()
+
+			plan, err := wppkg.Plan(ctx, resilientClient, subject, emit, orchestratorOptions...)
+			if err != nil {
+				return errors.Wrap(err, "failed to generate plan")
+			}
+
+			if err := wppkg.SavePlan(outputDir, plan); err != nil {
+				return errors.WithStack(err)
+			}
+
+			planPath := filepath.Join(outputDir, "plan.json")
+
+			result = output.Result{
+				Entrypoint: planPath,
+				Files:      []string{filepath.Join(outputDir, "plan.md")},
+			}
+
+			out.Printf("\n✓ %s\n", l.T(locale.PlanDone, outputDir))
+			out.Printf("  %s\n", l.T(locale.PlanFile, planPath))
+			out.Printf("  %s\n", l.T(locale.PlanPreview, filepath.Join(outputDir, "plan.md")))
+			out.Printf("\n%s\n", l.T(locale.PlanNextStep, fmt.Sprintf("ghostwriter whitepaper --plan %s --output-dir %s --skip-research", planPath, outputDir)))
+
+			return nil
+		},
+	}
+}
+
+func Root() *cli.Command {
+	return Plan()
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bornholm/ghostwriter/internal/command/output"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func Render() *cli.Command {
+	return &cli.Command{
+		Name:  "render",
+		Usage: "Render an existing whitepaper output directory to HTML and/or PDF (no LLM needed)",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "dir",
+				Required: true,
+				Aliases:  []string{"d"},
+				Usage:    "Path to the whitepaper output directory",
+				EnvVars:  []string{"GHOSTWRITER_RENDER_DIR"},
+			},
+			&cli.StringFlag{
+				Name:    "html",
+				Value:   "",
+				Usage:   "Path to the HTML output file",
+				EnvVars: []string{"GHOSTWRITER_OUTPUT_HTML"},
+			},
+			&cli.StringFlag{
+				Name:    "pdf",
+				Value:   "",
+				Usage:   "Path to the PDF output file",
+				EnvVars: []string{"GHOSTWRITER_OUTPUT_PDF"},
+			},
+			&cli.StringFlag{
+				Name:    "chromium-path",
+				Value:   "",
+				Usage:   "Path to the Chromium executable used for PDF rendering",
+				EnvVars: []string{"GHOSTWRITER_CHROMIUM_PATH"},
+			},
+			&cli.BoolFlag{
+				Name:    "no-sandbox",
+				Value:   false,
+				EnvVars: []string{"GHOSTWRITER_NO_SANDBOX"},
+			},
+			shared.LocaleFlag(),
+			output.Flag(),
+		},
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "render")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			dir := strings.TrimSpace(cliCtx.String("dir"))
+			outputHTML := cliCtx.String("html")
+			outputPDF := cliCtx.String("pdf")
+			chromiumPath := cliCtx.String("chromium-path")
+			noSandbox := cliCtx.Bool("no-sandbox")
+
+			if outputHTML == "" && outputPDF == "" {
+				return errors.New("nothing to render: use --html and/or --pdf")
+			}
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			indexPath := filepath.Join(dir, "index.md")
+			if _, err := os.Stat(indexPath); err != nil {
+				return errors.Wrapf(err, "could not find index.md in %q — is it a whitepaper output directory?", dir)
+			}
+
+			result = output.Result{Entrypoint: indexPath}
+
+			targets := []struct {
+				format wppkg.RenderFormat
+				path   string
+			}{
+				{wppkg.RenderFormatHTML, outputHTML},
+				{wppkg.RenderFormatPDF, outputPDF},
+			}
+
+			for _, t := range targets {
+				if t.path == "" {
+					continue
+				}
+
+				err := wppkg.RenderWhitePaper(cliCtx.Context, indexPath, wppkg.RenderOptions{
+					Format:       t.format,
+					OutputPath:   t.path,
+					ChromiumPath: chromiumPath,
+					NoSandbox:    noSandbox,
+				})
+				if err != nil {
+					return errors.Wrapf(err, "failed to render %s", t.format)
+				}
+
+				result.Files = append(result.Files, t.path)
+
+				out.Printf("✓ %s\n", l.T(locale.RenderDone, strings.ToUpper(string(t.format)), t.path))
+			}
+
+			return nil
+		},
+	}
+}
+
+func Root() *cli.Command {
+	return Render()
+}
@@ -0,0 +1,250 @@
+package research
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/output"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	whitepaperui "github.com/bornholm/ghostwriter/internal/command/whitepaper"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func Research() *cli.Command {
+	return &cli.Command{
+		Name:  "research",
+		Usage: "Run the research phase only and persist the collected documents in the knowledge base",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "subject",
+				Aliases: []string{"s"},
+				EnvVars: []string{"GHOSTWRITER_SUBJECT"},
+			},
+			&cli.StringFlag{
+				Name:      "subject-file",
+				Aliases:   []string{"sf"},
+				Usage:     "Path to a file whose content will be used as the subject",
+				TakesFile: true,
+				EnvVars:   []string{"GHOSTWRITER_SUBJECT_FILE"},
+			},
+			&cli.StringFlag{
+				Name:    "research-depth",
+				Value:   string(article.ResearchDeep),
+				Aliases: []string{"d"},
+				EnvVars: []string{"GHOSTWRITER_RESEARCH_DEPTH"},
+			},
+			&cli.StringSliceFlag{
+				Name:      "files",
+				Aliases:   []string{"f"},
+				EnvVars:   []string{"GHOSTWRITER_FILES"},
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:    "additional-context",
+				Value:   "",
+				Aliases: []string{"c"},
+				EnvVars: []string{"GHOSTWRITER_ADDITIONAL_CONTEXT"},
+			},
+			&cli.StringFlag{
+				Name:    "corpus-storage-path",
+				Value:   ".corpus",
+				Usage:   "Path to the Corpus data directory where collected documents are persisted",
+				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
+			},
+			shared.CollectionFlag(),
+			&cli.StringFlag{
+				Name:    "report-dir",
+				Value:   ".",
+				Aliases: []string{"o"},
+				Usage:   "Directory where research-report.md and research-report.json are written",
+				EnvVars: []string{"GHOSTWRITER_REPORT_DIR"},
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Value:   2 * time.Hour,
+				Usage:   "Maximum duration of the run",
+				EnvVars: []string{"GHOSTWRITER_TIMEOUT"},
+			},
+			output.Flag(),
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
+		},
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "research")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			subject := strings.TrimSpace(cliCtx.String("subject"))
+			if subjectFile := cliCtx.String("subject-file"); subjectFile != "" {
+				data, err := os.ReadFile(subjectFile)
+				if err != nil {
+					return errors.Wrap(err, "failed to read subject file")
+				}
+				subject = strings.TrimSpace(string(data))
+			}
+			if subject == "" {
+				return errors.New("subject is required: use --subject or --subject-file")
+			}
+			researchDepth := cliCtx.String("research-depth")
+			files := cliCtx.StringSlice("files")
+			additionalContext := cliCtx.String("additional-context")
+			corpusStoragePath := cliCtx.String("corpus-storage-path")
+			reportDir := cliCtx.String("report-dir")
+
+			ctx, cancel := context.WithTimeout(cliCtx.Context, cliCtx.Duration("timeout"))
+			defer cancel()
+
+			tracker, err := shared.NewUsageTracker(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			ctx = usage.WithContextTracker(ctx, tracker)
+
+			resilientClient, err := llmclient.NewClient(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to create llm client")
+			}
+
+			orchestratorOptions := []article.OrchestratorOptionFunc{
+				article.WithResearchDepth(article.ResearchDepth(researchDepth)),
+			}
+
+			if additionalContext != "" {
+				data, err := os.ReadFile(additionalContext)
+				if err != nil {
+					return errors.Wrap(err, "failed to read additional context file")
+				}
+				orchestratorOptions = append(orchestratorOptions, article.WithAdditionalContext(string(data)))
+			}
+
+			kb, kbClose, err := shared.BuildKnowledgeBase(ctx, corpusStoragePath, shared.RunCollection(cliCtx, subject, true))
+			if err != nil {
+				return errors.Wrap(err, "could not create knowledge base")
+			}
+			defer func() { _ = kbClose() }()
+
+			orchestratorOptions = append(orchestratorOptions, article.WithKnowledgeBase(kb))
+
+			webScraper, searchClient, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			orchestratorOptions = append(orchestratorOptions, article.WithScraper(webScraper), article.WithSearchClient(searchClient))
+
+			if len(files) > 0 {
+				if err := shared.BootstrapKnowledgeBase(kb, files); err != nil {
+					return errors.Wrap(err, "could not bootstrap knowledge base")
+				}
+			}
+
+			out.Printf("\n%s\n\n", l.T(locale.ResearchHeader, subject))
+
+			ctx = article.WithProgressTracking(ctx, out.Progress(func(evt article.ProgressEvent) {
+				fmt.Printf("%s   %s\n", time.Now().Format("15:04:05"), evt.Step())
+			}))
+
+			emit := out.Emit(func(evt agent.Event) error {
+				switch evt.Type() {
+				case agent.EventTypeTextDelta, agent.EventTypeComplete:
+					return nil
+				}
+				if text := whitepaperui.RenderEvent(l, evt); text != "" {
+					fmt.Print(text)
+				}
+				return nil
+			})
+			tracker.EmitTo(emit)
+			defer func() {
+				result.Usage = shared.ReportUsage(tracker, filepath.Join(reportDir, reportBaseName+".usage.json"), emit)
+			}()
+
+			report, researchErr := article.Research(ctx, resilientClient, subject, emit, orchestratorOptions...)
+
+			// Always write the report, even partial, so failed runs can be inspected.
+			if report != nil {
+				if err := writeReport(reportDir, report); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			if researchErr != nil {
+				return errors.Wrap(researchErr, "failed to conduct research")
+			}
+
+			result = output.Result{
+				Entrypoint: filepath.Join(reportDir, reportBaseName+".md"),
+				Files:      []string{filepath.Join(reportDir, reportBaseName+".json")},
+			}
+			for _, doc := range report.Documents {
+				result.Sources = append(result.Sources, article.Source{
+					URL:        doc.URL,
+					Title:      doc.Title,
+					Keywords:   doc.Keywords,
+					SourceType: doc.SourceType,
+					Relevance:  doc.Relevance,
+				})
+			}
+
+			queries, failedSearches, failedScrapes, indexed := report.Stats()
+			out.Printf("\n✓ %s", l.T(locale.ResearchCompleted, queries, indexed))
+			if failedSearches > 0 || failedScrapes > 0 {
+				out.Printf(", %s", l.T(locale.ResearchFailures, failedSearches, failedScrapes))
+			}
+			out.Printf("\n")
+			out.Printf("  %s\n", l.T(locale.ResearchReport, filepath.Join(reportDir, reportBaseName+".md")))
+			out.Printf("  %s\n", l.T(locale.ResearchKnowledgeBase, corpusStoragePath))
+
+			return nil
+		},
+	}
+}
+
+const reportBaseName = "research-report"
+
+func writeReport(dir string, report *article.ResearchReport) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "could not create report directory")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, reportBaseName+".md"), []byte(report.Markdown()), 0644); err != nil {
+		return errors.Wrap(err, "could not write research report")
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not serialize research report")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, reportBaseName+".json"), data, 0644); err != nil {
+		return errors.Wrap(err, "could not write research report")
+	}
+
+	return nil
+}
+
+func Root() *cli.Command {
+	return Research()
+}
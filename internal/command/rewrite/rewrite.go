@@ -0,0 +1,196 @@
+package rewrite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/output"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	whitepaperui "github.com/bornholm/ghostwriter/internal/command/whitepaper"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func Rewrite() *cli.Command {
+	return &cli.Command{
+		Name:  "rewrite",
+		Usage: "Rewrite a single chapter of a whitepaper output directory and reassemble it",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "dir",
+				Required: true,
+				Aliases:  []string{"d"},
+				Usage:    "Path to the whitepaper output directory",
+				EnvVars:  []string{"GHOSTWRITER_REWRITE_DIR"},
+			},
+			&cli.IntFlag{
+				Name:     "chapter",
+				Required: true,
+				Aliases:  []string{"n"},
+				Usage:    "Number of the chapter to rewrite",
+			},
+			&cli.StringFlag{
+				Name:    "instructions",
+				Value:   "",
+				Aliases: []string{"i"},
+				Usage:   "Guidance for the rewrite (e.g. \"more concrete examples, shorter introduction\")",
+			},
+			shared.StyleGuideFlag(),
+			&cli.StringFlag{
+				Name:    "additional-context",
+				Value:   "",
+				Aliases: []string{"c"},
+				EnvVars: []string{"GHOSTWRITER_ADDITIONAL_CONTEXT"},
+			},
+			&cli.StringFlag{
+				Name:    "corpus-storage-path",
+				Value:   "",
+				Usage:   "Path to Corpus data dir (defaults to <dir>/.corpus if it exists)",
+				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
+			},
+			shared.CollectionFlag(),
+			&cli.IntFlag{
+				Name:    "max-review-rounds",
+				Value:   2,
+				Usage:   "Maximum number of write→review rounds for the chapter",
+				EnvVars: []string{"GHOSTWRITER_MAX_REVIEW_ROUNDS"},
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Value:   2 * time.Hour,
+				Usage:   "Maximum duration of the run",
+				EnvVars: []string{"GHOSTWRITER_TIMEOUT"},
+			},
+			output.Flag(),
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
+		},
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "rewrite")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			dir := strings.TrimSpace(cliCtx.String("dir"))
+			number := cliCtx.Int("chapter")
+			styleGuide := cliCtx.String("style-guide")
+			additionalContext := cliCtx.String("additional-context")
+			corpusStoragePath := cliCtx.String("corpus-storage-path")
+
+			// Auto-discover corpus path
+			if corpusStoragePath == "" {
+				candidate := filepath.Join(dir, ".corpus")
+				if _, err := os.Stat(candidate); err == nil {
+					corpusStoragePath = candidate
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(cliCtx.Context, cliCtx.Duration("timeout"))
+			defer cancel()
+
+			tracker, err := shared.NewUsageTracker(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			ctx = usage.WithContextTracker(ctx, tracker)
+
+			resilientClient, err := llmclient.NewClient(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to create llm client")
+			}
+
+			webScraper, searchClient, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			rewriteOptions := []wppkg.RewriteOptionFunc{
+				wppkg.WithRewriteInputDir(dir),
+				wppkg.WithRewriteChapter(number),
+				wppkg.WithRewriteInstructions(cliCtx.String("instructions")),
+				wppkg.WithRewriteMaxReviewRounds(cliCtx.Int("max-review-rounds")),
+				wppkg.WithRewriteLocale(l),
+			}
+
+			if styleGuide != "" {
+				guidelines, err := shared.ReadStyleGuide(styleGuide)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				rewriteOptions = append(rewriteOptions, wppkg.WithRewriteStyleGuidelines(guidelines))
+			}
+
+			if additionalContext != "" {
+				data, err := os.ReadFile(additionalContext)
+				if err != nil {
+					return errors.Wrap(err, "failed to read additional context file")
+				}
+				rewriteOptions = append(rewriteOptions, wppkg.WithRewriteAdditionalContext(string(data)))
+			}
+
+			if corpusStoragePath != "" {
+				kb, kbClose, err := shared.BuildKnowledgeBase(ctx, corpusStoragePath, shared.Collection{Name: cliCtx.String("collection")})
+				if err != nil {
+					return errors.Wrap(err, "could not open knowledge base")
+				}
+				defer func() { _ = kbClose() }()
+				rewriteOptions = append(rewriteOptions, wppkg.WithRewriteKnowledgeBase(kb))
+			}
+
+			out.Printf("\n%s\n\n", l.T(locale.RewriteHeader, number, dir))
+
+			emit := out.Emit(func(evt agent.Event) error {
+				if text := whitepaperui.RenderEvent(l, evt); text != "" {
+					fmt.Print(text)
+				}
+				return nil
+			})
+			tracker.EmitTo(emit)
+			defer func() {
+				result.Usage = shared.ReportUsage(tracker, filepath.Join(dir, fmt.Sprintf("usage-rewrite-%02d.json", number)), emit)
+			}()
+
+			orchestrator := wppkg.NewOrchestrator(resilientClient,
+				wppkg.WithScraper(webScraper),
+				wppkg.WithSearchClient(searchClient),
+			)
+
+			rewritten, err := orchestrator.RewriteChapter(ctx, emit, rewriteOptions...)
+			if err != nil {
+				return errors.Wrap(err, "failed to rewrite chapter")
+			}
+
+			result = output.Result{
+				Entrypoint: rewritten.Entrypoint,
+				Files:      []string{rewritten.File},
+			}
+
+			out.Printf("\n✓ %s\n", l.T(locale.RewriteCompleted, rewritten.Chapter.Number, rewritten.Chapter.WordCount, rewritten.File))
+
+			return nil
+		},
+	}
+}
+
+func Root() *cli.Command {
+	return Rewrite()
+}
@@ -5,8 +5,16 @@ import (
 	"log/slog"
 	"os"
 	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/bornholm/ghostwriter/internal/command/config"
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
 	"github.com/bornholm/ghostwriter/internal/logx"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/cassette"
+	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 )
@@ -47,6 +55,31 @@ func Main(name string, version string, usage string, commands ...*cli.Command) {
 			})
 			slog.SetDefault(logger)
 
+			// Load .env first so that its variables take precedence over the profile
+			if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return errors.Wrap(err, "could not load .env file")
+			}
+
+			if err := applyProfile(ctx.String("config"), ctx.String("profile")); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if err := applyCassette(ctx); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if err := applyCache(ctx); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if err := applyResilience(ctx); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if err := applySearchEngines(ctx); err != nil {
+				return errors.WithStack(err)
+			}
+
 			return nil
 		},
 		Flags: []cli.Flag{
@@ -61,6 +94,88 @@ func Main(name string, version string, usage string, commands ...*cli.Command) {
 				EnvVars: []string{"GHOSTWRITER_DEBUG"},
 				Usage:   "Enable debug mode",
 			},
+			&cli.StringFlag{
+				Name:      "config",
+				EnvVars:   []string{"GHOSTWRITER_CONFIG"},
+				Usage:     "Path to the configuration file (defaults to ghostwriter.yaml in the working directory)",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				EnvVars: []string{"GHOSTWRITER_PROFILE"},
+				Usage:   "Name of the configuration profile to use",
+			},
+			&cli.StringFlag{
+				Name:      "llm-record",
+				EnvVars:   []string{llmclient.RecordEnvVar},
+				Usage:     "Record every LLM chat completion to this cassette file",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:      "llm-replay",
+				EnvVars:   []string{llmclient.ReplayEnvVar},
+				Usage:     "Replay the LLM chat completions recorded in this cassette file instead of calling the provider",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:      "llm-cache",
+				EnvVars:   []string{llmclient.CacheEnvVar},
+				Usage:     "Cache the LLM chat completions in this directory and serve identical requests from it",
+				TakesFile: true,
+			},
+			&cli.DurationFlag{
+				Name:    "llm-cache-ttl",
+				EnvVars: []string{llmclient.CacheTTLEnvVar},
+				Usage:   fmt.Sprintf("Lifetime of the cached LLM chat completions, 0 to keep them forever (default %s)", llmclient.DefaultCacheTTL),
+			},
+			&cli.BoolFlag{
+				Name:    "no-cache",
+				EnvVars: []string{llmclient.NoCacheEnvVar},
+				Usage:   "Do not use the LLM response cache, even if configured",
+			},
+			&cli.IntFlag{
+				Name:    "llm-retries",
+				EnvVars: []string{llmclient.RetriesEnvVar},
+				Usage:   fmt.Sprintf("Number of retries of an LLM call failing with a rate limit or a server error (default %d)", llmclient.DefaultResilience.Retries),
+			},
+			&cli.DurationFlag{
+				Name:    "llm-retry-delay",
+				EnvVars: []string{llmclient.RetryDelayEnvVar},
+				Usage:   fmt.Sprintf("Delay before the first retry of an LLM call, doubled at each retry (default %s)", llmclient.DefaultResilience.RetryDelay),
+			},
+			&cli.Float64Flag{
+				Name:    "llm-chat-rate",
+				EnvVars: []string{llmclient.ChatRateEnvVar},
+				Usage:   fmt.Sprintf("Maximum number of LLM chat completions per minute, 0 for no limit (default %g)", llmclient.DefaultResilience.ChatRate),
+			},
+			&cli.Float64Flag{
+				Name:    "llm-embeddings-rate",
+				EnvVars: []string{llmclient.EmbeddingsRateEnvVar},
+				Usage:   fmt.Sprintf("Maximum number of LLM embeddings calls per minute, 0 for no limit (default %g)", llmclient.DefaultResilience.EmbeddingsRate),
+			},
+			&cli.IntFlag{
+				Name:    "llm-burst",
+				EnvVars: []string{llmclient.BurstEnvVar},
+				Usage:   fmt.Sprintf("Number of LLM calls that may be sent at once within the rate limits (default %d)", llmclient.DefaultResilience.Burst),
+			},
+			&cli.IntFlag{
+				Name:    "llm-breaker-threshold",
+				EnvVars: []string{llmclient.BreakerThresholdEnvVar},
+				Usage:   fmt.Sprintf("Number of consecutive LLM call failures opening the circuit breaker (default %d)", llmclient.DefaultResilience.BreakerThreshold),
+			},
+			&cli.DurationFlag{
+				Name:    "llm-breaker-reset",
+				EnvVars: []string{llmclient.BreakerResetEnvVar},
+				Usage:   fmt.Sprintf("Time the circuit breaker stays open before letting LLM calls through again (default %s)", llmclient.DefaultResilience.BreakerReset),
+			},
+			&cli.StringSliceFlag{
+				Name:    "search-engine",
+				EnvVars: []string{shared.SearchEnginesEnvVar},
+				Usage: fmt.Sprintf(
+					"Search engine used by the research, repeat to query several engines together: %s, %s or %s (default %s)",
+					shared.SearchEngineDuckDuckGo, shared.SearchEngineSearXNG, shared.SearchEngineGoogle, shared.SearchEngineDuckDuckGo,
+				),
+			},
 			&cli.StringFlag{
 				Name:    "log-level",
 				EnvVars: []string{"GHOSTWRITER_LOG_LEVEL"},
@@ -91,3 +206,160 @@ func Main(name string, version string, usage string, commands ...*cli.Command) {
 		os.Exit(1)
 	}
 }
+
+// applyProfile loads the configuration file and exports the selected profile
+// as GHOSTWRITER_* environment variables. Command flags are parsed after the
+// app Before hook, so every command resolves its settings in the same order:
+// flag, environment, profile, flag default.
+func applyProfile(configPath string, profileName string) error {
+	if configPath == "" {
+		found, err := config.Find()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if found == "" {
+			if profileName != "" {
+				return errors.Errorf("profile %q requested but no configuration file found", profileName)
+			}
+			return nil
+		}
+		configPath = found
+	}
+
+	file, err := config.Load(configPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	profile, found, err := file.Profile(profileName)
+	if err != nil {
+		return errors.Wrapf(err, "invalid configuration file %q", configPath)
+	}
+	if !found {
+		return nil
+	}
+
+	if err := profile.Apply(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	slog.Debug("configuration profile applied", slog.String("file", configPath))
+
+	return nil
+}
+
+// applyCassette exports the --llm-record and --llm-replay flags for the LLM
+// clients of the commands. When replaying, the prompts are given the date the
+// cassette was recorded at, so that they match the recorded requests.
+func applyCassette(ctx *cli.Context) error {
+	for flag, envVar := range map[string]string{"llm-record": llmclient.RecordEnvVar, "llm-replay": llmclient.ReplayEnvVar} {
+		if value := ctx.String(flag); value != "" {
+			if err := os.Setenv(envVar, value); err != nil {
+				return errors.Wrapf(err, "could not set %s", envVar)
+			}
+		}
+	}
+
+	_, replay, err := llmclient.CassettePaths()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if replay == "" {
+		return nil
+	}
+
+	interactions, err := cassette.Load(replay)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if recordedAt := cassette.RecordedAt(interactions); !recordedAt.IsZero() {
+		ctx.Context = article.WithContextNow(ctx.Context, recordedAt)
+	}
+
+	slog.Debug("replaying llm cassette", slog.String("file", replay), slog.Int("interactions", len(interactions)))
+
+	return nil
+}
+
+// applyCache exports the response cache flags for the LLM clients of the
+// commands, and checks the resulting settings.
+func applyCache(ctx *cli.Context) error {
+	flags := []struct {
+		name, envVar, value string
+	}{
+		{"llm-cache", llmclient.CacheEnvVar, ctx.String("llm-cache")},
+		{"llm-cache-ttl", llmclient.CacheTTLEnvVar, ctx.Duration("llm-cache-ttl").String()},
+		{"no-cache", llmclient.NoCacheEnvVar, strconv.FormatBool(ctx.Bool("no-cache"))},
+	}
+
+	for _, f := range flags {
+		if !ctx.IsSet(f.name) {
+			continue
+		}
+		if err := os.Setenv(f.envVar, f.value); err != nil {
+			return errors.Wrapf(err, "could not set %s", f.envVar)
+		}
+	}
+
+	dir, ttl, err := llmclient.CacheSettings()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if dir == "" {
+		return nil
+	}
+
+	slog.Debug("llm response cache enabled", slog.String("dir", dir), slog.Duration("ttl", ttl))
+
+	return nil
+}
+
+// applyResilience exports the retry, rate limiting and circuit breaker flags
+// for the LLM clients of the commands, and checks the resulting settings.
+func applyResilience(ctx *cli.Context) error {
+	flags := []struct {
+		name, envVar, value string
+	}{
+		{"llm-retries", llmclient.RetriesEnvVar, strconv.Itoa(ctx.Int("llm-retries"))},
+		{"llm-retry-delay", llmclient.RetryDelayEnvVar, ctx.Duration("llm-retry-delay").String()},
+		{"llm-chat-rate", llmclient.ChatRateEnvVar, strconv.FormatFloat(ctx.Float64("llm-chat-rate"), 'g', -1, 64)},
+		{"llm-embeddings-rate", llmclient.EmbeddingsRateEnvVar, strconv.FormatFloat(ctx.Float64("llm-embeddings-rate"), 'g', -1, 64)},
+		{"llm-burst", llmclient.BurstEnvVar, strconv.Itoa(ctx.Int("llm-burst"))},
+		{"llm-breaker-threshold", llmclient.BreakerThresholdEnvVar, strconv.Itoa(ctx.Int("llm-breaker-threshold"))},
+		{"llm-breaker-reset", llmclient.BreakerResetEnvVar, ctx.Duration("llm-breaker-reset").String()},
+	}
+
+	for _, f := range flags {
+		if !ctx.IsSet(f.name) {
+			continue
+		}
+		if err := os.Setenv(f.envVar, f.value); err != nil {
+			return errors.Wrapf(err, "could not set %s", f.envVar)
+		}
+	}
+
+	if _, err := llmclient.ResilienceSettings(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// applySearchEngines exports the --search-engine flag for the web clients of
+// the commands. The SearXNG instances and the Google API key are read from
+// the environment or the profile.
+func applySearchEngines(ctx *cli.Context) error {
+	if !ctx.IsSet("search-engine") {
+		return nil
+	}
+
+	engines := strings.Join(ctx.StringSlice("search-engine"), ",")
+	if err := os.Setenv(shared.SearchEnginesEnvVar, engines); err != nil {
+		return errors.Wrapf(err, "could not set %s", shared.SearchEnginesEnvVar)
+	}
+
+	slog.Debug("search engines selected", slog.String("engines", engines))
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package serve
+
+import (
+	"archive/zip"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxRequestSize     = 10 << 20
+	sseKeepAlivePeriod = 15 * time.Second
+)
+
+// Handler exposes the job API:
+//
+//	POST   /api/jobs             submit a job (JobRequest)
+//	GET    /api/jobs             list the jobs
+//	GET    /api/jobs/{id}        get the status of a job
+//	DELETE /api/jobs/{id}        cancel a job
+//	GET    /api/jobs/{id}/events stream the job events (Server-Sent Events)
+//	GET    /api/jobs/{id}/output download the output directory as a zip archive
+type Handler struct {
+	manager *Manager
+	token   string
+	mux     *http.ServeMux
+}
+
+// NewHandler returns the API handler. When token is not empty, requests must
+// provide it as a bearer token.
+func NewHandler(manager *Manager, token string) *Handler {
+	h := &Handler{
+		manager: manager,
+		token:   token,
+		mux:     http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("POST /api/jobs", h.submitJob)
+	h.mux.HandleFunc("GET /api/jobs", h.listJobs)
+	h.mux.HandleFunc("GET /api/jobs/{id}", h.getJob)
+	h.mux.HandleFunc("DELETE /api/jobs/{id}", h.cancelJob)
+	h.mux.HandleFunc("GET /api/jobs/{id}/events", h.streamEvents)
+	h.mux.HandleFunc("GET /api/jobs/{id}/output", h.downloadOutput)
+
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" && !h.authorized(r) {
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		// EventSource cannot set headers
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *Handler) submitJob(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid job request"))
+		return
+	}
+
+	job, err := h.manager.Submit(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			writeError(w, http.StatusNotFound, err)
+		case errors.Is(err, ErrJobNotFinished):
+			writeError(w, http.StatusConflict, err)
+		default:
+			writeError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/jobs/"+job.ID())
+	writeJSON(w, http.StatusAccepted, job.Info())
+}
+
+func (h *Handler) listJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := h.manager.List()
+	infos := make([]JobInfo, 0, len(jobs))
+	for _, job := range jobs {
+		infos = append(infos, job.Info())
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (h *Handler) getJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, job.Info())
+}
+
+func (h *Handler) cancelJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+	if !job.Cancel() {
+		writeError(w, http.StatusConflict, errors.Errorf("job %q already finished", job.ID()))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job.Info())
+}
+
+// streamEvents replays the recorded events then streams the new ones until
+// the job finishes. Clients reconnecting with Last-Event-ID only receive the
+// events they missed.
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	lastID := 0
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		if id, err := strconv.Atoi(value); err == nil {
+			lastID = id
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		events, changed, finished := job.Events(lastID)
+
+		for _, evt := range events {
+			if err := writeSSE(w, strconv.Itoa(evt.ID), string(evt.Type), evt); err != nil {
+				return
+			}
+			lastID = evt.ID
+		}
+
+		if finished {
+			_ = writeSSE(w, "", "end", job.Info())
+			flusher.Flush()
+			return
+		}
+
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-changed:
+		}
+	}
+}
+
+func (h *Handler) downloadOutput(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+
+	if !job.Finished() {
+		writeError(w, http.StatusConflict, errors.Wrapf(ErrJobNotFinished, "job %q", job.ID()))
+		return
+	}
+
+	if _, err := os.Stat(job.OutputDir()); err != nil {
+		writeError(w, http.StatusNotFound, errors.Errorf("job %q has no output", job.ID()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.ID()+".zip"))
+
+	if err := writeZip(w, job.OutputDir()); err != nil {
+		// Headers are already sent: the archive is truncated
+		slog.Error("could not write job output archive", slog.String("job", job.ID()), slog.Any("error", err))
+	}
+}
+
+func (h *Handler) job(w http.ResponseWriter, r *http.Request) (*Job, bool) {
+	job, err := h.manager.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	return job, true
+}
+
+func writeZip(w io.Writer, dir string) error {
+	archive := zip.NewWriter(w)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		entry, err := archive.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer file.Close()
+
+		_, err = io.Copy(entry, file)
+		return errors.WithStack(err)
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(archive.Close())
+}
+
+func writeSSE(w io.Writer, id string, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	fmt.Fprintf(&b, "event: %s\n", event)
+	fmt.Fprintf(&b, "data: %s\n\n", payload)
+
+	_, err = io.WriteString(w, b.String())
+	return errors.WithStack(err)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("could not write response", slog.Any("error", err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,363 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
+	"github.com/pkg/errors"
+)
+
+type JobType string
+
+const (
+	JobTypeWhitepaper JobType = "whitepaper"
+	JobTypeArticle    JobType = "article"
+	JobTypeFix        JobType = "fix"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCanceled  JobStatus = "canceled"
+)
+
+// JobRequest describes a job submitted through the API. Its options mirror the
+// flags of the corresponding CLI command, except that the style guide, the
+// additional context and the documents are given inline instead of as file
+// paths: the server never reads the files of its host on behalf of a client.
+type JobRequest struct {
+	Type              JobType               `json:"type"`
+	Subject           string                `json:"subject,omitempty"`
+	TargetWords       int                   `json:"target_words,omitempty"`
+	StyleGuide        string                `json:"style_guide,omitempty"`
+	ResearchDepth     string                `json:"research_depth,omitempty"`
+	Documents         []JobDocument         `json:"documents,omitempty"`
+	AdditionalContext string                `json:"additional_context,omitempty"`
+	MaxReviewRounds   int                   `json:"max_review_rounds,omitempty"`
+	SkipResearch      bool                  `json:"skip_research,omitempty"`
+	Plan              *wppkg.WhitePaperPlan `json:"plan,omitempty"`
+	HTML              bool                  `json:"html,omitempty"`
+	PDF               bool                  `json:"pdf,omitempty"`
+
+	// SourceJob is the id of the whitepaper (or fix) job whose output a fix job
+	// starts from. The output is copied so that the source job is left untouched.
+	SourceJob string `json:"source_job,omitempty"`
+	Enrich    bool   `json:"enrich,omitempty"`
+}
+
+// JobDocument is a document added to the knowledge base of a job before it
+// starts, like the files given with --files on the command line.
+type JobDocument struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+func (d JobDocument) researchDocument() article.ResearchDocument {
+	return article.ResearchDocument{
+		Title:      d.Name,
+		Content:    d.Content,
+		Keywords:   []string{},
+		SourceType: "file",
+		Relevance:  1,
+	}
+}
+
+// normalize validates the request and applies the CLI defaults.
+func (r *JobRequest) normalize() error {
+	names := make(map[string]struct{}, len(r.Documents))
+	for i, doc := range r.Documents {
+		if doc.Name == "" || doc.Content == "" {
+			return errors.Errorf("document #%d: name and content are required", i+1)
+		}
+		if _, exists := names[doc.Name]; exists {
+			return errors.Errorf("document #%d: duplicate name %q", i+1, doc.Name)
+		}
+		names[doc.Name] = struct{}{}
+	}
+
+	switch r.Type {
+	case JobTypeWhitepaper:
+		if r.TargetWords == 0 {
+			r.TargetWords = 10000
+		}
+		if r.Plan != nil {
+			plan, err := wppkg.PreparePlan(*r.Plan, r.TargetWords)
+			if err != nil {
+				return errors.Wrap(err, "invalid plan")
+			}
+			r.Plan = &plan
+			if r.Subject == "" {
+				r.Subject = plan.Title
+			}
+		}
+	case JobTypeArticle:
+		if r.TargetWords == 0 {
+			r.TargetWords = 1500
+		}
+	case JobTypeFix:
+		if r.SourceJob == "" {
+			return errors.New("source_job is required for fix jobs")
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown job type %q (expected %q, %q or %q)", r.Type, JobTypeWhitepaper, JobTypeArticle, JobTypeFix)
+	}
+
+	if r.Subject == "" {
+		return errors.New("subject is required")
+	}
+	if r.ResearchDepth == "" {
+		r.ResearchDepth = string(article.ResearchDeep)
+	}
+	if r.MaxReviewRounds == 0 {
+		r.MaxReviewRounds = 2
+	}
+
+	return nil
+}
+
+// JobEvent is an agent.Event recorded for a job, as sent to SSE clients.
+type JobEvent struct {
+	ID   int             `json:"id"`
+	Type agent.EventType `json:"type"`
+	Time time.Time       `json:"time"`
+	Data any             `json:"data,omitempty"`
+}
+
+// JobInfo is the public state of a job.
+type JobInfo struct {
+	ID         string     `json:"id"`
+	Type       JobType    `json:"type"`
+	Subject    string     `json:"subject,omitempty"`
+	Status     JobStatus  `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Result     string     `json:"result,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Events     int        `json:"events"`
+	// Usage is the token usage of the finished job.
+	Usage *usage.Report `json:"usage,omitempty"`
+}
+
+// Job is a generation running in the background with its own context and
+// output directory. Its events are kept so that late subscribers can replay them.
+type Job struct {
+	id        string
+	request   JobRequest
+	outputDir string
+	createdAt time.Time
+
+	status     JobStatus
+	err        error
+	result     string
+	usage      *usage.Report
+	startedAt  time.Time
+	finishedAt time.Time
+	done       bool
+	events     []JobEvent
+	changed    chan struct{}
+	cancel     func()
+	// kb is the knowledge base of the job, from which the fix jobs started
+	// from it get their documents.
+	kb article.KnowledgeBase
+
+	mu sync.Mutex
+}
+
+func newJob(id string, req JobRequest, outputDir string) *Job {
+	return &Job{
+		id:        id,
+		request:   req,
+		outputDir: outputDir,
+		createdAt: time.Now(),
+		status:    JobStatusPending,
+		events:    make([]JobEvent, 0),
+		changed:   make(chan struct{}),
+	}
+}
+
+func (j *Job) ID() string {
+	return j.id
+}
+
+func (j *Job) OutputDir() string {
+	return j.outputDir
+}
+
+func (j *Job) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info := JobInfo{
+		ID:        j.id,
+		Type:      j.request.Type,
+		Subject:   j.request.Subject,
+		Status:    j.status,
+		Result:    j.result,
+		CreatedAt: j.createdAt,
+		Events:    len(j.events),
+		Usage:     j.usage,
+	}
+	if j.err != nil {
+		info.Error = j.err.Error()
+	}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		info.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		info.FinishedAt = &finishedAt
+	}
+
+	return info
+}
+
+// Finished reports whether the job returned.
+func (j *Job) Finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finished()
+}
+
+func (j *Job) finished() bool {
+	return j.done
+}
+
+func (j *Job) knowledgeBase() article.KnowledgeBase {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.kb
+}
+
+func (j *Job) setKnowledgeBase(kb article.KnowledgeBase) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.kb = kb
+}
+
+// Cancel stops the job. Returns false if it already finished.
+func (j *Job) Cancel() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.done || j.status == JobStatusCanceled {
+		return false
+	}
+
+	j.status = JobStatusCanceled
+	if j.cancel != nil {
+		j.cancel()
+	}
+
+	return true
+}
+
+// Events returns the events recorded after the given event id, a channel
+// closed when new events are recorded or the job finishes, and whether the
+// job is finished.
+func (j *Job) Events(after int) ([]JobEvent, <-chan struct{}, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if after < 0 {
+		after = 0
+	}
+	if after > len(j.events) {
+		after = len(j.events)
+	}
+
+	events := make([]JobEvent, len(j.events)-after)
+	copy(events, j.events[after:])
+
+	return events, j.changed, j.finished()
+}
+
+func (j *Job) start(cancel func()) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.status != JobStatusPending {
+		cancel()
+		return false
+	}
+
+	j.status = JobStatusRunning
+	j.startedAt = time.Now()
+	j.cancel = cancel
+
+	return true
+}
+
+func (j *Job) finish(result string, report *usage.Report, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.done = true
+	j.finishedAt = time.Now()
+	j.result = result
+	j.usage = report
+
+	switch {
+	case j.status == JobStatusCanceled:
+		j.err = err
+	case err != nil:
+		j.status = JobStatusFailed
+		j.err = err
+	default:
+		j.status = JobStatusSucceeded
+	}
+
+	j.notify()
+}
+
+// emit records the event. It implements agent.EmitFunc.
+func (j *Job) emit(evt agent.Event) error {
+	switch evt.Type() {
+	case agent.EventTypeTextDelta, agent.EventTypeComplete:
+		// Streamed prose and intermediate JSON results are too verbose to be replayed
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.events = append(j.events, JobEvent{
+		ID:   len(j.events) + 1,
+		Type: evt.Type(),
+		Time: time.Now(),
+		Data: eventData(evt),
+	})
+
+	j.notify()
+
+	return nil
+}
+
+// notify wakes up the subscribers waiting for changes.
+// The caller must hold the lock.
+func (j *Job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// eventData returns the event payload if it can be encoded as JSON, its
+// textual representation otherwise.
+func eventData(evt agent.Event) any {
+	data := evt.Data()
+	if _, err := json.Marshal(data); err != nil {
+		return fmt.Sprintf("%+v", data)
+	}
+	return data
+}
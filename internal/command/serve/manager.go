@@ -0,0 +1,389 @@
+package serve
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/output"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/scraper"
+	"github.com/bornholm/ghostwriter/pkg/search"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobNotFinished = errors.New("job not finished")
+)
+
+// Manager runs the submitted jobs. All jobs share the LLM client (and thus its
+// rate limiter) and the web clients, but each job has its own knowledge base.
+type Manager struct {
+	ctx  context.Context
+	opts ManagerOptions
+
+	jobs map[string]*Job
+	wg   sync.WaitGroup
+	mu   sync.RWMutex
+}
+
+// ManagerOptions configures the Manager.
+type ManagerOptions struct {
+	Client       llm.Client
+	Scraper      scraper.Scraper
+	SearchClient search.Client
+	// DataDir receives one output directory per job.
+	DataDir string
+	// CorpusStoragePath receives one Corpus data directory per job, so that
+	// the documents of a job never show up in another.
+	CorpusStoragePath string
+	JobTimeout        time.Duration
+	ChromiumPath      string
+	NoSandbox         bool
+	// Locale is the language of the phase names and of the generated
+	// document headings.
+	Locale locale.Locale
+	// Pricing estimates the cost of the tokens used by each job.
+	Pricing usage.Pricing
+	// Budget limits the usage of each job.
+	Budget usage.Budget
+}
+
+// NewManager returns a job manager. Jobs are canceled when ctx is done.
+func NewManager(ctx context.Context, opts ManagerOptions) *Manager {
+	return &Manager{
+		ctx:  ctx,
+		opts: opts,
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Submit validates the request and starts the job in the background.
+func (m *Manager) Submit(req JobRequest) (*Job, error) {
+	if err := req.normalize(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var source *Job
+	if req.Type == JobTypeFix {
+		var err error
+		source, err = m.Get(req.SourceJob)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if !source.Finished() {
+			return nil, errors.Wrapf(ErrJobNotFinished, "source job %q", req.SourceJob)
+		}
+		if source.request.Type == JobTypeArticle {
+			return nil, errors.Errorf("source job %q is not a whitepaper", req.SourceJob)
+		}
+		req.Subject = source.request.Subject
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	job := newJob(id, req, filepath.Join(m.opts.DataDir, id))
+
+	m.mu.Lock()
+	m.jobs[id] = job
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.run(job, source)
+	}()
+
+	return job, nil
+}
+
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return nil, errors.Wrapf(ErrJobNotFound, "no job with id %q", id)
+	}
+
+	return job, nil
+}
+
+// List returns the jobs, most recent first.
+func (m *Manager) List() []*Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].createdAt.After(jobs[j].createdAt)
+	})
+
+	return jobs
+}
+
+// Wait blocks until all jobs returned.
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Manager) run(job *Job, source *Job) {
+	ctx, cancel := context.WithTimeout(m.ctx, m.opts.JobTimeout)
+	defer cancel()
+
+	if !job.start(cancel) {
+		// Canceled before it started
+		job.finish("", nil, context.Canceled)
+		return
+	}
+
+	logger := slog.With(slog.String("job", job.id), slog.String("type", string(job.request.Type)))
+	logger.Info("job started")
+
+	tracker := usage.NewTracker(m.opts.Pricing, usage.WithBudget(m.opts.Budget))
+	tracker.EmitTo(job.emit)
+	ctx = usage.WithContextTracker(ctx, tracker)
+
+	var (
+		result string
+		err    error
+	)
+
+	if err = os.MkdirAll(job.outputDir, 0755); err == nil {
+		result, err = m.runJob(ctx, job, source)
+	}
+
+	if err != nil {
+		logger.Error("job failed", slog.Any("error", err))
+	} else {
+		logger.Info("job done")
+	}
+
+	report := shared.ReportUsage(tracker, filepath.Join(job.outputDir, usage.FileName), job.emit)
+
+	job.finish(result, report, err)
+}
+
+// runJob runs the job with a knowledge base of its own, holding the documents
+// of the request and, for a fix job, those of its source job.
+func (m *Manager) runJob(ctx context.Context, job *Job, source *Job) (string, error) {
+	kb, kbClose, err := shared.BuildKnowledgeBase(ctx, filepath.Join(m.opts.CorpusStoragePath, job.id), shared.Collection{})
+	if err != nil {
+		return "", errors.Wrap(err, "could not create knowledge base")
+	}
+	defer func() { _ = kbClose() }()
+
+	job.setKnowledgeBase(kb)
+
+	docs := make([]article.ResearchDocument, 0, len(job.request.Documents))
+	if source != nil {
+		if sourceKB := source.knowledgeBase(); sourceKB != nil {
+			docs = append(docs, sourceKB.GetAllDocuments()...)
+		}
+	}
+	for _, doc := range job.request.Documents {
+		docs = append(docs, doc.researchDocument())
+	}
+
+	for _, doc := range docs {
+		if err := kb.AddDocument(doc); err != nil {
+			return "", errors.Wrap(err, "could not bootstrap knowledge base")
+		}
+	}
+
+	switch job.request.Type {
+	case JobTypeWhitepaper:
+		return m.runWhitepaper(ctx, job, kb)
+	case JobTypeArticle:
+		return m.runArticle(ctx, job, kb)
+	case JobTypeFix:
+		return m.runFix(ctx, job, source, kb)
+	default:
+		return "", errors.Errorf("unknown job type %q", job.request.Type)
+	}
+}
+
+func (m *Manager) runWhitepaper(ctx context.Context, job *Job, kb article.KnowledgeBase) (string, error) {
+	req := job.request
+
+	opts := []wppkg.OrchestratorOptionFunc{
+		wppkg.WithTargetWordCount(req.TargetWords),
+		wppkg.WithResearchDepth(article.ResearchDepth(req.ResearchDepth)),
+		wppkg.WithOutputDir(job.outputDir),
+		wppkg.WithChromiumPath(m.opts.ChromiumPath),
+		wppkg.WithNoSandbox(m.opts.NoSandbox),
+		wppkg.WithMaxReviewRounds(req.MaxReviewRounds),
+		wppkg.WithSkipResearch(req.SkipResearch),
+		wppkg.WithModelName(llmclient.ModelName()),
+		wppkg.WithKnowledgeBase(kb),
+		wppkg.WithScraper(m.opts.Scraper),
+		wppkg.WithSearchClient(m.opts.SearchClient),
+		wppkg.WithLocale(m.opts.Locale),
+	}
+
+	if req.Plan != nil {
+		opts = append(opts, wppkg.WithPlan(req.Plan))
+	}
+	if req.HTML {
+		opts = append(opts, wppkg.WithRenderHTML(filepath.Join(job.outputDir, "whitepaper.html")))
+	}
+	if req.PDF {
+		opts = append(opts, wppkg.WithRenderPDF(filepath.Join(job.outputDir, "whitepaper.pdf")))
+	}
+	if req.StyleGuide != "" {
+		opts = append(opts, wppkg.WithStyleGuidelines(req.StyleGuide))
+	}
+	if req.AdditionalContext != "" {
+		opts = append(opts, wppkg.WithAdditionalContext(req.AdditionalContext))
+	}
+
+	result, err := wppkg.WriteWhitePaper(ctx, m.opts.Client, req.Subject, job.emit, opts...)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate white paper")
+	}
+
+	return relativeResult(job.outputDir, result.Entrypoint), nil
+}
+
+func (m *Manager) runArticle(ctx context.Context, job *Job, kb article.KnowledgeBase) (string, error) {
+	req := job.request
+
+	opts := []article.OrchestratorOptionFunc{
+		article.WithTargetWordCount(req.TargetWords),
+		article.WithResearchDepth(article.ResearchDepth(req.ResearchDepth)),
+		article.WithMaxReviewRounds(req.MaxReviewRounds),
+		article.WithSkipResearch(req.SkipResearch),
+		article.WithKnowledgeBase(kb),
+		article.WithScraper(m.opts.Scraper),
+		article.WithSearchClient(m.opts.SearchClient),
+	}
+
+	if req.StyleGuide != "" {
+		opts = append(opts, article.WithStyleGuidelines(req.StyleGuide))
+	}
+	if req.AdditionalContext != "" {
+		opts = append(opts, article.WithAdditionalContext(req.AdditionalContext))
+	}
+
+	ctx = article.WithProgressTracking(ctx, func(evt article.ProgressEvent) {
+		_ = job.emit(output.NewArticleProgressEvent(evt))
+	})
+
+	document, err := article.WriteArticle(ctx, m.opts.Client, req.Subject, job.emit, opts...)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate article")
+	}
+
+	output := "article.md"
+	if err := os.WriteFile(filepath.Join(job.outputDir, output), []byte(article.FormatMarkdown(document)), 0644); err != nil {
+		return "", errors.Wrap(err, "failed to write article")
+	}
+
+	return output, nil
+}
+
+func (m *Manager) runFix(ctx context.Context, job *Job, source *Job, kb article.KnowledgeBase) (string, error) {
+	req := job.request
+
+	if err := copyDir(source.outputDir, job.outputDir); err != nil {
+		return "", errors.Wrap(err, "could not copy source job output")
+	}
+
+	opts := []wppkg.FixOptionFunc{
+		wppkg.WithFixInputDir(job.outputDir),
+		wppkg.WithFixForceEnrichment(req.Enrich),
+		wppkg.WithFixKnowledgeBase(kb),
+		wppkg.WithFixLocale(m.opts.Locale),
+	}
+
+	if req.StyleGuide != "" {
+		opts = append(opts, wppkg.WithFixStyleGuidelines(req.StyleGuide))
+	}
+	if req.AdditionalContext != "" {
+		opts = append(opts, wppkg.WithFixAdditionalContext(req.AdditionalContext))
+	}
+
+	if _, err := wppkg.FixWhitePaperInDir(ctx, m.opts.Client, job.emit, opts...); err != nil {
+		return "", errors.Wrap(err, "failed to fix white paper")
+	}
+
+	return "index.md", nil
+}
+
+func relativeResult(dir string, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+func newJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// copyDir copies the regular files of src into dst, preserving the tree.
+func copyDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return errors.WithStack(os.MkdirAll(target, 0755))
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer in.Close()
+
+		out, err := os.Create(target)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer out.Close()
+
+		if _, err := io.Copy(out, in); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return errors.WithStack(out.Close())
+	})
+}
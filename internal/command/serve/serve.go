@@ -0,0 +1,164 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func Serve() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Run an HTTP server exposing a job API to write white papers and articles",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "address",
+				Value:   "127.0.0.1:8080",
+				Aliases: []string{"a"},
+				Usage:   "Address the HTTP server listens on (a --token is required unless it is a loopback address)",
+				EnvVars: []string{"GHOSTWRITER_SERVE_ADDRESS"},
+			},
+			&cli.StringFlag{
+				Name:    "data-dir",
+				Value:   "jobs",
+				Usage:   "Directory where each job gets its own output directory",
+				EnvVars: []string{"GHOSTWRITER_SERVE_DATA_DIR"},
+			},
+			&cli.StringFlag{
+				Name:    "token",
+				Value:   "",
+				Usage:   "Bearer token required to call the API (disabled when empty, only allowed on a loopback address)",
+				EnvVars: []string{"GHOSTWRITER_SERVE_TOKEN"},
+			},
+			&cli.DurationFlag{
+				Name:    "job-timeout",
+				Value:   2 * time.Hour,
+				Usage:   "Maximum duration of a job",
+				EnvVars: []string{"GHOSTWRITER_SERVE_JOB_TIMEOUT", "GHOSTWRITER_TIMEOUT"},
+			},
+			&cli.StringFlag{
+				Name:    "corpus-storage-path",
+				Value:   ".corpus",
+				Usage:   "Directory where each job gets its own Corpus data directory",
+				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
+			},
+			&cli.StringFlag{
+				Name:    "chromium-path",
+				Value:   "",
+				EnvVars: []string{"GHOSTWRITER_CHROMIUM_PATH"},
+			},
+			&cli.BoolFlag{
+				Name:    "no-sandbox",
+				Value:   false,
+				EnvVars: []string{"GHOSTWRITER_NO_SANDBOX"},
+			},
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
+		},
+		Action: func(cliCtx *cli.Context) error {
+			address := cliCtx.String("address")
+			dataDir := cliCtx.String("data-dir")
+			token := cliCtx.String("token")
+
+			if token == "" && !shared.IsLoopback(address) {
+				return errors.Errorf("refusing to serve the api on %q without authentication: set a --token or listen on a loopback address", address)
+			}
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			ctx, stop := signal.NotifyContext(cliCtx.Context, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			pricing, budget, err := shared.UsageSettings(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			resilientClient, err := llmclient.NewClient(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to create llm client")
+			}
+
+			if err := os.MkdirAll(dataDir, 0755); err != nil {
+				return errors.Wrap(err, "could not create data directory")
+			}
+
+			// Jobs outlive the requests that submitted them but not the server.
+			// They keep the values of the command context, e.g. the date of a
+			// replayed LLM cassette.
+			jobsCtx, cancelJobs := context.WithCancel(context.WithoutCancel(cliCtx.Context))
+			defer cancelJobs()
+
+			webScraper, searchClient, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			manager := NewManager(jobsCtx, ManagerOptions{
+				Client:            resilientClient,
+				Scraper:           webScraper,
+				SearchClient:      searchClient,
+				DataDir:           dataDir,
+				CorpusStoragePath: cliCtx.String("corpus-storage-path"),
+				JobTimeout:        cliCtx.Duration("job-timeout"),
+				ChromiumPath:      cliCtx.String("chromium-path"),
+				NoSandbox:         cliCtx.Bool("no-sandbox"),
+				Locale:            l,
+				Pricing:           pricing,
+				Budget:            budget,
+			})
+
+			server := &http.Server{
+				Addr:              address,
+				Handler:           NewHandler(manager, token),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			errs := make(chan error, 1)
+			go func() {
+				errs <- server.ListenAndServe()
+			}()
+
+			fmt.Printf("✓ %s\n", l.T(locale.ServeStarted, address))
+
+			select {
+			case err := <-errs:
+				if !errors.Is(err, http.ErrServerClosed) {
+					return errors.Wrap(err, "http server failed")
+				}
+			case <-ctx.Done():
+			}
+
+			slog.Info("shutting down, canceling running jobs")
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			cancelJobs()
+			err = server.Shutdown(shutdownCtx)
+			manager.Wait()
+
+			return errors.WithStack(err)
+		},
+	}
+}
+
+func Root() *cli.Command {
+	return Serve()
+}
@@ -0,0 +1,16 @@
+package shared
+
+import "net"
+
+// IsLoopback reports whether the address only accepts local connections.
+func IsLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -5,27 +5,61 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/bornholm/corpus/pkg/corpus"
+	"github.com/bornholm/corpus/pkg/model"
+	"github.com/bornholm/genai/llm"
 	"github.com/bornholm/genai/llm/provider"
 	providerenv "github.com/bornholm/genai/llm/provider/env"
 	"github.com/bornholm/ghostwriter/internal/command/llmclient"
 	"github.com/bornholm/ghostwriter/pkg/article"
 	corpusadapter "github.com/bornholm/ghostwriter/pkg/knowledgebase/corpus"
+	"github.com/gosimple/slug"
 	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
 )
 
 // BuildKnowledgeBase creates a Corpus-backed knowledge base stored at storagePath.
 // A dedicated LLM client is first attempted via GHOSTWRITER_CORPUS_* env vars.
 // If those vars are absent, it falls back to the main GHOSTWRITER_* provider.
 // Corpus can also run without an LLM client (disabling vector search, HyDE and Judge).
-func BuildKnowledgeBase(ctx context.Context, storagePath string) (article.KnowledgeBase, func() error, error) {
-	corpusLLMClient, err := provider.Create(ctx, providerenv.With("GHOSTWRITER_CORPUS_", ".env"))
+// GHOSTWRITER_CORPUS_TEMPERATURE forces the temperature of its chat completions.
+// Its calls, the embeddings included, are recorded to and replayed from the
+// cassette of the main client, so that a replayed run retrieves the same
+// documents as the recorded one.
+// The documents are added to the collection selected by collection. The
+// returned function saves them, for the next runs using the same collection
+// name: call it once done.
+func BuildKnowledgeBase(ctx context.Context, storagePath string, collection Collection) (article.KnowledgeBase, func() error, error) {
+	_, replay, err := llmclient.CassettePaths()
 	if err != nil {
-		corpusLLMClient, _ = provider.Create(ctx, providerenv.With("GHOSTWRITER_", ".env"))
+		return nil, nil, errors.WithStack(err)
 	}
-	if corpusLLMClient != nil {
-		corpusLLMClient = llmclient.Wrap(corpusLLMClient)
+
+	var corpusLLMClient llm.Client
+	corpusModel := llmclient.PrefixedModelName("GHOSTWRITER_CORPUS_")
+	if replay == "" {
+		corpusLLMClient, err = provider.Create(ctx, providerenv.With("GHOSTWRITER_CORPUS_", ".env"))
+		if err != nil {
+			corpusLLMClient, _ = provider.Create(ctx, providerenv.With("GHOSTWRITER_", ".env"))
+			corpusModel = llmclient.ModelName()
+		}
+	} else if corpusModel == "" {
+		corpusModel = llmclient.ModelName()
+	}
+	if corpusLLMClient != nil || replay != "" {
+		temperature, err := llmclient.PrefixedTemperature("GHOSTWRITER_CORPUS_")
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		wrapped, err := llmclient.Wrap(corpusLLMClient, corpusModel)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		// The temperature is forced above the middleware so that it is part of
+		// the cached and recorded requests
+		corpusLLMClient = llmclient.WithTemperature(wrapped, temperature)
 	}
 
 	if err := os.MkdirAll(storagePath, 0755); err != nil {
@@ -46,35 +80,140 @@ func BuildKnowledgeBase(ctx context.Context, storagePath string) (article.Knowle
 		return nil, nil, errors.Wrap(err, "could not initialise corpus")
 	}
 
-	collectionID, err := c.CreateCollection(ctx, "ghostwriter")
+	if collection.Name == "" {
+		collectionID, err := c.CreateCollection(ctx, "ghostwriter")
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "could not create corpus collection")
+		}
+
+		kb := corpusadapter.New(c, collectionID)
+		return kb, kb.Close, nil
+	}
+
+	name := slug.Make(collection.Name)
+	if name == "" {
+		return nil, nil, errors.Errorf("invalid knowledge base collection name %q", collection.Name)
+	}
+
+	collectionID, err := loadOrCreateCollection(ctx, c, storagePath, name, collection.Reset)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	kb := corpusadapter.New(c, collectionID,
+		corpusadapter.WithCacheFile(filepath.Join(storagePath, "ghostwriter-documents-"+name+".json")),
+	)
+	return kb, kb.Close, nil
+}
+
+// DefaultCollection is the collection of the kb command when no other one is
+// selected.
+const DefaultCollection = "default"
+
+// Collection selects the knowledge base collection of a run.
+type Collection struct {
+	// Name is the name of a collection kept in the storage directory and
+	// reused by the next runs with the same name. A run without name gets a
+	// fresh collection which is not kept.
+	Name string
+	// Reset replaces the collection kept under Name with a fresh one.
+	Reset bool
+}
+
+// RunCollection returns the collection of a run about subject: the collection
+// named with the --collection flag if set, the collection of the subject
+// otherwise. The collection of the subject starts afresh unless reuse is true,
+// e.g. to rely on the documents of a previous research or an interrupted run.
+func RunCollection(cliCtx *cli.Context, subject string, reuse bool) Collection {
+	if name := cliCtx.String("collection"); name != "" {
+		return Collection{Name: name}
+	}
+	return Collection{Name: subject, Reset: !reuse}
+}
+
+// CollectionFlag returns the --collection flag shared by the commands.
+func CollectionFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:    "collection",
+		Value:   "",
+		Usage:   "Name of the knowledge base collection to use and keep in the corpus storage, to share documents between runs",
+		EnvVars: []string{"GHOSTWRITER_CORPUS_COLLECTION"},
+	}
+}
+
+// loadOrCreateCollection returns the collection kept under name in the
+// storage directory, or creates it, so that its documents remain searchable by
+// the next runs. reset replaces a kept collection with a new one.
+func loadOrCreateCollection(ctx context.Context, c *corpus.Corpus, storagePath string, name string, reset bool) (model.CollectionID, error) {
+	idPath := filepath.Join(storagePath, "ghostwriter-collection-"+name)
+
+	if !reset {
+		data, err := os.ReadFile(idPath)
+		if err == nil {
+			if id := strings.TrimSpace(string(data)); id != "" {
+				return model.CollectionID(id), nil
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", errors.Wrap(err, "could not read corpus collection id")
+		}
+	}
+
+	collectionID, err := c.CreateCollection(ctx, "ghostwriter-"+name)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "could not create corpus collection")
+		return "", errors.Wrap(err, "could not create corpus collection")
 	}
 
-	kb := corpusadapter.New(c, collectionID)
-	return kb, func() error { return nil }, nil
+	if err := os.WriteFile(idPath, []byte(collectionID), 0644); err != nil {
+		return "", errors.Wrap(err, "could not save corpus collection id")
+	}
+
+	// The documents of the replaced collection must not be listed anymore
+	if reset {
+		cachePath := filepath.Join(storagePath, "ghostwriter-documents-"+name+".json")
+		if err := os.Remove(cachePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", errors.Wrap(err, "could not remove knowledge base cache")
+		}
+	}
+
+	return collectionID, nil
 }
 
 // BootstrapKnowledgeBase adds files from the given glob patterns to the knowledge base.
 func BootstrapKnowledgeBase(kb article.KnowledgeBase, files []string) error {
-	for _, f := range files {
+	docs, err := FileDocuments(files)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, doc := range docs {
+		if err := kb.AddDocument(doc); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// FileDocuments reads the files matching the given glob patterns and returns
+// them as research documents identified by their file:// URL.
+func FileDocuments(patterns []string) ([]article.ResearchDocument, error) {
+	docs := make([]article.ResearchDocument, 0)
+	for _, f := range patterns {
 		matches, err := filepath.Glob(f)
 		if err != nil {
-			return errors.Wrapf(err, "could not match file pattern '%s'", f)
+			return nil, errors.Wrapf(err, "could not match file pattern '%s'", f)
 		}
 		for _, m := range matches {
 			absPath, err := filepath.Abs(m)
 			if err != nil {
-				return errors.Wrapf(err, "could not retrieve absolute path for file '%s'", m)
+				return nil, errors.Wrapf(err, "could not retrieve absolute path for file '%s'", m)
 			}
 
 			data, err := os.ReadFile(m)
 			if err != nil {
-				return errors.Wrapf(err, "could not read file '%s'", m)
+				return nil, errors.Wrapf(err, "could not read file '%s'", m)
 			}
 
 			u := &url.URL{Scheme: "file", Path: absPath}
-			err = kb.AddDocument(article.ResearchDocument{
+			docs = append(docs, article.ResearchDocument{
 				URL:        u.String(),
 				Title:      filepath.Base(m),
 				Content:    string(data),
@@ -82,10 +221,7 @@ func BootstrapKnowledgeBase(kb article.KnowledgeBase, files []string) error {
 				SourceType: "file",
 				Relevance:  1,
 			})
-			if err != nil {
-				return errors.WithStack(err)
-			}
 		}
 	}
-	return nil
+	return docs, nil
 }
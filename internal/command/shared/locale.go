@@ -0,0 +1,27 @@
+package shared
+
+import (
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+// LocaleFlag returns the --locale flag shared by the commands.
+func LocaleFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:    "locale",
+		Value:   string(locale.Default),
+		Usage:   "Language of the terminal messages and of the generated document headings: en or fr",
+		EnvVars: []string{"GHOSTWRITER_LOCALE"},
+	}
+}
+
+// Locale returns the locale selected with the --locale flag.
+func Locale(cliCtx *cli.Context) (locale.Locale, error) {
+	l, err := locale.Parse(cliCtx.String("locale"))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return l, nil
+}
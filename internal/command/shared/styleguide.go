@@ -0,0 +1,34 @@
+package shared
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+// StyleGuideFlag returns the --style-guide flag shared by the commands.
+func StyleGuideFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:    "style-guide",
+		Value:   "",
+		Aliases: []string{"g"},
+		Usage:   "Path to a style guide file (inline text is used as is when no such file exists)",
+		EnvVars: []string{"GHOSTWRITER_STYLE_GUIDE"},
+	}
+}
+
+// ReadStyleGuide returns the content of the style guide file at the given path
+// or, when no such file exists, the value itself. This allows the style guide
+// to be passed inline, e.g. through GHOSTWRITER_STYLE_GUIDE.
+func ReadStyleGuide(value string) (string, error) {
+	data, err := os.ReadFile(value)
+	if err == nil {
+		return string(data), nil
+	}
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ENAMETOOLONG) {
+		return value, nil
+	}
+	return "", errors.Wrap(err, "failed to read style guide")
+}
@@ -0,0 +1,84 @@
+package shared
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+// MaxTokensFlag returns the --max-tokens flag shared by the commands.
+func MaxTokensFlag() *cli.Int64Flag {
+	return &cli.Int64Flag{
+		Name:    "max-tokens",
+		Usage:   "Stop the run gracefully before its LLM calls use more tokens than this limit (0 for no limit)",
+		EnvVars: []string{"GHOSTWRITER_MAX_TOKENS"},
+	}
+}
+
+// MaxCostFlag returns the --max-cost flag shared by the commands.
+func MaxCostFlag() *cli.Float64Flag {
+	return &cli.Float64Flag{
+		Name:    "max-cost",
+		Usage:   "Stop the run gracefully before its estimated cost exceeds this limit, in the currency of the pricing table (0 for no limit)",
+		EnvVars: []string{"GHOSTWRITER_MAX_COST"},
+	}
+}
+
+// UsageSettings returns the pricing table of the GHOSTWRITER_PRICING
+// environment variable, usually set through the pricing section of a
+// configuration profile, and the budget given with the --max-tokens and --max-cost flags.
+func UsageSettings(cliCtx *cli.Context) (usage.Pricing, usage.Budget, error) {
+	pricing, err := usage.ParsePricing(os.Getenv("GHOSTWRITER_PRICING"))
+	if err != nil {
+		return nil, usage.Budget{}, errors.Wrap(err, "invalid GHOSTWRITER_PRICING")
+	}
+
+	budget := usage.Budget{
+		MaxTokens: cliCtx.Int64("max-tokens"),
+		MaxCost:   cliCtx.Float64("max-cost"),
+	}
+
+	if budget.MaxTokens < 0 || budget.MaxCost < 0 {
+		return nil, usage.Budget{}, errors.New("--max-tokens and --max-cost must not be negative")
+	}
+
+	if budget.MaxCost > 0 && len(pricing) == 0 {
+		return nil, usage.Budget{}, errors.New("--max-cost requires a pricing table")
+	}
+
+	return pricing, budget, nil
+}
+
+// NewUsageTracker returns a tracker pricing the LLM calls and enforcing the
+// budget of UsageSettings.
+func NewUsageTracker(cliCtx *cli.Context) (*usage.Tracker, error) {
+	pricing, budget, err := UsageSettings(cliCtx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return usage.NewTracker(pricing, usage.WithBudget(budget)), nil
+}
+
+// ReportUsage writes the usage report of the run to path and emits its
+// summary. It returns nil when no LLM call was made. Failing to write the
+// report is logged, as it must not fail a run that produced its documents.
+func ReportUsage(tracker *usage.Tracker, path string, emit agent.EmitFunc) *usage.Report {
+	report := tracker.Report()
+	if report.Calls == 0 {
+		return nil
+	}
+
+	if err := report.WriteFile(path); err != nil {
+		slog.Warn("could not write usage report", slog.String("path", path), slog.Any("error", err))
+		path = ""
+	}
+
+	_ = emit(usage.NewSummaryEvent(report, path))
+
+	return &report
+}
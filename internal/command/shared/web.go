@@ -0,0 +1,222 @@
+package shared
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bornholm/ghostwriter/pkg/scraper"
+	"github.com/bornholm/ghostwriter/pkg/scraper/chromedp"
+	"github.com/bornholm/ghostwriter/pkg/scraper/surf"
+	"github.com/bornholm/ghostwriter/pkg/search"
+	"github.com/bornholm/ghostwriter/pkg/search/duckduckgo"
+	"github.com/bornholm/ghostwriter/pkg/search/google"
+	"github.com/bornholm/ghostwriter/pkg/search/meta"
+	"github.com/bornholm/ghostwriter/pkg/search/searx"
+	"github.com/pkg/errors"
+)
+
+const (
+	ScraperSurf     = "surf"
+	ScraperHTTP     = "http"
+	ScraperChromedp = "chromedp"
+
+	SearchEngineDuckDuckGo = "duckduckgo"
+	SearchEngineSearx      = "searx"
+	SearchEngineSearXNG    = "searxng"
+	SearchEngineGoogle     = "google"
+)
+
+const (
+	SearchEnginesEnvVar = "GHOSTWRITER_SEARCH_ENGINES"
+	GoogleAPIKeyEnvVar  = "GHOSTWRITER_GOOGLE_API_KEY"
+	GoogleCXEnvVar      = "GHOSTWRITER_GOOGLE_CX"
+	SearXNGURLsEnvVar   = "GHOSTWRITER_SEARXNG_URLS"
+
+	SearXNGJSONEnvVar       = "GHOSTWRITER_SEARXNG_JSON"
+	SearXNGAuthHeaderEnvVar = "GHOSTWRITER_SEARXNG_AUTH_HEADER"
+	SearXNGLanguageEnvVar   = "GHOSTWRITER_SEARXNG_LANGUAGE"
+	SearXNGCategoriesEnvVar = "GHOSTWRITER_SEARXNG_CATEGORIES"
+	SearXNGTimeRangeEnvVar  = "GHOSTWRITER_SEARXNG_TIME_RANGE"
+	SearXNGSafeSearchEnvVar = "GHOSTWRITER_SEARXNG_SAFE_SEARCH"
+)
+
+// Every search engine retries a failed search on its own before the results
+// of the engines are merged.
+const (
+	searchMaxRetries = 2
+	searchRetryDelay = 2 * time.Second
+)
+
+// NewWebClients returns the scraper and the search client selected with the
+// GHOSTWRITER_SCRAPER and GHOSTWRITER_SEARCH_ENGINES environment variables,
+// usually set through the --search-engine flag or a configuration profile.
+// The returned function releases the scraper.
+func NewWebClients() (scraper.Scraper, search.Client, func(), error) {
+	webScraper, closeScraper, err := newScraper(os.Getenv("GHOSTWRITER_SCRAPER"))
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	searchClient, err := newSearchClient(os.Getenv(SearchEnginesEnvVar), webScraper)
+	if err != nil {
+		closeScraper()
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	return webScraper, searchClient, closeScraper, nil
+}
+
+func newScraper(name string) (scraper.Scraper, func(), error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", ScraperSurf:
+		return surf.NewScraper(), func() {}, nil
+	case ScraperHTTP:
+		return scraper.NewHTTPScraper(http.DefaultClient), func() {}, nil
+	case ScraperChromedp:
+		s, err := chromedp.NewScraper(true)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "could not start chromedp scraper")
+		}
+		return s, s.Close, nil
+	default:
+		return nil, nil, errors.Errorf("unknown scraper %q (expected %q, %q or %q)", name, ScraperSurf, ScraperHTTP, ScraperChromedp)
+	}
+}
+
+// newSearchClient parses a comma-separated list of search engines, DuckDuckGo
+// by default. Several engines are queried together through the meta client,
+// which ranks the results with reciprocal rank fusion.
+func newSearchClient(names string, webScraper scraper.Scraper) (search.Client, error) {
+	engines := make([]meta.Engine, 0)
+	seen := make(map[string]struct{})
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == SearchEngineSearXNG {
+			name = SearchEngineSearx
+		}
+		if _, exists := seen[name]; exists {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		client, err := newSearchEngine(name, webScraper)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		engines = append(engines, meta.Engine{
+			Name:   name,
+			Client: search.WithRetry(client, searchMaxRetries, searchRetryDelay),
+		})
+	}
+
+	switch len(engines) {
+	case 0:
+		return search.WithRetry(duckduckgo.NewClient(webScraper), searchMaxRetries, searchRetryDelay), nil
+	case 1:
+		return engines[0].Client, nil
+	default:
+		return meta.NewClientWithEngines(engines), nil
+	}
+}
+
+func newSearchEngine(name string, webScraper scraper.Scraper) (search.Client, error) {
+	switch name {
+	case SearchEngineDuckDuckGo:
+		return duckduckgo.NewClient(webScraper), nil
+
+	case SearchEngineSearx:
+		opts, err := searXNGOptions()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return searx.NewClient(opts...), nil
+
+	case SearchEngineGoogle:
+		apiKey, cx := os.Getenv(GoogleAPIKeyEnvVar), os.Getenv(GoogleCXEnvVar)
+		if apiKey == "" || cx == "" {
+			return nil, errors.Errorf("the google search engine requires %s and %s", GoogleAPIKeyEnvVar, GoogleCXEnvVar)
+		}
+		return google.NewClient(apiKey, cx), nil
+
+	default:
+		return nil, errors.Errorf("unknown search engine %q (expected %q, %q or %q)", name, SearchEngineDuckDuckGo, SearchEngineSearXNG, SearchEngineGoogle)
+	}
+}
+
+// searXNGOptions returns the options of the SearXNG client set with the
+// GHOSTWRITER_SEARXNG_* environment variables.
+func searXNGOptions() ([]searx.OptionFunc, error) {
+	instances := splitList(os.Getenv(SearXNGURLsEnvVar))
+	for _, raw := range instances {
+		if u, err := url.Parse(raw); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, errors.Errorf("invalid %s: %q is not an absolute url", SearXNGURLsEnvVar, raw)
+		}
+	}
+
+	opts := []searx.OptionFunc{searx.WithInstances(instances...)}
+
+	if raw := os.Getenv(SearXNGJSONEnvVar); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", SearXNGJSONEnvVar)
+		}
+		if enabled && len(instances) == 0 {
+			return nil, errors.Errorf("%s requires the instances of %s", SearXNGJSONEnvVar, SearXNGURLsEnvVar)
+		}
+		opts = append(opts, searx.WithJSON(enabled))
+	}
+
+	if raw := os.Getenv(SearXNGAuthHeaderEnvVar); raw != "" {
+		name, value, found := strings.Cut(raw, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, errors.Errorf("invalid %s: expected \"Name: value\"", SearXNGAuthHeaderEnvVar)
+		}
+		opts = append(opts, searx.WithHeader(strings.TrimSpace(name), strings.TrimSpace(value)))
+	}
+
+	if language := os.Getenv(SearXNGLanguageEnvVar); language != "" {
+		opts = append(opts, searx.WithLanguage(language))
+	}
+
+	if categories := splitList(os.Getenv(SearXNGCategoriesEnvVar)); len(categories) > 0 {
+		opts = append(opts, searx.WithCategories(categories...))
+	}
+
+	switch timeRange := os.Getenv(SearXNGTimeRangeEnvVar); timeRange {
+	case "":
+	case "day", "week", "month", "year":
+		opts = append(opts, searx.WithTimeRange(timeRange))
+	default:
+		return nil, errors.Errorf("invalid %s %q (expected day, week, month or year)", SearXNGTimeRangeEnvVar, timeRange)
+	}
+
+	if raw := os.Getenv(SearXNGSafeSearchEnvVar); raw != "" {
+		level, err := strconv.Atoi(raw)
+		if err != nil || level < 0 || level > 2 {
+			return nil, errors.Errorf("invalid %s %q (expected 0, 1 or 2)", SearXNGSafeSearchEnvVar, raw)
+		}
+		opts = append(opts, searx.WithSafeSearch(level))
+	}
+
+	return opts, nil
+}
+
+// splitList returns the non-empty items of a comma-separated list.
+func splitList(raw string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
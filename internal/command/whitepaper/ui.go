@@ -8,6 +8,8 @@ import (
 	"charm.land/lipgloss/v2"
 
 	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
 	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
 )
 
@@ -56,14 +58,16 @@ var (
 			Italic(true)
 )
 
-func RenderEvent(evt agent.Event) string {
+// RenderEvent returns the styled terminal output of the event in the given
+// locale, or an empty string for the events that are not displayed.
+func RenderEvent(l locale.Locale, evt agent.Event) string {
 	switch evt.Type() {
 	case agent.EventTypeTextDelta:
 		data := evt.Data().(*agent.TextDeltaData)
 		return data.Delta
 	case agent.EventTypeComplete:
 		data := evt.Data().(*agent.CompleteData)
-		return renderComplete(data)
+		return renderComplete(l, data)
 	case agent.EventTypeToolCallStart:
 		data := evt.Data().(*agent.ToolCallStartData)
 		return renderToolCallStart(data)
@@ -72,22 +76,28 @@ func RenderEvent(evt agent.Event) string {
 		return renderToolCallDone(data)
 	case agent.EventTypeTodoUpdated:
 		data := evt.Data().(*agent.TodoUpdatedData)
-		return renderTodoUpdated(data)
+		return renderTodoUpdated(l, data)
 	case agent.EventTypeReasoning:
 		data := evt.Data().(*agent.ReasoningData)
-		return renderReasoning(data)
+		return renderReasoning(l, data)
 	case agent.EventTypeError:
 		data := evt.Data().(*agent.ErrorData)
-		return renderError(data)
+		return renderError(l, data)
 	case wppkg.EventTypePhase:
 		data := evt.Data().(*wppkg.PhaseData)
 		return renderPhase(data)
 	case wppkg.EventTypeChapterStart:
 		data := evt.Data().(*wppkg.ChapterStartData)
-		return renderChapterStart(data)
+		return renderChapterStart(l, data)
 	case wppkg.EventTypeChapterDone:
 		data := evt.Data().(*wppkg.ChapterDoneData)
-		return renderChapterDone(data)
+		return renderChapterDone(l, data)
+	case usage.EventTypeSummary:
+		data := evt.Data().(*usage.SummaryData)
+		return renderUsage(l, data)
+	case usage.EventTypeFallback:
+		data := evt.Data().(*usage.FallbackData)
+		return renderFallback(l, data)
 	default:
 		return ""
 	}
@@ -107,27 +117,59 @@ func renderPhase(data *wppkg.PhaseData) string {
 	return fmt.Sprintf("\n%s %s %s\n", timestamp, icon, titleStyle.Render(data.Name))
 }
 
-func renderChapterStart(data *wppkg.ChapterStartData) string {
+func renderChapterStart(l locale.Locale, data *wppkg.ChapterStartData) string {
 	timestamp := timeStyle.Render(formatTime(time.Now()))
-	chNum := fmt.Sprintf("%d/%d", data.Number, data.Total)
-	title := titleStyle.Render(fmt.Sprintf("Chapitre %s : %s", chNum, data.Title))
-	target := subtleStyle.Render(fmt.Sprintf("cible : %d mots", data.Target))
+	title := titleStyle.Render(l.T(locale.ChapterStart, data.Number, data.Total, data.Title))
+	target := subtleStyle.Render(l.T(locale.ChapterTarget, data.Target))
 	return fmt.Sprintf("\n%s  ─ %s  %s\n", timestamp, title, target)
 }
 
-func renderChapterDone(data *wppkg.ChapterDoneData) string {
+func renderChapterDone(l locale.Locale, data *wppkg.ChapterDoneData) string {
 	check := successStyle.Render("✓")
-	chNum := fmt.Sprintf("%d/%d", data.Number, data.Total)
-	info := infoStyle.Render(fmt.Sprintf("Chapitre %s — %d mots", chNum, data.WordCount))
+	info := infoStyle.Render(l.T(locale.ChapterDone, data.Number, data.Total, data.WordCount))
 	return fmt.Sprintf("  %s %s\n", check, info)
 }
 
-func renderComplete(data *agent.CompleteData) string {
+func renderFallback(l locale.Locale, data *usage.FallbackData) string {
+	timestamp := timeStyle.Render(formatTime(time.Now()))
+	icon := errorStyle.Render("⚠")
+	return fmt.Sprintf("%s %s %s\n", timestamp, icon, subtleStyle.Render(l.T(locale.UsageFallback, data.From, data.To, data.Reason)))
+}
+
+func renderUsage(l locale.Locale, data *usage.SummaryData) string {
+	var b strings.Builder
+
+	header := l.T(locale.UsageSummary, data.Calls, data.TotalTokens, data.PromptTokens, data.CompletionTokens)
+	fmt.Fprintf(&b, "\n  %s %s", infoStyle.Render("Σ"), infoStyle.Render(header))
+	if data.Cost != nil {
+		fmt.Fprintf(&b, " — %s", subtleStyle.Render(l.T(locale.UsageCost, *data.Cost)))
+	}
+	b.WriteString("\n")
+
+	for _, role := range data.Roles {
+		line := l.T(locale.UsageRole, role.Role, role.TotalTokens)
+		if role.Cost != nil {
+			line += " — " + l.T(locale.UsageCost, *role.Cost)
+		}
+		fmt.Fprintf(&b, "    %s\n", subtleStyle.Render(line))
+	}
+
+	if len(data.UnpricedModels) > 0 {
+		fmt.Fprintf(&b, "    %s\n", subtleStyle.Render(l.T(locale.UsageUnpriced, strings.Join(data.UnpricedModels, ", "))))
+	}
+	if data.File != "" {
+		fmt.Fprintf(&b, "    %s\n", subtleStyle.Render(l.T(locale.UsageFile, data.File)))
+	}
+
+	return b.String()
+}
+
+func renderComplete(l locale.Locale, data *agent.CompleteData) string {
 	if data.Message == "" {
 		return ""
 	}
 	timestamp := timeStyle.Render(formatTime(time.Now()))
-	header := successStyle.Render("✓ Terminé")
+	header := successStyle.Render("✓ " + l.T(locale.Completed))
 	return fmt.Sprintf("\n%s %s — %s\n", timestamp, header, data.Message)
 }
 
@@ -167,12 +209,12 @@ func renderToolCallDone(data *agent.ToolCallDoneData) string {
 	return fmt.Sprintf("%s %s\n%s\n", timestamp, header, strings.Join(lines, "\n"))
 }
 
-func renderTodoUpdated(data *agent.TodoUpdatedData) string {
+func renderTodoUpdated(l locale.Locale, data *agent.TodoUpdatedData) string {
 	if len(data.Items) == 0 {
 		return ""
 	}
 	var lines []string
-	lines = append(lines, "\n  "+infoStyle.Render("📋 Tâches"))
+	lines = append(lines, "\n  "+infoStyle.Render("📋 "+l.T(locale.Tasks)))
 	for i, item := range data.Items {
 		var icon string
 		var style lipgloss.Style
@@ -194,12 +236,12 @@ func renderTodoUpdated(data *agent.TodoUpdatedData) string {
 	return strings.Join(lines, "\n") + "\n"
 }
 
-func renderReasoning(data *agent.ReasoningData) string {
+func renderReasoning(l locale.Locale, data *agent.ReasoningData) string {
 	if data.Reasoning == "" {
 		return ""
 	}
 	timestamp := timeStyle.Render(formatTime(time.Now()))
-	header := reasoningStyle.Render("🤔 Raisonnement")
+	header := reasoningStyle.Render("🤔 " + l.T(locale.Reasoning))
 	lines := strings.Split(strings.TrimSpace(data.Reasoning), "\n")
 	// Limit to first 5 lines
 	if len(lines) > 5 {
@@ -212,12 +254,46 @@ func renderReasoning(data *agent.ReasoningData) string {
 	return fmt.Sprintf("\n%s %s\n%s\n", timestamp, header, strings.Join(lines, "\n"))
 }
 
-func renderError(data *agent.ErrorData) string {
+func renderError(l locale.Locale, data *agent.ErrorData) string {
 	timestamp := timeStyle.Render(formatTime(time.Now()))
-	header := errorStyle.Render("✗ Erreur")
+	header := errorStyle.Render("✗ " + l.T(locale.Error))
 	return fmt.Sprintf("\n%s %s — %s\n", timestamp, header, errorStyle.Render(data.Message))
 }
 
 func formatTime(t time.Time) string {
 	return t.Format("15:04:05")
 }
+
+// SummarizeEvent returns a single unstyled line describing the pipeline
+// progress events (phases, chapters, tool calls and errors), or an empty
+// string for the other events.
+func SummarizeEvent(l locale.Locale, evt agent.Event) string {
+	switch data := evt.Data().(type) {
+	case *wppkg.PhaseData:
+		if data.Done {
+			if data.Info != "" {
+				return fmt.Sprintf("✓ %s — %s", data.Name, data.Info)
+			}
+			return fmt.Sprintf("✓ %s", data.Name)
+		}
+		return fmt.Sprintf("▶ %s", data.Name)
+	case *wppkg.ChapterStartData:
+		return l.T(locale.ChapterStart, data.Number, data.Total, data.Title)
+	case *wppkg.ChapterDoneData:
+		return "✓ " + l.T(locale.ChapterDone, data.Number, data.Total, data.WordCount)
+	case *agent.ToolCallStartData:
+		return fmt.Sprintf("⚡ %s", data.Name)
+	case *agent.ErrorData:
+		return fmt.Sprintf("✗ %s", data.Message)
+	case *usage.FallbackData:
+		return "⚠ " + l.T(locale.UsageFallback, data.From, data.To, data.Reason)
+	case *usage.SummaryData:
+		line := "Σ " + l.T(locale.UsageSummary, data.Calls, data.TotalTokens, data.PromptTokens, data.CompletionTokens)
+		if data.Cost != nil {
+			line += ", " + l.T(locale.UsageCost, *data.Cost)
+		}
+		return line
+	default:
+		return ""
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/bornholm/genai/agent"
 	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/output"
 	"github.com/bornholm/ghostwriter/internal/command/shared"
 	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
 	wppkg "github.com/bornholm/ghostwriter/pkg/whitepaper"
 	"github.com/gosimple/slug"
 	"github.com/pkg/errors"
@@ -56,12 +60,7 @@ func Whitepaper() *cli.Command {
 				Value:   "",
 				EnvVars: []string{"GHOSTWRITER_OUTPUT_HTML"},
 			},
-			&cli.StringFlag{
-				Name:    "style-guide",
-				Value:   "",
-				Aliases: []string{"g"},
-				EnvVars: []string{"GHOSTWRITER_STYLE_GUIDE"},
-			},
+			shared.StyleGuideFlag(),
 			&cli.StringFlag{
 				Name:    "research-depth",
 				Value:   string(article.ResearchDeep),
@@ -108,8 +107,51 @@ func Whitepaper() *cli.Command {
 				Usage:   "Path to the Corpus data directory (activates the Corpus knowledge base backend)",
 				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
 			},
+			shared.CollectionFlag(),
+			&cli.BoolFlag{
+				Name:    "skip-research",
+				Value:   false,
+				Usage:   "Skip the research phase and rely on the documents already in the knowledge base (see the research command)",
+				EnvVars: []string{"GHOSTWRITER_SKIP_RESEARCH"},
+			},
+			&cli.StringFlag{
+				Name:      "plan",
+				Value:     "",
+				Usage:     "Path to a plan.json (see the plan command) to use instead of generating one",
+				TakesFile: true,
+				EnvVars:   []string{"GHOSTWRITER_PLAN"},
+			},
+			&cli.BoolFlag{
+				Name:    "resume",
+				Value:   false,
+				Usage:   "Resume an interrupted run from the checkpoint saved in the output directory",
+				EnvVars: []string{"GHOSTWRITER_RESUME"},
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Value:   2 * time.Hour,
+				Usage:   "Maximum duration of the run",
+				EnvVars: []string{"GHOSTWRITER_TIMEOUT"},
+			},
+			output.Flag(),
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
 		},
-		Action: func(cliCtx *cli.Context) error {
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "whitepaper")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
 			subject := strings.TrimSpace(cliCtx.String("subject"))
 			if subjectFile := cliCtx.String("subject-file"); subjectFile != "" {
 				data, err := os.ReadFile(subjectFile)
@@ -118,10 +160,23 @@ func Whitepaper() *cli.Command {
 				}
 				subject = strings.TrimSpace(string(data))
 			}
+			targetWords := cliCtx.Int("target-words")
+
+			var plan *wppkg.WhitePaperPlan
+			if planPath := cliCtx.String("plan"); planPath != "" {
+				loaded, err := wppkg.LoadPlan(planPath, targetWords)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				plan = &loaded
+				if subject == "" {
+					subject = plan.Title
+				}
+			}
+
 			if subject == "" {
-				return errors.New("subject is required: use --subject or --subject-file")
+				return errors.New("subject is required: use --subject, --subject-file or --plan")
 			}
-			targetWords := cliCtx.Int("target-words")
 			outputDir := cliCtx.String("output-dir")
 			outputPDF := cliCtx.String("output-pdf")
 			outputHTML := cliCtx.String("output-html")
@@ -133,14 +188,23 @@ func Whitepaper() *cli.Command {
 			noSandbox := cliCtx.Bool("no-sandbox")
 			corpusStoragePath := cliCtx.String("corpus-storage-path")
 			maxReviewRounds := cliCtx.Int("max-review-rounds")
+			skipResearch := cliCtx.Bool("skip-research")
+			resume := cliCtx.Bool("resume")
+			parts := cliCtx.Bool("parts")
 
 			if outputDir == "" {
 				outputDir = slug.Make(subject)
 			}
 
-			ctx, cancel := context.WithTimeout(cliCtx.Context, 2*time.Hour)
+			ctx, cancel := context.WithTimeout(cliCtx.Context, cliCtx.Duration("timeout"))
 			defer cancel()
 
+			tracker, err := shared.NewUsageTracker(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			ctx = usage.WithContextTracker(ctx, tracker)
+
 			resilientClient, err := llmclient.NewClient(ctx)
 			if err != nil {
 				return errors.Wrap(err, "failed to create llm client")
@@ -155,14 +219,20 @@ func Whitepaper() *cli.Command {
 				wppkg.WithChromiumPath(chromiumPath),
 				wppkg.WithNoSandbox(noSandbox),
 				wppkg.WithMaxReviewRounds(maxReviewRounds),
+				wppkg.WithParts(parts),
+				wppkg.WithSkipResearch(skipResearch),
+				wppkg.WithPlan(plan),
+				wppkg.WithResume(resume),
+				wppkg.WithModelName(llmclient.ModelName()),
+				wppkg.WithLocale(l),
 			}
 
 			if styleGuide != "" {
-				data, err := os.ReadFile(styleGuide)
+				guidelines, err := shared.ReadStyleGuide(styleGuide)
 				if err != nil {
-					return errors.Wrap(err, "failed to read style guide")
+					return errors.WithStack(err)
 				}
-				orchestratorOptions = append(orchestratorOptions, wppkg.WithStyleGuidelines(string(data)))
+				orchestratorOptions = append(orchestratorOptions, wppkg.WithStyleGuidelines(guidelines))
 			}
 
 			if additionalContext != "" {
@@ -174,7 +244,7 @@ func Whitepaper() *cli.Command {
 			}
 
 			// Build knowledge base: Corpus backend (default) or Bleve fallback.
-			kb, kbClose, err := shared.BuildKnowledgeBase(ctx, corpusStoragePath)
+			kb, kbClose, err := shared.BuildKnowledgeBase(ctx, corpusStoragePath, shared.RunCollection(cliCtx, subject, skipResearch || resume))
 			if err != nil {
 				return errors.Wrap(err, "could not create knowledge base")
 			}
@@ -182,37 +252,69 @@ func Whitepaper() *cli.Command {
 
 			orchestratorOptions = append(orchestratorOptions, wppkg.WithKnowledgeBase(kb))
 
+			webScraper, searchClient, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			orchestratorOptions = append(orchestratorOptions, wppkg.WithScraper(webScraper), wppkg.WithSearchClient(searchClient))
+
 			if len(files) > 0 {
 				if err := shared.BootstrapKnowledgeBase(kb, files); err != nil {
 					return errors.Wrap(err, "could not bootstrap knowledge base")
 				}
 			}
 
-			fmt.Printf("\n%s\n%s\n\n",
-				titleStyle.Render(fmt.Sprintf("Livre blanc : %q", subject)),
+			out.Printf("\n%s\n%s\n\n",
+				titleStyle.Render(l.T(locale.WhitePaperHeader, subject)),
 				subtleStyle.Render(strings.Repeat("─", 60)),
 			)
 
-			emit := func(evt agent.Event) error {
-				output := RenderEvent(evt)
-				if output != "" {
-					fmt.Print(output)
+			emit := out.Emit(func(evt agent.Event) error {
+				if text := RenderEvent(l, evt); text != "" {
+					fmt.Print(text)
 				}
 				return nil
-			}
+			})
+			tracker.EmitTo(emit)
+			defer func() { result.Usage = shared.ReportUsage(tracker, filepath.Join(outputDir, usage.FileName), emit) }()
 
-			result, err := wppkg.WriteWhitePaper(ctx, resilientClient, subject, emit, orchestratorOptions...)
+			whitePaper, err := wppkg.WriteWhitePaper(ctx, resilientClient, subject, emit, orchestratorOptions...)
+			if errors.Is(err, usage.ErrBudgetExceeded) && whitePaper.Entrypoint != "" {
+				// The incomplete white paper has been assembled
+				result = output.Result{Entrypoint: whitePaper.Entrypoint}
+				for _, file := range whitePaper.ChapterFiles {
+					result.Files = append(result.Files, filepath.Join(outputDir, file))
+				}
+				out.Printf("\n%s\n", errorStyle.Render("✗ "+l.T(locale.WhitePaperOverBudget, outputDir)))
+				out.Printf("  %s %s\n", subtleStyle.Render("Index :"), whitePaper.Entrypoint)
+				return errors.Wrap(err, "failed to generate white paper")
+			}
 			if err != nil {
+				if !errors.Is(err, wppkg.ErrIncompatibleCheckpoint) {
+					out.Printf("\n%s\n", errorStyle.Render("✗ "+l.T(locale.WhitePaperInterrupted, outputDir)))
+				}
 				return errors.Wrap(err, "failed to generate white paper")
 			}
 
-			fmt.Printf("\n%s\n", successStyle.Render(fmt.Sprintf("✓ Livre blanc généré dans %s/", outputDir)))
-			fmt.Printf("  %s %s\n", subtleStyle.Render("Index :"), result.Entrypoint)
+			result = output.Result{
+				Entrypoint: whitePaper.Entrypoint,
+				Sources:    whitePaper.Metadata.Sources,
+			}
+			for _, file := range whitePaper.ChapterFiles {
+				result.Files = append(result.Files, filepath.Join(outputDir, file))
+			}
+
+			out.Printf("\n%s\n", successStyle.Render("✓ "+l.T(locale.WhitePaperDone, outputDir)))
+			out.Printf("  %s %s\n", subtleStyle.Render("Index :"), whitePaper.Entrypoint)
 			if outputHTML != "" {
-				fmt.Printf("  %s %s\n", subtleStyle.Render("HTML  :"), outputHTML)
+				result.Files = append(result.Files, outputHTML)
+				out.Printf("  %s %s\n", subtleStyle.Render("HTML  :"), outputHTML)
 			}
 			if outputPDF != "" {
-				fmt.Printf("  %s %s\n", subtleStyle.Render("PDF   :"), outputPDF)
+				result.Files = append(result.Files, outputPDF)
+				out.Printf("  %s %s\n", subtleStyle.Render("PDF   :"), outputPDF)
 			}
 
 			return nil
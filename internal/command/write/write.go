@@ -0,0 +1,252 @@
+package write
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/internal/command/llmclient"
+	"github.com/bornholm/ghostwriter/internal/command/output"
+	"github.com/bornholm/ghostwriter/internal/command/shared"
+	whitepaperui "github.com/bornholm/ghostwriter/internal/command/whitepaper"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	"github.com/gosimple/slug"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func Write() *cli.Command {
+	return &cli.Command{
+		Name:  "write",
+		Usage: "Write a single article about the given subject",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "subject",
+				Aliases: []string{"s"},
+				EnvVars: []string{"GHOSTWRITER_SUBJECT"},
+			},
+			&cli.StringFlag{
+				Name:      "subject-file",
+				Aliases:   []string{"sf"},
+				Usage:     "Path to a file whose content will be used as the subject",
+				TakesFile: true,
+				EnvVars:   []string{"GHOSTWRITER_SUBJECT_FILE"},
+			},
+			&cli.IntFlag{
+				Name:    "target-words",
+				Value:   1500,
+				Aliases: []string{"t"},
+				EnvVars: []string{"GHOSTWRITER_TARGET_WORDS"},
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Value:   "",
+				Aliases: []string{"o"},
+				Usage:   "Path to the generated Markdown file (defaults to <subject-slug>.md)",
+				EnvVars: []string{"GHOSTWRITER_OUTPUT"},
+			},
+			shared.StyleGuideFlag(),
+			&cli.StringFlag{
+				Name:    "research-depth",
+				Value:   string(article.ResearchDeep),
+				Aliases: []string{"d"},
+				EnvVars: []string{"GHOSTWRITER_RESEARCH_DEPTH"},
+			},
+			&cli.StringSliceFlag{
+				Name:      "files",
+				Aliases:   []string{"f"},
+				EnvVars:   []string{"GHOSTWRITER_FILES"},
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:    "additional-context",
+				Value:   "",
+				Aliases: []string{"c"},
+				EnvVars: []string{"GHOSTWRITER_ADDITIONAL_CONTEXT"},
+			},
+			&cli.IntFlag{
+				Name:    "max-review-rounds",
+				Value:   2,
+				Usage:   "Maximum number of write→review rounds per section (minimum 1)",
+				EnvVars: []string{"GHOSTWRITER_MAX_REVIEW_ROUNDS"},
+			},
+			&cli.StringFlag{
+				Name:    "corpus-storage-path",
+				Value:   ".corpus",
+				Usage:   "Path to the Corpus data directory (activates the Corpus knowledge base backend)",
+				EnvVars: []string{"GHOSTWRITER_CORPUS_STORAGE_PATH"},
+			},
+			shared.CollectionFlag(),
+			&cli.BoolFlag{
+				Name:    "skip-research",
+				Value:   false,
+				Usage:   "Skip the research phase and rely on the documents already in the knowledge base (see the research command)",
+				EnvVars: []string{"GHOSTWRITER_SKIP_RESEARCH"},
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Value:   2 * time.Hour,
+				Usage:   "Maximum duration of the run",
+				EnvVars: []string{"GHOSTWRITER_TIMEOUT"},
+			},
+			output.Flag(),
+			shared.LocaleFlag(),
+			shared.MaxTokensFlag(),
+			shared.MaxCostFlag(),
+		},
+		Action: func(cliCtx *cli.Context) (err error) {
+			out, err := output.New(cliCtx.String("output-format"), "write")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var result output.Result
+			defer func() { err = out.Finish(result, err) }()
+
+			l, err := shared.Locale(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			subject := strings.TrimSpace(cliCtx.String("subject"))
+			if subjectFile := cliCtx.String("subject-file"); subjectFile != "" {
+				data, err := os.ReadFile(subjectFile)
+				if err != nil {
+					return errors.Wrap(err, "failed to read subject file")
+				}
+				subject = strings.TrimSpace(string(data))
+			}
+			if subject == "" {
+				return errors.New("subject is required: use --subject or --subject-file")
+			}
+			targetWords := cliCtx.Int("target-words")
+			outputPath := cliCtx.String("output")
+			styleGuide := cliCtx.String("style-guide")
+			researchDepth := cliCtx.String("research-depth")
+			files := cliCtx.StringSlice("files")
+			additionalContext := cliCtx.String("additional-context")
+			corpusStoragePath := cliCtx.String("corpus-storage-path")
+			maxReviewRounds := cliCtx.Int("max-review-rounds")
+			skipResearch := cliCtx.Bool("skip-research")
+
+			if outputPath == "" {
+				outputPath = slug.Make(subject) + ".md"
+			}
+
+			ctx, cancel := context.WithTimeout(cliCtx.Context, cliCtx.Duration("timeout"))
+			defer cancel()
+
+			tracker, err := shared.NewUsageTracker(cliCtx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			ctx = usage.WithContextTracker(ctx, tracker)
+
+			resilientClient, err := llmclient.NewClient(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to create llm client")
+			}
+
+			orchestratorOptions := []article.OrchestratorOptionFunc{
+				article.WithTargetWordCount(targetWords),
+				article.WithResearchDepth(article.ResearchDepth(researchDepth)),
+				article.WithMaxReviewRounds(maxReviewRounds),
+				article.WithSkipResearch(skipResearch),
+			}
+
+			if styleGuide != "" {
+				guidelines, err := shared.ReadStyleGuide(styleGuide)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				orchestratorOptions = append(orchestratorOptions, article.WithStyleGuidelines(guidelines))
+			}
+
+			if additionalContext != "" {
+				data, err := os.ReadFile(additionalContext)
+				if err != nil {
+					return errors.Wrap(err, "failed to read additional context file")
+				}
+				orchestratorOptions = append(orchestratorOptions, article.WithAdditionalContext(string(data)))
+			}
+
+			kb, kbClose, err := shared.BuildKnowledgeBase(ctx, corpusStoragePath, shared.RunCollection(cliCtx, subject, skipResearch))
+			if err != nil {
+				return errors.Wrap(err, "could not create knowledge base")
+			}
+			defer func() { _ = kbClose() }()
+
+			orchestratorOptions = append(orchestratorOptions, article.WithKnowledgeBase(kb))
+
+			webScraper, searchClient, closeWeb, err := shared.NewWebClients()
+			if err != nil {
+				return errors.Wrap(err, "could not create web clients")
+			}
+			defer closeWeb()
+
+			orchestratorOptions = append(orchestratorOptions, article.WithScraper(webScraper), article.WithSearchClient(searchClient))
+
+			if len(files) > 0 {
+				if err := shared.BootstrapKnowledgeBase(kb, files); err != nil {
+					return errors.Wrap(err, "could not bootstrap knowledge base")
+				}
+			}
+
+			out.Printf("\n%s\n\n", l.T(locale.ArticleHeader, subject))
+
+			ctx = article.WithProgressTracking(ctx, out.Progress(func(evt article.ProgressEvent) {
+				fmt.Printf("\n%s ▶ %s\n", time.Now().Format("15:04:05"), evt.Step())
+			}))
+
+			emit := out.Emit(func(evt agent.Event) error {
+				// Streamed prose and intermediate JSON results are not meant for the terminal
+				switch evt.Type() {
+				case agent.EventTypeTextDelta, agent.EventTypeComplete:
+					return nil
+				}
+				if text := whitepaperui.RenderEvent(l, evt); text != "" {
+					fmt.Print(text)
+				}
+				return nil
+			})
+			tracker.EmitTo(emit)
+			defer func() {
+				result.Usage = shared.ReportUsage(tracker, strings.TrimSuffix(outputPath, filepath.Ext(outputPath))+".usage.json", emit)
+			}()
+
+			document, err := article.WriteArticle(ctx, resilientClient, subject, emit, orchestratorOptions...)
+			if err != nil {
+				return errors.Wrap(err, "failed to generate article")
+			}
+
+			if dir := filepath.Dir(outputPath); dir != "." {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return errors.Wrap(err, "failed to create output directory")
+				}
+			}
+
+			if err := os.WriteFile(outputPath, []byte(article.FormatMarkdown(document)), 0644); err != nil {
+				return errors.Wrap(err, "failed to write article")
+			}
+
+			result = output.Result{
+				Entrypoint: outputPath,
+				Sources:    document.Sources,
+			}
+
+			out.Printf("\n✓ %s\n", l.T(locale.ArticleDone, outputPath, document.WordCount, len(document.Sources)))
+
+			return nil
+		},
+	}
+}
+
+func Root() *cli.Command {
+	return Write()
+}
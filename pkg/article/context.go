@@ -2,6 +2,7 @@ package article
 
 import (
 	"context"
+	"time"
 
 	"github.com/bornholm/genai/agent"
 )
@@ -21,6 +22,8 @@ const (
 	ContextKeyPreviousSectionContent agent.ContextKey = "article_previous_section_content"
 	ContextKeyDocumentSections      agent.ContextKey = "article_document_sections"
 	ContextKeyDocumentDraft         agent.ContextKey = "article_document_draft"
+	ContextKeyResearchReport        agent.ContextKey = "article_research_report"
+	ContextKeyNow                   agent.ContextKey = "article_now"
 )
 
 // AgentRole defines the role of an agent in the article writing process
@@ -34,6 +37,13 @@ const (
 	RoleOrchestrator AgentRole = "orchestrator"
 )
 
+// Roles of the agents specific to white papers.
+const (
+	RoleCoherenceEditor AgentRole = "coherence"
+	RoleCitationLinker  AgentRole = "citation_linker"
+	RoleDiagramInserter AgentRole = "diagram_inserter"
+)
+
 // ResearchDepth defines how deep the research should be
 type ResearchDepth string
 
@@ -180,3 +190,29 @@ func ContextDocumentDraft(ctx context.Context) string {
 	draft, _ := ctx.Value(ContextKeyDocumentDraft).(string)
 	return draft
 }
+
+// WithContextResearchReport attaches a research report to the context so the
+// research agent records its queries, visited URLs and indexed documents
+func WithContextResearchReport(ctx context.Context, report *ResearchReport) context.Context {
+	return context.WithValue(ctx, ContextKeyResearchReport, report)
+}
+
+// ContextResearchReport retrieves the research report from context, if any
+func ContextResearchReport(ctx context.Context) *ResearchReport {
+	report, _ := ctx.Value(ContextKeyResearchReport).(*ResearchReport)
+	return report
+}
+
+// WithContextNow sets the current time given to the agents in their prompts,
+// so that a replayed run sends the same prompts as the recorded one
+func WithContextNow(ctx context.Context, now time.Time) context.Context {
+	return context.WithValue(ctx, ContextKeyNow, now)
+}
+
+// ContextNow retrieves the current time from context, defaulting to time.Now()
+func ContextNow(ctx context.Context) time.Time {
+	if now, ok := ctx.Value(ContextKeyNow).(time.Time); ok {
+		return now
+	}
+	return time.Now()
+}
@@ -1,6 +1,8 @@
 package article
 
 import (
+	"maps"
+	"slices"
 	"sync"
 
 	"github.com/blevesearch/bleve/v2"
@@ -140,14 +142,14 @@ func (kb *BleveKnowledgeBase) Search(query string, limit int) ([]ResearchDocumen
 	return results, nil
 }
 
-// GetAllDocuments returns all documents in the knowledge base.
+// GetAllDocuments returns all documents in the knowledge base, ordered by URL.
 func (kb *BleveKnowledgeBase) GetAllDocuments() []ResearchDocument {
 	kb.mutex.RLock()
 	defer kb.mutex.RUnlock()
 
 	var docs []ResearchDocument
-	for _, doc := range kb.documents {
-		docs = append(docs, doc)
+	for _, url := range slices.Sorted(maps.Keys(kb.documents)) {
+		docs = append(docs, kb.documents[url])
 	}
 
 	return docs
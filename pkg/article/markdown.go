@@ -0,0 +1,53 @@
+package article
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FormatMarkdown renders the document as a standalone Markdown file with a
+// YAML front matter and a trailing sources list.
+func FormatMarkdown(doc Document) string {
+	var b strings.Builder
+
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %q\n", doc.Title)
+	fmt.Fprintf(&b, "date: %q\n", time.Now().Format("2006-01-02"))
+	fmt.Fprintf(&b, "word_count: %d\n", doc.WordCount)
+	if len(doc.Keywords) > 0 {
+		b.WriteString("keywords:\n")
+		for _, kw := range doc.Keywords {
+			fmt.Fprintf(&b, "  - %q\n", kw)
+		}
+	}
+	b.WriteString("---\n\n")
+
+	content := strings.TrimSpace(doc.Content)
+	if !strings.HasPrefix(content, "# ") {
+		fmt.Fprintf(&b, "# %s\n\n", doc.Title)
+	}
+	b.WriteString(content)
+	b.WriteString("\n\n")
+
+	sources := make([]Source, 0, len(doc.Sources))
+	for _, s := range doc.Sources {
+		if s.URL != "" {
+			sources = append(sources, s)
+		}
+	}
+
+	if len(sources) > 0 {
+		b.WriteString("## Sources\n\n")
+		for i, s := range sources {
+			title := s.Title
+			if title == "" {
+				title = s.URL
+			}
+			fmt.Fprintf(&b, "%d. [%s](%s)\n", i+1, title, s.URL)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/bornholm/genai/agent"
 	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/pkg/scraper"
 	"github.com/bornholm/ghostwriter/pkg/scraper/surf"
+	"github.com/bornholm/ghostwriter/pkg/search"
 	"github.com/bornholm/ghostwriter/pkg/search/duckduckgo"
 	"github.com/bornholm/ghostwriter/pkg/tool"
 	"github.com/pkg/errors"
@@ -32,6 +34,9 @@ type OrchestratorOptions struct {
 	Tools             []llm.Tool
 	KnowledgeBase     KnowledgeBase
 	MaxReviewRounds   int
+	SkipResearch      bool
+	SearchClient      search.Client   // defaults to DuckDuckGo
+	Scraper           scraper.Scraper // defaults to surf
 }
 
 func NewOrchestratorOptions(optFuncs ...OrchestratorOptionFunc) *OrchestratorOptions {
@@ -102,6 +107,27 @@ func WithMaxReviewRounds(n int) OrchestratorOptionFunc {
 	}
 }
 
+// WithSkipResearch skips the research phase and relies on an already populated knowledge base
+func WithSkipResearch(skip bool) OrchestratorOptionFunc {
+	return func(opts *OrchestratorOptions) {
+		opts.SkipResearch = skip
+	}
+}
+
+// WithSearchClient sets the search engine used by the research agent
+func WithSearchClient(client search.Client) OrchestratorOptionFunc {
+	return func(opts *OrchestratorOptions) {
+		opts.SearchClient = client
+	}
+}
+
+// WithScraper sets the scraper used to fetch web pages
+func WithScraper(s scraper.Scraper) OrchestratorOptionFunc {
+	return func(opts *OrchestratorOptions) {
+		opts.Scraper = s
+	}
+}
+
 // WriteArticle orchestrates the complete article writing process
 func (o *Orchestrator) WriteArticle(ctx context.Context, subject string, emit agent.EmitFunc, optFuncs ...OrchestratorOptionFunc) (Document, error) {
 	opts := NewOrchestratorOptions(optFuncs...)
@@ -131,13 +157,17 @@ func (o *Orchestrator) WriteArticle(ctx context.Context, subject string, emit ag
 	}
 
 	// Step 1: Research
-	tracker.EmitPhaseStart(PhaseResearching, "Starting comprehensive research", GetPhaseBaseProgress(PhaseResearching))
-	if err := o.conductResearch(ctx, subject, opts.ResearchDepth, knowledgeBase, opts, emit); err != nil {
-		return Document{}, errors.WithStack(err)
+	if opts.SkipResearch {
+		tracker.EmitPhaseComplete(PhaseResearching, "Research skipped, using existing knowledge base", GetPhaseBaseProgress(PhasePlanning))
+	} else {
+		tracker.EmitPhaseStart(PhaseResearching, "Starting comprehensive research", GetPhaseBaseProgress(PhaseResearching))
+		if err := o.conductResearch(ctx, subject, opts.ResearchDepth, knowledgeBase, opts, emit); err != nil {
+			return Document{}, errors.WithStack(err)
+		}
+		tracker.EmitPhaseComplete(PhaseResearching, "Research completed", GetPhaseBaseProgress(PhasePlanning))
 	}
 	ctx = WithContextKnowledgeBase(ctx, knowledgeBase)
 	ctx = WithContextResearchComplete(ctx, true)
-	tracker.EmitPhaseComplete(PhaseResearching, "Research completed", GetPhaseBaseProgress(PhasePlanning))
 
 	// Step 2: Plan
 	tracker.EmitPhaseStart(PhasePlanning, "Starting document planning", GetPhaseBaseProgress(PhasePlanning))
@@ -187,6 +217,30 @@ func (o *Orchestrator) WriteArticle(ctx context.Context, subject string, emit ag
 	return article, nil
 }
 
+// Research runs the research phase only, populating the configured knowledge
+// base, and returns a report of the queries, URLs and documents it went through
+func (o *Orchestrator) Research(ctx context.Context, subject string, emit agent.EmitFunc, optFuncs ...OrchestratorOptionFunc) (*ResearchReport, error) {
+	opts := NewOrchestratorOptions(optFuncs...)
+
+	knowledgeBase := opts.KnowledgeBase
+	if knowledgeBase == nil {
+		kb, err := NewKnowledgeBase()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		knowledgeBase = kb
+	}
+
+	report := NewResearchReport(subject, opts.ResearchDepth)
+	ctx = WithContextResearchReport(ctx, report)
+
+	if err := o.conductResearch(ctx, subject, opts.ResearchDepth, knowledgeBase, opts, emit); err != nil {
+		return report, errors.WithStack(err)
+	}
+
+	return report, nil
+}
+
 // conductResearch uses the research agent to build knowledge base
 func (o *Orchestrator) conductResearch(ctx context.Context, subject string, depth ResearchDepth, kb KnowledgeBase, opts *OrchestratorOptions, emit agent.EmitFunc) error {
 	researchCtx := WithContextAgentRole(ctx, RoleResearcher)
@@ -279,7 +333,8 @@ func (o *Orchestrator) writeSectionsWithReview(ctx context.Context, plan Documen
 			if round == 0 {
 				content, err = o.writeSection(ctx, *section, subject, opts.ResearchDepth, index, previousSectionContent, emit)
 			} else {
-				content, err = o.writerHandler.reviseSection(ctx, *section, subject, previousSectionContent, approved.Content, feedback, round, emit)
+				reviseCtx := WithContextAgentRole(ctx, RoleWriter)
+				content, err = o.writerHandler.reviseSection(reviseCtx, *section, subject, previousSectionContent, approved.Content, feedback, round, emit)
 			}
 			if err != nil {
 				return nil, errors.WithStack(err)
@@ -371,10 +426,24 @@ func (o *Orchestrator) writeSection(ctx context.Context, section DocumentSection
 
 // NewOrchestrator creates a new article writing orchestrator
 func NewOrchestrator(client llm.Client, tools ...llm.Tool) *Orchestrator {
-	scraper := surf.NewScraper()
-	scraperTool := tool.NewScrapeWebpageTool(scraper)
+	return newOrchestrator(client, NewOrchestratorOptions(WithTools(tools)))
+}
+
+func newOrchestrator(client llm.Client, opts *OrchestratorOptions) *Orchestrator {
+	tools := opts.Tools
+
+	webScraper := opts.Scraper
+	if webScraper == nil {
+		webScraper = surf.NewScraper()
+	}
+	scraperTool := tool.NewScrapeWebpageTool(webScraper)
 
-	researchHandler := NewResearchAgent(client, duckduckgo.NewClient(scraper), scraper)
+	searchClient := opts.SearchClient
+	if searchClient == nil {
+		searchClient = duckduckgo.NewClient(webScraper)
+	}
+
+	researchHandler := NewResearchAgent(client, searchClient, webScraper)
 
 	plannerTools := append(tools, scraperTool)
 	plannerHandler := NewPlannerHandler(client, plannerTools...)
@@ -392,9 +461,16 @@ func NewOrchestrator(client llm.Client, tools ...llm.Tool) *Orchestrator {
 	}
 }
 
+// Research is a convenience function to create an orchestrator and run the research phase only
+func Research(ctx context.Context, client llm.Client, subject string, emit agent.EmitFunc, optFuncs ...OrchestratorOptionFunc) (*ResearchReport, error) {
+	opts := NewOrchestratorOptions(optFuncs...)
+	orchestrator := newOrchestrator(client, opts)
+	return orchestrator.Research(ctx, subject, emit, optFuncs...)
+}
+
 // WriteArticle is a convenience function to create an orchestrator and write an article
 func WriteArticle(ctx context.Context, client llm.Client, subject string, emit agent.EmitFunc, optFuncs ...OrchestratorOptionFunc) (Document, error) {
 	opts := NewOrchestratorOptions(optFuncs...)
-	orchestrator := NewOrchestrator(client, opts.Tools...)
+	orchestrator := newOrchestrator(client, opts)
 	return orchestrator.WriteArticle(ctx, subject, emit, optFuncs...)
 }
@@ -0,0 +1,179 @@
+package article_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/fakellm"
+	"github.com/bornholm/ghostwriter/pkg/fakeweb"
+	"github.com/bornholm/ghostwriter/pkg/search"
+	"github.com/pkg/errors"
+)
+
+func TestWriteArticle(t *testing.T) {
+	web := newTestWeb(t)
+	client := fakellm.NewClient(fakellm.WithRejectedReviews(1))
+	progress := &progressRecorder{}
+
+	doc, err := writeTestArticle(t, client, web, progress)
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	if len(doc.Sections) != 3 {
+		t.Fatalf("expected 3 sections, got %d", len(doc.Sections))
+	}
+	for _, title := range []string{"Context and Stakes", "Current Landscape", "Challenges and Risks"} {
+		if !strings.Contains(doc.Content, "## "+title) {
+			t.Errorf("expected the article to contain the section %q, got:\n%s", title, doc.Content)
+		}
+	}
+
+	var urls []string
+	for _, s := range doc.Sources {
+		urls = append(urls, s.URL)
+	}
+	slices.Sort(urls)
+	if expected := []string{web.URL("/solar-basics"), web.URL("/solar-costs")}; !slices.Equal(urls, expected) {
+		t.Errorf("expected sources %v, got %v", expected, urls)
+	}
+
+	// The refinements of the generated queries reach the search client
+	refined := 0
+	for _, opts := range web.SearchOptions() {
+		if opts.Language == "en" && opts.TimeRange == search.TimeRangeYear {
+			refined++
+		}
+	}
+	if refined == 0 {
+		t.Errorf("expected the search options of the queries to be passed on, got %+v", web.SearchOptions())
+	}
+
+	expectedPhases := []article.ProgressPhase{
+		article.PhaseInitializing,
+		article.PhaseResearching,
+		article.PhasePlanning,
+		article.PhaseWriting,
+		article.PhaseReviewing,
+		article.PhaseCompleted,
+	}
+	if phases := progress.phases(); !slices.Equal(phases, expectedPhases) {
+		t.Errorf("expected phases %v, got %v", expectedPhases, phases)
+	}
+
+	// Each section is rejected once, then revised and approved
+	if revisions := progress.count("Revising section"); revisions != 3 {
+		t.Errorf("expected 3 revisions, got %d", revisions)
+	}
+	reviews := 0
+	for _, c := range client.Calls() {
+		if c.Role == article.RoleEditor && c.Tools == 0 {
+			reviews++
+		}
+	}
+	if reviews != 6 {
+		t.Errorf("expected 6 reviews, got %d", reviews)
+	}
+}
+
+func TestWriteArticleErrors(t *testing.T) {
+	errUnavailable := errors.New("model unavailable")
+
+	t.Run("planner failure", func(t *testing.T) {
+		client := fakellm.NewClient(fakellm.WithError(article.RolePlanner, errUnavailable))
+		progress := &progressRecorder{}
+
+		_, err := writeTestArticle(t, client, newTestWeb(t), progress)
+		if !errors.Is(err, errUnavailable) {
+			t.Errorf("expected the planner error, got: %v", err)
+		}
+		if calls := client.CallsFor(article.RoleWriter); calls != 0 {
+			t.Errorf("expected no section to be written, got %d calls", calls)
+		}
+		if slices.Contains(progress.phases(), article.PhaseCompleted) {
+			t.Error("expected the article not to be completed")
+		}
+	})
+
+	t.Run("writer failure", func(t *testing.T) {
+		client := fakellm.NewClient(fakellm.WithError(article.RoleWriter, errUnavailable))
+
+		_, err := writeTestArticle(t, client, newTestWeb(t), &progressRecorder{})
+		if !errors.Is(err, errUnavailable) {
+			t.Errorf("expected the writer error, got: %v", err)
+		}
+	})
+}
+
+func writeTestArticle(t *testing.T, client *fakellm.Client, web *fakeweb.Web, progress *progressRecorder) (article.Document, error) {
+	t.Helper()
+
+	ctx := article.WithProgressTracking(context.Background(), progress.record)
+
+	return article.WriteArticle(ctx, client, "Solar energy for small businesses", func(agent.Event) error { return nil },
+		article.WithTargetWordCount(600),
+		article.WithResearchDepth(article.ResearchBasic),
+		article.WithMaxReviewRounds(2),
+		article.WithSearchClient(web.SearchClient()),
+		article.WithScraper(web.Scraper()),
+	)
+}
+
+func newTestWeb(t *testing.T) *fakeweb.Web {
+	t.Helper()
+
+	web := fakeweb.New(
+		fakeweb.Page{Path: "/solar-basics", Title: "Solar basics", Description: "How solar panels work", Body: "Solar panels convert sunlight into electricity."},
+		fakeweb.Page{Path: "/solar-costs", Title: "Solar costs", Description: "The cost of solar installations", Body: "Installation costs have dropped over the last decade."},
+	)
+	t.Cleanup(web.Close)
+
+	return web
+}
+
+type progressRecorder struct {
+	mu     sync.Mutex
+	events []article.ProgressEvent
+}
+
+func (r *progressRecorder) record(evt article.ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, evt)
+}
+
+// phases returns the phases of the recorded events, in order of first
+// appearance.
+func (r *progressRecorder) phases() []article.ProgressPhase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var phases []article.ProgressPhase
+	for _, evt := range r.events {
+		if !slices.Contains(phases, evt.Phase()) {
+			phases = append(phases, evt.Phase())
+		}
+	}
+
+	return phases
+}
+
+// count returns the number of recorded events whose step starts with prefix.
+func (r *progressRecorder) count(prefix string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, evt := range r.events {
+		if strings.HasPrefix(evt.Step(), prefix) {
+			n++
+		}
+	}
+
+	return n
+}
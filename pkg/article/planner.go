@@ -5,6 +5,8 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/bornholm/genai/agent"
@@ -141,8 +143,8 @@ func (h *PlannerHandler) createPlanningPrompt(subject string, targetWordCount in
 
 	if sourceTypeCounts, ok := stats["source_type_counts"].(map[string]int); ok {
 		prompt.WriteString("- Source types:\n")
-		for sourceType, count := range sourceTypeCounts {
-			prompt.WriteString(fmt.Sprintf("  - %s: %d documents\n", sourceType, count))
+		for _, sourceType := range slices.Sorted(maps.Keys(sourceTypeCounts)) {
+			prompt.WriteString(fmt.Sprintf("  - %s: %d documents\n", sourceType, sourceTypeCounts[sourceType]))
 		}
 	}
 	prompt.WriteString("\n")
@@ -163,10 +165,9 @@ func (h *PlannerHandler) createPlanningPrompt(subject string, targetWordCount in
 	}
 
 	prompt.WriteString("**Key Topics Identified:**\n")
-	keywordCount := 0
-	for keyword, count := range allKeywords {
-		prompt.WriteString(fmt.Sprintf("- %s (mentioned %d times)\n", keyword, count))
-		keywordCount++
+	// Sorted so that the prompt is the same from one run to the next
+	for _, keyword := range slices.Sorted(maps.Keys(allKeywords)) {
+		prompt.WriteString(fmt.Sprintf("- %s (mentioned %d times)\n", keyword, allKeywords[keyword]))
 	}
 	prompt.WriteString("\n")
 
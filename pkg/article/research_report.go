@@ -0,0 +1,246 @@
+package article
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// URLStatus describes what happened to a search result during research
+type URLStatus string
+
+const (
+	URLStatusScraped      URLStatus = "scraped"
+	URLStatusIndexed      URLStatus = "indexed"
+	URLStatusSkipped      URLStatus = "skipped"
+	URLStatusScrapeFailed URLStatus = "scrape_failed"
+	URLStatusIndexFailed  URLStatus = "index_failed"
+)
+
+// ResearchReport records what the research agent did: the generated queries,
+// the URLs visited for each of them, the failures and the indexed documents.
+// A report is filled when attached to the context with WithContextResearchReport.
+type ResearchReport struct {
+	Subject    string              `json:"subject"`
+	Depth      ResearchDepth       `json:"depth"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	Iterations []ResearchIteration `json:"iterations"`
+	Documents  []ResearchDocument  `json:"documents"`
+
+	mu sync.Mutex
+}
+
+// ResearchIteration groups the queries generated during one research iteration
+type ResearchIteration struct {
+	Number  int           `json:"number"`
+	Queries []QueryReport `json:"queries"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// QueryReport records a search query and the URLs it led to
+type QueryReport struct {
+	SearchQuery
+	Error string      `json:"error,omitempty"`
+	URLs  []URLReport `json:"urls"`
+}
+
+// URLReport records the outcome of a single search result
+type URLReport struct {
+	URL    string    `json:"url"`
+	Title  string    `json:"title"`
+	Status URLStatus `json:"status"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// NewResearchReport creates an empty research report
+func NewResearchReport(subject string, depth ResearchDepth) *ResearchReport {
+	return &ResearchReport{
+		Subject:    subject,
+		Depth:      depth,
+		StartedAt:  time.Now(),
+		Iterations: make([]ResearchIteration, 0),
+		Documents:  make([]ResearchDocument, 0),
+	}
+}
+
+func (r *ResearchReport) startIteration(number int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Iterations = append(r.Iterations, ResearchIteration{Number: number, Queries: make([]QueryReport, 0)})
+}
+
+func (r *ResearchReport) iterationFailed(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.Iterations) == 0 {
+		return
+	}
+	r.Iterations[len(r.Iterations)-1].Error = err.Error()
+}
+
+func (r *ResearchReport) recordQuery(query SearchQuery, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.Iterations) == 0 {
+		return
+	}
+	report := QueryReport{SearchQuery: query, URLs: make([]URLReport, 0)}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	iteration := &r.Iterations[len(r.Iterations)-1]
+	iteration.Queries = append(iteration.Queries, report)
+}
+
+func (r *ResearchReport) recordURL(url string, title string, status URLStatus, reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.Iterations) == 0 {
+		return
+	}
+	iteration := &r.Iterations[len(r.Iterations)-1]
+	if len(iteration.Queries) == 0 {
+		return
+	}
+	query := &iteration.Queries[len(iteration.Queries)-1]
+	query.URLs = append(query.URLs, URLReport{URL: url, Title: title, Status: status, Reason: reason})
+}
+
+// recordIndexing updates the status of a scraped URL once the knowledge base
+// accepted or rejected the document.
+func (r *ResearchReport) recordIndexing(doc ResearchDocument, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.Iterations) == 0 {
+		return
+	}
+
+	status, reason := URLStatusIndexed, ""
+	if err != nil {
+		status, reason = URLStatusIndexFailed, err.Error()
+	} else {
+		r.Documents = append(r.Documents, doc)
+	}
+
+	iteration := &r.Iterations[len(r.Iterations)-1]
+	for i := range iteration.Queries {
+		for j := range iteration.Queries[i].URLs {
+			u := &iteration.Queries[i].URLs[j]
+			if u.URL == doc.URL && u.Status == URLStatusScraped {
+				u.Status, u.Reason = status, reason
+			}
+		}
+	}
+}
+
+func (r *ResearchReport) finish() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FinishedAt = time.Now()
+}
+
+// Stats returns the number of queries, failed searches, failed scrapes and indexed documents
+func (r *ResearchReport) Stats() (queries int, failedSearches int, failedScrapes int, indexed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, it := range r.Iterations {
+		for _, q := range it.Queries {
+			queries++
+			if q.Error != "" {
+				failedSearches++
+			}
+			for _, u := range q.URLs {
+				if u.Status == URLStatusScrapeFailed {
+					failedScrapes++
+				}
+			}
+		}
+	}
+	return queries, failedSearches, failedScrapes, len(r.Documents)
+}
+
+// Markdown renders the report as a human readable Markdown document
+func (r *ResearchReport) Markdown() string {
+	queries, failedSearches, failedScrapes, indexed := r.Stats()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Research report: %s\n\n", r.Subject)
+	fmt.Fprintf(&b, "- Depth: %s\n", r.Depth)
+	fmt.Fprintf(&b, "- Started: %s\n", r.StartedAt.Format(time.RFC3339))
+	if !r.FinishedAt.IsZero() {
+		fmt.Fprintf(&b, "- Duration: %s\n", r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	}
+	fmt.Fprintf(&b, "- Queries: %d (%d failed)\n", queries, failedSearches)
+	fmt.Fprintf(&b, "- Failed scrapes: %d\n", failedScrapes)
+	fmt.Fprintf(&b, "- Indexed documents: %d\n\n", indexed)
+
+	for _, it := range r.Iterations {
+		fmt.Fprintf(&b, "## Iteration %d\n\n", it.Number)
+		if it.Error != "" {
+			fmt.Fprintf(&b, "> Query generation failed: %s\n\n", it.Error)
+		}
+		for _, q := range it.Queries {
+			fmt.Fprintf(&b, "### %q (priority %d)\n\n", q.Query, q.Priority)
+			if q.Rationale != "" {
+				fmt.Fprintf(&b, "%s\n\n", q.Rationale)
+			}
+			if len(q.Keywords) > 0 {
+				fmt.Fprintf(&b, "Keywords: %s\n\n", strings.Join(q.Keywords, ", "))
+			}
+			if q.Error != "" {
+				fmt.Fprintf(&b, "> Search failed: %s\n\n", q.Error)
+				continue
+			}
+			if len(q.URLs) == 0 {
+				b.WriteString("_No results_\n\n")
+				continue
+			}
+			for _, u := range q.URLs {
+				title := u.Title
+				if title == "" {
+					title = u.URL
+				}
+				fmt.Fprintf(&b, "- `%s` [%s](%s)", u.Status, title, u.URL)
+				if u.Reason != "" {
+					fmt.Fprintf(&b, " — %s", u.Reason)
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("## Indexed documents\n\n")
+	if len(r.Documents) == 0 {
+		b.WriteString("_None_\n")
+	}
+	for i, d := range r.Documents {
+		fmt.Fprintf(&b, "%d. [%s](%s) — %s, relevance %.2f\n", i+1, d.Title, d.URL, d.SourceType, d.Relevance)
+	}
+
+	return b.String()
+}
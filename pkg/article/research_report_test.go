@@ -0,0 +1,53 @@
+package article
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResearchReport(t *testing.T) {
+	r := NewResearchReport("subject", ResearchBasic)
+
+	r.startIteration(1)
+	r.recordQuery(SearchQuery{Query: "first", Rationale: "because"}, nil)
+	r.recordURL("https://example.com/a", "A", URLStatusScraped, "")
+	r.recordURL("https://example.com/b", "B", URLStatusScrapeFailed, "timeout")
+	r.recordURL("https://example.com/c", "C", URLStatusScraped, "")
+	r.recordQuery(SearchQuery{Query: "second"}, errors.New("captcha"))
+
+	r.recordIndexing(ResearchDocument{URL: "https://example.com/a", Title: "A"}, nil)
+	r.recordIndexing(ResearchDocument{URL: "https://example.com/c", Title: "C"}, errors.New("too large"))
+	r.finish()
+
+	queries, failedSearches, failedScrapes, indexed := r.Stats()
+	if queries != 2 || failedSearches != 1 || failedScrapes != 1 || indexed != 1 {
+		t.Errorf("Stats() = %d, %d, %d, %d; want 2, 1, 1, 1", queries, failedSearches, failedScrapes, indexed)
+	}
+
+	urls := r.Iterations[0].Queries[0].URLs
+	if urls[0].Status != URLStatusIndexed {
+		t.Errorf("expected %q to be indexed, got %q", urls[0].URL, urls[0].Status)
+	}
+	if urls[2].Status != URLStatusIndexFailed || urls[2].Reason != "too large" {
+		t.Errorf("expected %q to have failed indexing, got %q (%q)", urls[2].URL, urls[2].Status, urls[2].Reason)
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{"# Research report: subject", `### "first"`, "because", "> Search failed: captcha", "1. [A](https://example.com/a)"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected markdown to contain %q", want)
+		}
+	}
+}
+
+func TestResearchReportNil(t *testing.T) {
+	var r *ResearchReport
+
+	// Recording on a nil report must be a no-op
+	r.startIteration(1)
+	r.recordQuery(SearchQuery{Query: "query"}, nil)
+	r.recordURL("https://example.com", "", URLStatusSkipped, "")
+	r.recordIndexing(ResearchDocument{}, nil)
+	r.finish()
+}
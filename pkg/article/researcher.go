@@ -5,7 +5,9 @@ import (
 	"embed"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
@@ -31,6 +33,23 @@ type SearchQuery struct {
 	Keywords  []string `json:"keywords" jsonschema:"required,description=Key terms related to this query"`
 	Priority  int      `json:"priority" jsonschema:"required,description=Priority level 1-5, 5 being highest"`
 	Rationale string   `json:"rationale" jsonschema:"required,description=Why this query is important for the research"`
+	// Refinements of the search, an empty value meaning no filter. They are
+	// required like the other fields since providers enforce the schema in
+	// strict mode.
+	Language  string   `json:"language" jsonschema:"required,description=ISO 639-1 code of the language of the results (e.g. en) or an empty string for any language"`
+	Region    string   `json:"region" jsonschema:"required,description=ISO 3166-1 alpha-2 code of the region of the results (e.g. us) or an empty string for any region"`
+	TimeRange string   `json:"timeRange" jsonschema:"required,description=Only return results published within the last day/week/month/year or an empty string for any date"`
+	Sites     []string `json:"sites" jsonschema:"required,description=Only return results from these domains (e.g. europa.eu) or an empty array for any site"`
+}
+
+// SearchOptions returns the search options of the query.
+func (q SearchQuery) SearchOptions() []search.OptionFunc {
+	return []search.OptionFunc{
+		search.WithLanguage(q.Language),
+		search.WithRegion(q.Region),
+		search.WithTimeRange(search.TimeRange(q.TimeRange)),
+		search.WithSites(q.Sites...),
+	}
 }
 
 // SearchQueriesResponse represents the LLM response for query generation
@@ -90,6 +109,8 @@ func (h *ResearchAgent) Handle(ctx context.Context, input agent.Input, emit agen
 func (h *ResearchAgent) conductResearch(ctx context.Context, subject string, depth ResearchDepth, kb KnowledgeBase) error {
 	// Initialize progress tracking
 	tracker := NewProgressTracker(ctx)
+	report := ContextResearchReport(ctx)
+	defer report.finish()
 
 	tracker.EmitSubProgress(PhaseResearching, "Initializing structured research process",
 		GetPhaseBaseProgress(PhaseResearching), 0.05, ResearchingWeight, map[string]interface{}{
@@ -118,6 +139,7 @@ func (h *ResearchAgent) conductResearch(ctx context.Context, subject string, dep
 	// Main research loop
 	for state.CurrentIteration < state.MaxIterations && state.TotalArticles < state.TargetArticles {
 		state.CurrentIteration++
+		report.startIteration(state.CurrentIteration)
 
 		iterationProgress := 0.1 + (0.8 * float64(state.CurrentIteration-1) / float64(state.MaxIterations))
 
@@ -131,6 +153,7 @@ func (h *ResearchAgent) conductResearch(ctx context.Context, subject string, dep
 		// Generate search queries for this iteration
 		queries, err := h.generateSearchQueries(ctx, subject, state.ContentSummaries, state.CurrentIteration)
 		if err != nil {
+			report.iterationFailed(err)
 			if errors.Is(err, llm.ErrNoMessage) || errors.Is(err, llm.ErrUnavailable) {
 				slog.WarnContext(ctx, "query generation returned no response, stopping research early",
 					slog.Int("iteration", state.CurrentIteration),
@@ -228,9 +251,12 @@ func (h *ResearchAgent) executeSearchAndScrape(ctx context.Context, queries []Se
 	var allArticles []ResearchDocument
 	var failedSearches, failedScrapes int
 
+	report := ContextResearchReport(ctx)
+
 	for _, query := range queries {
 		// Search for results
-		results, err := h.searchClient.Search(ctx, query.Query)
+		results, err := h.searchClient.Search(ctx, query.Query, query.SearchOptions()...)
+		report.recordQuery(query, err)
 		if err != nil {
 			failedSearches++
 			slog.WarnContext(ctx, "search query failed", slog.String("query", query.Query), slog.Any("error", err))
@@ -248,18 +274,21 @@ func (h *ResearchAgent) executeSearchAndScrape(ctx context.Context, queries []Se
 
 			// Skip PDF links — they cause token-limit errors in the embedding pipeline
 			if strings.HasSuffix(strings.ToLower(strings.SplitN(result.URL, "?", 2)[0]), ".pdf") {
+				report.recordURL(result.URL, result.Title, URLStatusSkipped, "pdf document")
 				continue
 			}
 
 			// Check if URL already processed (deduplication)
 			normalizedURL := h.normalizeURL(result.URL)
 			if state.ProcessedURLs[normalizedURL] {
+				report.recordURL(result.URL, result.Title, URLStatusSkipped, "already processed")
 				continue
 			}
 
 			// Check if already indexed in the KB (covers persistent backends like Corpus)
 			if kb.HasDocument(result.URL) || kb.HasDocument(normalizedURL) {
 				state.ProcessedURLs[normalizedURL] = true
+				report.recordURL(result.URL, result.Title, URLStatusSkipped, "already indexed")
 				continue
 			}
 
@@ -267,6 +296,7 @@ func (h *ResearchAgent) executeSearchAndScrape(ctx context.Context, queries []Se
 			article, err := h.scrapeArticle(ctx, result)
 			if err != nil {
 				failedScrapes++
+				report.recordURL(result.URL, result.Title, URLStatusScrapeFailed, err.Error())
 				slog.WarnContext(ctx, "failed to scrape article", slog.String("url", result.URL), slog.Any("error", err))
 				continue
 			}
@@ -276,6 +306,7 @@ func (h *ResearchAgent) executeSearchAndScrape(ctx context.Context, queries []Se
 
 			// Mark URL as processed
 			state.ProcessedURLs[normalizedURL] = true
+			report.recordURL(result.URL, result.Title, URLStatusScraped, "")
 			allArticles = append(allArticles, article)
 		}
 	}
@@ -294,8 +325,11 @@ func (h *ResearchAgent) executeSearchAndScrape(ctx context.Context, queries []Se
 // addToKnowledgeBaseWithDeduplication adds articles to KB while preventing duplicates
 func (h *ResearchAgent) addToKnowledgeBaseWithDeduplication(ctx context.Context, articles []ResearchDocument, kb KnowledgeBase, state *ResearchState) error {
 	tracker := NewProgressTracker(ctx)
+	report := ContextResearchReport(ctx)
 	for _, article := range articles {
-		if err := kb.AddDocument(article); err != nil {
+		err := kb.AddDocument(article)
+		report.recordIndexing(article, err)
+		if err != nil {
 			slog.WarnContext(ctx, "could not index document, skipping", slog.String("url", article.URL), slog.Any("error", err))
 			continue
 		}
@@ -324,9 +358,10 @@ func (h *ResearchAgent) extractContentSummary(articles []ResearchDocument) strin
 		}
 	}
 
-	// Get most frequent keywords as themes
-	for keyword, count := range keywordCounts {
-		if count >= researchKeywordMinFrequency {
+	// Get most frequent keywords as themes, sorted so that the prompt is the
+	// same from one run to the next
+	for _, keyword := range slices.Sorted(maps.Keys(keywordCounts)) {
+		if keywordCounts[keyword] >= researchKeywordMinFrequency {
 			themes = append(themes, keyword)
 		}
 	}
@@ -364,6 +399,14 @@ func (h *ResearchAgent) scrapeArticle(ctx context.Context, result search.Result)
 		return ResearchDocument{}, errors.WithStack(err)
 	}
 
+	title := result.Title
+	if title == "" {
+		title = strings.TrimSpace(doc.Find("title").First().Text())
+	}
+	if title == "" {
+		title = result.URL
+	}
+
 	// Extract HTML body content
 	html, err := doc.Find("body").Html()
 	if err != nil {
@@ -396,13 +439,20 @@ func (h *ResearchAgent) scrapeArticle(ctx context.Context, result search.Result)
 
 	return ResearchDocument{
 		URL:        result.URL,
-		Title:      result.Title,
+		Title:      title,
 		Content:    contentStr,
 		Keywords:   keywords,
 		SourceType: h.detectSourceType(result.URL),
 	}, nil
 }
 
+// ScrapeDocument fetches the web page at the given URL with the scraper and
+// converts it to a research document, as the research agent does for search results.
+func ScrapeDocument(ctx context.Context, s scraper.Scraper, rawURL string) (ResearchDocument, error) {
+	h := NewResearchAgent(nil, nil, s)
+	return h.scrapeArticle(ctx, search.Result{URL: rawURL})
+}
+
 // detectSourceType attempts to determine the source type from URL
 func (h *ResearchAgent) detectSourceType(url string) string {
 	url = strings.ToLower(url)
@@ -1,6 +1,8 @@
 package article
 
 import (
+	"encoding/json"
+	"slices"
 	"testing"
 )
 
@@ -90,3 +92,60 @@ func TestRelevanceCalculation(t *testing.T) {
 		}
 	}
 }
+
+// Providers send the response schemas in strict mode, which requires every
+// property of every object to be required.
+func TestSearchQueriesSchemaStrict(t *testing.T) {
+	data, err := json.Marshal(newTestAgent().createSearchQueriesSchema().Schema())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	objects := 0
+
+	var walk func(path string, node any)
+	walk = func(path string, node any) {
+		switch n := node.(type) {
+		case map[string]any:
+			if properties, ok := n["properties"].(map[string]any); ok {
+				objects++
+
+				required := make([]string, 0)
+				if list, ok := n["required"].([]any); ok {
+					for _, name := range list {
+						required = append(required, name.(string))
+					}
+				}
+
+				for name := range properties {
+					if !slices.Contains(required, name) {
+						t.Errorf("expected property %q of %s to be required", name, path)
+					}
+				}
+
+				if n["additionalProperties"] != false {
+					t.Errorf("expected %s not to allow additional properties", path)
+				}
+			}
+
+			for key, child := range n {
+				walk(path+"."+key, child)
+			}
+		case []any:
+			for _, child := range n {
+				walk(path, child)
+			}
+		}
+	}
+
+	walk("$", schema)
+
+	if objects != 2 {
+		t.Errorf("expected the response and the query objects, got %d objects", objects)
+	}
+}
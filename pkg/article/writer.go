@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"strings"
 	"text/template"
-	"time"
 
 	"github.com/bornholm/genai/agent"
 	"github.com/bornholm/genai/agent/loop"
@@ -69,7 +68,7 @@ func (h *WriterHandler) writeSection(ctx context.Context, section DocumentSectio
 
 	// Load the writer system prompt
 	systemPrompt, err := prompt.FromFS[any](&writerPrompts, "prompts/writer_system.gotmpl", nil, prompt.WithFuncs(template.FuncMap{
-		"now": func() string { return time.Now().Format("2006-01-02") },
+		"now": func() string { return ContextNow(ctx).Format("2006-01-02") },
 	}))
 	if err != nil {
 		return SectionContent{}, errors.WithStack(err)
@@ -230,7 +229,7 @@ func (h *WriterHandler) reviseSection(ctx context.Context, section DocumentSecti
 		})
 
 	systemPrompt, err := prompt.FromFS[any](&writerPrompts, "prompts/writer_system.gotmpl", nil, prompt.WithFuncs(template.FuncMap{
-		"now": func() string { return time.Now().Format("2006-01-02") },
+		"now": func() string { return ContextNow(ctx).Format("2006-01-02") },
 	}))
 	if err != nil {
 		return SectionContent{}, errors.WithStack(err)
@@ -0,0 +1,220 @@
+package cassette
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/pkg/errors"
+)
+
+// Cache is an llm.Client serving the chat completions of the client it wraps
+// from a directory of cached responses. Entries are keyed by a hash of the
+// model and the request, and expire once older than the TTL of the cache.
+// Embeddings are passed through uncached.
+//
+// Unlike a Player, a Cache calls the client it wraps for the requests it has
+// no fresh entry for. Failing to read or write an entry is logged and does not
+// fail the chat completion.
+type Cache struct {
+	client llm.Client
+	dir    string
+	ttl    time.Duration
+	model  func(ctx context.Context) string
+	now    func() time.Time
+}
+
+// cacheEntry is the content of a cache file: the interaction and the model
+// that served it.
+type cacheEntry struct {
+	Model string `json:"model"`
+	Interaction
+}
+
+// NewCache returns a Cache storing its entries in dir. Entries older than ttl
+// are refreshed; a ttl of zero keeps them forever. model returns the
+// "provider/model" identifier of the client serving a call.
+func NewCache(client llm.Client, dir string, ttl time.Duration, model func(ctx context.Context) string) *Cache {
+	return &Cache{
+		client: client,
+		dir:    dir,
+		ttl:    ttl,
+		model:  model,
+		now:    time.Now,
+	}
+}
+
+// ChatCompletion implements llm.Client.
+func (c *Cache) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	entry, cached, err := c.lookup(ctx, funcs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if cached {
+		return entry.Response.ChatCompletionResponse(), nil
+	}
+
+	res, err := c.client.ChatCompletion(ctx, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	response, err := NewResponse(res)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	entry.Response = response
+	c.store(ctx, entry)
+
+	return res, nil
+}
+
+// ChatCompletionStream implements llm.Client. A cached response is streamed
+// as a Player does; otherwise the response is cached once the stream
+// completes.
+func (c *Cache) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	entry, cached, err := c.lookup(ctx, funcs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if cached {
+		chunks := entry.Response.StreamChunks()
+
+		stream := make(chan llm.StreamChunk, len(chunks))
+		for _, chunk := range chunks {
+			stream <- chunk
+		}
+		close(stream)
+
+		return stream, nil
+	}
+
+	stream, err := c.client.ChatCompletionStream(ctx, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return teeStream(ctx, stream, func(res Response) error {
+		entry.Response = res
+		c.store(ctx, entry)
+		return nil
+	}), nil
+}
+
+// Embeddings implements llm.Client.
+func (c *Cache) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	res, err := c.client.Embeddings(ctx, inputs, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+// lookup returns the entry of the request of funcs, and whether it was found
+// fresh in the cache. Otherwise the entry has no response yet.
+func (c *Cache) lookup(ctx context.Context, funcs []llm.ChatCompletionOptionFunc) (cacheEntry, bool, error) {
+	req, err := NewRequest(llm.NewChatCompletionOptions(funcs...))
+	if err != nil {
+		return cacheEntry{}, false, errors.WithStack(err)
+	}
+
+	model := c.model(ctx)
+
+	data, err := json.Marshal(struct {
+		Model   string  `json:"model"`
+		Request Request `json:"request"`
+	}{model, req})
+	if err != nil {
+		return cacheEntry{}, false, errors.Wrap(err, "could not marshal request")
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	entry := cacheEntry{
+		Model:       model,
+		Interaction: Interaction{Hash: hash, Request: req},
+	}
+
+	data, err = os.ReadFile(c.path(hash))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.WarnContext(ctx, "could not read llm cache entry", slog.String("hash", hash), slog.Any("error", err))
+		}
+		return entry, false, nil
+	}
+
+	var cached cacheEntry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		slog.WarnContext(ctx, "invalid llm cache entry", slog.String("hash", hash), slog.Any("error", err))
+		return entry, false, nil
+	}
+
+	if c.ttl > 0 && c.now().Sub(cached.RecordedAt) > c.ttl {
+		slog.DebugContext(ctx, "llm cache entry expired", slog.String("hash", hash), slog.Time("cached_at", cached.RecordedAt))
+		return entry, false, nil
+	}
+
+	slog.DebugContext(ctx, "llm cache hit", slog.String("hash", hash), slog.String("model", model))
+
+	return cached, true, nil
+}
+
+// store writes entry to the cache. The file is written next to its final
+// path then renamed, so that concurrent runs never read a partial entry.
+func (c *Cache) store(ctx context.Context, entry cacheEntry) {
+	entry.RecordedAt = c.now()
+
+	if err := c.write(entry); err != nil {
+		slog.WarnContext(ctx, "could not write llm cache entry", slog.String("hash", entry.Hash), slog.Any("error", err))
+	}
+}
+
+func (c *Cache) write(entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal cache entry")
+	}
+
+	path := c.path(entry.Hash)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrapf(err, "could not create cache directory %q", filepath.Dir(path))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "could not create cache entry")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "could not write cache entry %q", tmp.Name())
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "could not write cache entry %q", tmp.Name())
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrapf(err, "could not write cache entry %q", path)
+	}
+
+	return nil
+}
+
+// path returns the file of the entry of hash, sharded by the first bytes of
+// the hash to keep directories small.
+func (c *Cache) path(hash string) string {
+	return filepath.Join(c.dir, hash[:2], hash+".json")
+}
+
+var _ llm.Client = &Cache{}
@@ -0,0 +1,94 @@
+package cassette
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	model := "openrouter/model-a"
+
+	upstream := &scriptedClient{responses: []Response{
+		{Content: "first", Usage: &Usage{PromptTokens: 10, CompletionTokens: 1, TotalTokens: 11}},
+		{Content: "second"},
+		{Content: "third"},
+	}}
+
+	cache := NewCache(upstream, dir, time.Hour, func(context.Context) string { return model })
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	req := []llm.ChatCompletionOptionFunc{
+		llm.WithMessages(llm.NewMessage(llm.RoleUser, "Write the introduction")),
+		llm.WithTools(fakeTool{}),
+	}
+
+	first := mustComplete(t, cache, req)
+	if first.Message().Content() != "first" {
+		t.Fatalf("expected the upstream response, got %q", first.Message().Content())
+	}
+
+	// Served from the cache, streamed or not
+	assertSameResponse(t, first, mustComplete(t, cache, req))
+	assertSameResponse(t, first, mustStream(t, cache, req))
+	if upstream.calls != 1 {
+		t.Errorf("expected a single upstream call, got %d", upstream.calls)
+	}
+
+	// Another request, or the same one sent to another model, is a miss
+	other := append(req, llm.WithTemperature(0.2))
+	if res := mustComplete(t, cache, other); res.Message().Content() != "second" {
+		t.Errorf("expected a miss for another request, got %q", res.Message().Content())
+	}
+
+	model = "openrouter/model-b"
+	if res := mustStream(t, cache, req); res.Message().Content() != "third" {
+		t.Errorf("expected a miss for another model, got %q", res.Message().Content())
+	}
+	model = "openrouter/model-a"
+
+	// The streamed response was cached as well
+	if res := mustComplete(t, NewCache(upstream, dir, 0, func(context.Context) string { return "openrouter/model-b" }), req); res.Message().Content() != "third" {
+		t.Errorf("expected the streamed response to be cached, got %q", res.Message().Content())
+	}
+
+	// Expired entries are refreshed
+	now = now.Add(2 * time.Hour)
+	if res := mustComplete(t, cache, req); res.Message().Content() != "first" {
+		t.Errorf("expected an expired entry to be refreshed, got %q", res.Message().Content())
+	}
+	if upstream.calls != 4 {
+		t.Errorf("expected 4 upstream calls, got %d", upstream.calls)
+	}
+}
+
+func TestCacheIgnoresInvalidEntries(t *testing.T) {
+	dir := t.TempDir()
+	upstream := &scriptedClient{responses: []Response{{Content: "fresh"}}}
+	cache := NewCache(upstream, dir, 0, func(context.Context) string { return "" })
+
+	req := []llm.ChatCompletionOptionFunc{llm.WithMessages(llm.NewMessage(llm.RoleUser, "Hello"))}
+	mustComplete(t, cache, req)
+
+	entries, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected a single cache entry, got %v (%v)", entries, err)
+	}
+
+	if err := os.WriteFile(entries[0], []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if res := mustComplete(t, cache, req); res.Message().Content() != "fresh" {
+		t.Errorf("expected the upstream response, got %q", res.Message().Content())
+	}
+	if upstream.calls != 2 {
+		t.Errorf("expected the invalid entry to be replaced, got %d upstream calls", upstream.calls)
+	}
+}
@@ -0,0 +1,360 @@
+// Package cassette records the chat completions and the embeddings of an LLM
+// client to a file and replays them, so that a run can be reproduced without
+// network access.
+//
+// A cassette is a JSON Lines file with one Interaction per line. Requests are
+// matched by a hash of their messages, tools, response schema and sampling
+// options, or of their embedded inputs: replaying a cassette only succeeds if
+// the run sends the same requests as the recorded one.
+//
+// The same requests key the entries of a Cache, which serves the responses of
+// the requests already sent and forwards the others.
+package cassette
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/pkg/errors"
+)
+
+// ErrNotRecorded is returned when replaying a request missing from the
+// cassette.
+var ErrNotRecorded = errors.New("request not recorded in cassette")
+
+// Interaction is a recorded chat completion or embeddings call.
+type Interaction struct {
+	Hash       string    `json:"hash"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Request    Request   `json:"request"`
+	Response   Response  `json:"response"`
+}
+
+// Request is the part of the chat completion or embeddings options
+// identifying a request.
+type Request struct {
+	Messages            []Message `json:"messages"`
+	Tools               []Tool    `json:"tools,omitempty"`
+	ToolChoice          string    `json:"tool_choice,omitempty"`
+	Temperature         float64   `json:"temperature"`
+	ResponseFormat      string    `json:"response_format,omitempty"`
+	ResponseSchema      *Schema   `json:"response_schema,omitempty"`
+	Seed                *int      `json:"seed,omitempty"`
+	MaxCompletionTokens *int      `json:"max_completion_tokens,omitempty"`
+	// Inputs are the texts of an embeddings request.
+	Inputs     []string `json:"inputs,omitempty"`
+	Dimensions *int     `json:"dimensions,omitempty"`
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content,omitempty"`
+	// ToolCallID is the identifier of the tool call answered by a tool message.
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+}
+
+type ToolCall struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Parameters string `json:"parameters"`
+}
+
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type Schema struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Schema      any    `json:"schema"`
+}
+
+// Response is a recorded chat completion or embeddings response.
+type Response struct {
+	Content    string      `json:"content"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	Reasoning  string      `json:"reasoning,omitempty"`
+	Embeddings [][]float64 `json:"embeddings,omitempty"`
+	Usage      *Usage      `json:"usage,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// NewRequest extracts the Request of chat completion options.
+func NewRequest(opts *llm.ChatCompletionOptions) (Request, error) {
+	req := Request{
+		Messages:            make([]Message, 0, len(opts.Messages)),
+		ToolChoice:          string(opts.ToolChoice),
+		Temperature:         opts.Temperature,
+		ResponseFormat:      string(opts.ResponseFormat),
+		Seed:                opts.Seed,
+		MaxCompletionTokens: opts.MaxCompletionTokens,
+	}
+
+	for _, m := range opts.Messages {
+		message := Message{
+			Role:    string(m.Role()),
+			Content: m.Content(),
+		}
+
+		switch typed := m.(type) {
+		// A tool call is also a tool message: match the tool calls first
+		case llm.ToolCallsMessage:
+			toolCalls, err := newToolCalls(typed.ToolCalls())
+			if err != nil {
+				return Request{}, errors.WithStack(err)
+			}
+			message.ToolCalls = toolCalls
+		case llm.ToolMessage:
+			message.ToolCallID = typed.ID()
+		}
+
+		req.Messages = append(req.Messages, message)
+	}
+
+	for _, t := range opts.Tools {
+		req.Tools = append(req.Tools, Tool{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Parameters:  t.Parameters(),
+		})
+	}
+
+	if s := opts.ResponseSchema; s != nil {
+		req.ResponseSchema = &Schema{
+			Name:        s.Name(),
+			Description: s.Description(),
+			Schema:      s.Schema(),
+		}
+	}
+
+	return req, nil
+}
+
+// NewEmbeddingsRequest returns the Request of an embeddings call.
+func NewEmbeddingsRequest(inputs []string, opts *llm.EmbeddingsOptions) Request {
+	return Request{
+		Inputs:     inputs,
+		Dimensions: opts.Dimensions,
+	}
+}
+
+// Hash returns the identifier of the request in a cassette.
+func (r Request) Hash() (string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal request")
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NewResponse extracts the Response of a chat completion response.
+func NewResponse(res llm.ChatCompletionResponse) (Response, error) {
+	toolCalls, err := newToolCalls(res.ToolCalls())
+	if err != nil {
+		return Response{}, errors.WithStack(err)
+	}
+
+	response := Response{ToolCalls: toolCalls}
+
+	if m := res.Message(); m != nil {
+		response.Content = m.Content()
+	}
+
+	if r, ok := res.(llm.ReasoningChatCompletionResponse); ok {
+		response.Reasoning = r.Reasoning()
+	}
+
+	if u := res.Usage(); u != nil {
+		response.Usage = &Usage{
+			PromptTokens:     u.PromptTokens(),
+			CompletionTokens: u.CompletionTokens(),
+			TotalTokens:      u.TotalTokens(),
+		}
+	}
+
+	return response, nil
+}
+
+// NewEmbeddingsResponse extracts the Response of an embeddings response.
+func NewEmbeddingsResponse(res llm.EmbeddingsResponse) Response {
+	response := Response{Embeddings: res.Embeddings()}
+
+	if u := res.Usage(); u != nil {
+		response.Usage = &Usage{
+			PromptTokens: u.PromptTokens(),
+			TotalTokens:  u.TotalTokens(),
+		}
+	}
+
+	return response
+}
+
+// EmbeddingsResponse converts the recorded embeddings response back.
+func (r Response) EmbeddingsResponse() llm.EmbeddingsResponse {
+	res := &embeddingsResponse{
+		embeddings: r.Embeddings,
+		usage:      llm.NewEmbeddingsUsage(0, 0),
+	}
+	if r.Usage != nil {
+		res.usage = llm.NewEmbeddingsUsage(r.Usage.PromptTokens, r.Usage.TotalTokens)
+	}
+	return res
+}
+
+type embeddingsResponse struct {
+	embeddings [][]float64
+	usage      llm.EmbeddingsUsage
+}
+
+func (r *embeddingsResponse) Embeddings() [][]float64 {
+	return r.embeddings
+}
+
+func (r *embeddingsResponse) Usage() llm.EmbeddingsUsage {
+	return r.usage
+}
+
+// ChatCompletionResponse converts the recorded response back.
+func (r Response) ChatCompletionResponse() llm.ChatCompletionResponse {
+	return llm.NewChatCompletionResponseWithReasoning(
+		llm.NewMessage(llm.RoleAssistant, r.Content),
+		r.chatCompletionUsage(),
+		r.Reasoning,
+		nil,
+		r.llmToolCalls()...,
+	)
+}
+
+// StreamChunks converts the recorded response back to the chunks of a
+// stream: a single delta followed by the completion chunk.
+func (r Response) StreamChunks() []llm.StreamChunk {
+	deltas := make([]llm.ToolCallDelta, 0, len(r.ToolCalls))
+	for i, tc := range r.ToolCalls {
+		deltas = append(deltas, llm.NewToolCallDelta(i, tc.ID, tc.Name, tc.Parameters))
+	}
+
+	return []llm.StreamChunk{
+		llm.NewStreamChunk(llm.NewReasoningStreamDelta(llm.RoleAssistant, r.Content, r.Reasoning, nil, deltas...)),
+		llm.NewCompleteStreamChunk(r.chatCompletionUsage()),
+	}
+}
+
+func (r Response) chatCompletionUsage() llm.ChatCompletionUsage {
+	if r.Usage == nil {
+		return llm.NewChatCompletionUsage(0, 0, 0)
+	}
+	return llm.NewChatCompletionUsage(r.Usage.PromptTokens, r.Usage.CompletionTokens, r.Usage.TotalTokens)
+}
+
+func (r Response) llmToolCalls() []llm.ToolCall {
+	toolCalls := make([]llm.ToolCall, 0, len(r.ToolCalls))
+	for _, tc := range r.ToolCalls {
+		toolCalls = append(toolCalls, llm.NewToolCall(tc.ID, tc.Name, tc.Parameters))
+	}
+	return toolCalls
+}
+
+func newToolCalls(toolCalls []llm.ToolCall) ([]ToolCall, error) {
+	if len(toolCalls) == 0 {
+		return nil, nil
+	}
+
+	recorded := make([]ToolCall, 0, len(toolCalls))
+	for _, tc := range toolCalls {
+		var params string
+		switch p := tc.Parameters().(type) {
+		case string:
+			params = p
+		case nil:
+		default:
+			data, err := json.Marshal(p)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not marshal parameters of tool call %q", tc.Name())
+			}
+			params = string(data)
+		}
+
+		recorded = append(recorded, ToolCall{ID: tc.ID(), Name: tc.Name(), Parameters: params})
+	}
+
+	return recorded, nil
+}
+
+// Load reads the interactions of a cassette file.
+func Load(path string) ([]Interaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read cassette %q", path)
+	}
+
+	interactions := make([]Interaction, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, errors.Wrapf(err, "invalid interaction at %s:%d", path, line)
+		}
+
+		interactions = append(interactions, interaction)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "could not read cassette %q", path)
+	}
+
+	return interactions, nil
+}
+
+// RecordedAt returns the time of the first recorded interaction, or the zero
+// time for an empty cassette.
+func RecordedAt(interactions []Interaction) time.Time {
+	var first time.Time
+	for _, i := range interactions {
+		if first.IsZero() || i.RecordedAt.Before(first) {
+			first = i.RecordedAt
+		}
+	}
+	return first
+}
+
+func describe(req Request) string {
+	if len(req.Inputs) > 0 {
+		return fmt.Sprintf("embeddings of %d inputs", len(req.Inputs))
+	}
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if content := req.Messages[i].Content; content != "" {
+			if runes := []rune(content); len(runes) > 80 {
+				content = string(runes[:80]) + "…"
+			}
+			return fmt.Sprintf("%d messages, last: %q", len(req.Messages), content)
+		}
+	}
+	return fmt.Sprintf("%d messages", len(req.Messages))
+}
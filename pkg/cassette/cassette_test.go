@@ -0,0 +1,332 @@
+package cassette
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/pkg/errors"
+)
+
+// scriptedClient answers the chat completions with its responses, in order.
+type scriptedClient struct {
+	responses []Response
+	calls     int
+}
+
+func (c *scriptedClient) next() Response {
+	res := c.responses[c.calls%len(c.responses)]
+	c.calls++
+	return res
+}
+
+func (c *scriptedClient) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	return c.next().ChatCompletionResponse(), nil
+}
+
+// ChatCompletionStream splits the content and the tool call parameters over
+// several chunks, as the providers do.
+func (c *scriptedClient) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	res := c.next()
+
+	stream := make(chan llm.StreamChunk, 16)
+	for _, word := range strings.SplitAfter(res.Content, " ") {
+		stream <- llm.NewStreamChunk(llm.NewStreamDelta(llm.RoleAssistant, word))
+	}
+	for i, tc := range res.ToolCalls {
+		half := len(tc.Parameters) / 2
+		stream <- llm.NewStreamChunk(llm.NewStreamDelta(llm.RoleAssistant, "", llm.NewToolCallDelta(i+1, tc.ID, tc.Name, tc.Parameters[:half])))
+		stream <- llm.NewStreamChunk(llm.NewStreamDelta(llm.RoleAssistant, "", llm.NewToolCallDelta(i+1, "", "", tc.Parameters[half:])))
+	}
+	stream <- llm.NewCompleteStreamChunk(res.chatCompletionUsage())
+	close(stream)
+
+	return stream, nil
+}
+
+// Embeddings embeds each input as its length.
+func (c *scriptedClient) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	embeddings := make([][]float64, 0, len(inputs))
+	for _, input := range inputs {
+		embeddings = append(embeddings, []float64{float64(len(input))})
+	}
+	return Response{Embeddings: embeddings, Usage: &Usage{PromptTokens: 5, TotalTokens: 5}}.EmbeddingsResponse(), nil
+}
+
+type fakeTool struct{}
+
+func (fakeTool) Name() string        { return "search_knowledge_base" }
+func (fakeTool) Description() string { return "Search the knowledge base" }
+func (fakeTool) Parameters() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{"query": map[string]any{"type": "string"}}}
+}
+func (fakeTool) Execute(ctx context.Context, params map[string]any) (llm.ToolResult, error) {
+	return llm.NewToolResult("nothing"), nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "run.jsonl")
+
+	toolCallResponse := Response{
+		ToolCalls: []ToolCall{{ID: "call_1", Name: "search_knowledge_base", Parameters: `{"query":"solar panels"}`}},
+		Usage:     &Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110},
+	}
+	planResponse := Response{
+		Content: `{"title": "Solar energy"}`,
+		Usage:   &Usage{PromptTokens: 200, CompletionTokens: 20, TotalTokens: 220},
+	}
+	chapterResponse := Response{
+		Content: "Solar panels convert sunlight into electricity.",
+		Usage:   &Usage{PromptTokens: 300, CompletionTokens: 30, TotalTokens: 330},
+	}
+
+	upstream := &scriptedClient{responses: []Response{toolCallResponse, planResponse, chapterResponse}}
+
+	recorder, err := NewRecorder(upstream, path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	toolCall := llm.NewToolCall("call_1", "search_knowledge_base", `{"query":"solar panels"}`)
+	conversation := []llm.ChatCompletionOptionFunc{
+		llm.WithMessages(
+			llm.NewMessage(llm.RoleSystem, "You are a writer."),
+			llm.NewMessage(llm.RoleUser, "Write about solar energy."),
+		),
+		llm.WithTools(fakeTool{}),
+	}
+	followUp := []llm.ChatCompletionOptionFunc{
+		llm.WithMessages(
+			llm.NewMessage(llm.RoleSystem, "You are a writer."),
+			llm.NewMessage(llm.RoleUser, "Write about solar energy."),
+			llm.NewToolCallsMessage(toolCall),
+			llm.NewToolMessage("call_1", llm.NewToolResult("Solar panels are made of cells.")),
+		),
+		llm.WithTools(fakeTool{}),
+		llm.WithResponseSchema(llm.NewResponseSchema("plan", "The plan", map[string]any{"type": "object"})),
+	}
+	chapter := []llm.ChatCompletionOptionFunc{
+		llm.WithMessages(llm.NewMessage(llm.RoleUser, "Write the first chapter.")),
+		llm.WithTemperature(0.2),
+	}
+
+	recorded := []llm.ChatCompletionResponse{
+		mustComplete(t, recorder, conversation),
+		mustComplete(t, recorder, followUp),
+		mustStream(t, recorder, chapter),
+	}
+
+	interactions, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(interactions) != 3 {
+		t.Fatalf("expected 3 interactions, got %d", len(interactions))
+	}
+	if interactions[1].Request.ResponseSchema == nil || interactions[1].Request.Messages[3].ToolCallID != "call_1" {
+		t.Errorf("unexpected recorded request: %+v", interactions[1].Request)
+	}
+
+	player, err := NewPlayer(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if player.RecordedAt().IsZero() {
+		t.Error("expected the recording time of the cassette")
+	}
+
+	// Replay in a different order, streaming the calls made without streaming
+	replayed := []llm.ChatCompletionResponse{
+		mustStream(t, player, conversation),
+		mustComplete(t, player, followUp),
+		mustComplete(t, player, chapter),
+	}
+
+	for i := range recorded {
+		assertSameResponse(t, recorded[i], replayed[i])
+	}
+
+	if replayed[2].Usage().TotalTokens() != 330 {
+		t.Errorf("expected the recorded usage, got %d tokens", replayed[2].Usage().TotalTokens())
+	}
+}
+
+func TestRecordReplayEmbeddings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+
+	chat := &scriptedClient{responses: []Response{{Content: "Solar panels."}}}
+	recorder, err := NewRecorder(chat, path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// The embeddings client of the knowledge base records to the same cassette
+	embeddings := recorder.Wrap(&scriptedClient{})
+
+	question := []llm.ChatCompletionOptionFunc{llm.WithMessages(llm.NewMessage(llm.RoleUser, "Solar?"))}
+	mustComplete(t, recorder, question)
+
+	recorded, err := embeddings.Embeddings(context.Background(), []string{"solar", "panels"}, llm.WithDimensions(2))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	interactions, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(interactions))
+	}
+
+	player, err := NewPlayer(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	replayed, err := player.Embeddings(context.Background(), []string{"solar", "panels"}, llm.WithDimensions(2))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if e, a := recorded.Embeddings(), replayed.Embeddings(); len(a) != 2 || e[0][0] != a[0][0] || e[1][0] != a[1][0] {
+		t.Errorf("expected embeddings %v, got %v", e, a)
+	}
+	if replayed.Usage().TotalTokens() != 5 {
+		t.Errorf("expected the recorded usage, got %d tokens", replayed.Usage().TotalTokens())
+	}
+
+	if _, err := player.Embeddings(context.Background(), []string{"solar", "panels"}); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded for other dimensions, got: %v", err)
+	}
+}
+
+func TestPlayerServesRepeatedRequestsInOrder(t *testing.T) {
+	req := []llm.ChatCompletionOptionFunc{llm.WithMessages(llm.NewMessage(llm.RoleUser, "Next?"))}
+	hash := mustHash(t, req)
+
+	player := NewPlayerFromInteractions([]Interaction{
+		{Hash: hash, Response: Response{Content: "first"}},
+		{Hash: hash, Response: Response{Content: "second"}},
+	})
+
+	for _, expected := range []string{"first", "second", "second"} {
+		if res := mustComplete(t, player, req); res.Message().Content() != expected {
+			t.Errorf("expected %q, got %q", expected, res.Message().Content())
+		}
+	}
+}
+
+func TestPlayerNotRecorded(t *testing.T) {
+	player := NewPlayerFromInteractions(nil)
+
+	_, err := player.ChatCompletion(context.Background(), llm.WithMessages(llm.NewMessage(llm.RoleUser, "Unknown")))
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded, got: %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), `"Unknown"`) {
+		t.Errorf("expected the error to describe the request, got: %v", err)
+	}
+}
+
+func TestRequestHash(t *testing.T) {
+	base := func(funcs ...llm.ChatCompletionOptionFunc) []llm.ChatCompletionOptionFunc {
+		return append([]llm.ChatCompletionOptionFunc{
+			llm.WithMessages(llm.NewMessage(llm.RoleUser, "Hello")),
+			llm.WithTools(fakeTool{}),
+		}, funcs...)
+	}
+
+	hash := mustHash(t, base())
+	if again := mustHash(t, base()); again != hash {
+		t.Errorf("expected a stable hash, got %s and %s", hash, again)
+	}
+
+	variants := map[string][]llm.ChatCompletionOptionFunc{
+		"temperature":     base(llm.WithTemperature(0.1)),
+		"response schema": base(llm.WithResponseSchema(llm.NewResponseSchema("answer", "", map[string]any{"type": "object"}))),
+		"tool choice":     base(llm.WithToolChoice(llm.ToolChoiceRequired)),
+	}
+	for name, funcs := range variants {
+		if mustHash(t, funcs) == hash {
+			t.Errorf("expected the %s to change the hash", name)
+		}
+	}
+}
+
+func mustHash(t *testing.T, funcs []llm.ChatCompletionOptionFunc) string {
+	t.Helper()
+
+	req, err := NewRequest(llm.NewChatCompletionOptions(funcs...))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	hash, err := req.Hash()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	return hash
+}
+
+func mustComplete(t *testing.T, client llm.Client, funcs []llm.ChatCompletionOptionFunc) llm.ChatCompletionResponse {
+	t.Helper()
+
+	res, err := client.ChatCompletion(context.Background(), funcs...)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	return res
+}
+
+// mustStream consumes a stream the way the agent loop does and returns the
+// rebuilt response.
+func mustStream(t *testing.T, client llm.Client, funcs []llm.ChatCompletionOptionFunc) llm.ChatCompletionResponse {
+	t.Helper()
+
+	stream, err := client.ChatCompletionStream(context.Background(), funcs...)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	acc := &streamAccumulator{}
+	var usage llm.ChatCompletionUsage
+	for chunk := range stream {
+		if err := chunk.Error(); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if chunk.IsComplete() {
+			usage = chunk.Usage()
+			continue
+		}
+		acc.add(chunk)
+	}
+
+	return acc.response(usage).ChatCompletionResponse()
+}
+
+func assertSameResponse(t *testing.T, expected, actual llm.ChatCompletionResponse) {
+	t.Helper()
+
+	if e, a := expected.Message().Content(), actual.Message().Content(); e != a {
+		t.Errorf("expected content %q, got %q", e, a)
+	}
+
+	if e, a := len(expected.ToolCalls()), len(actual.ToolCalls()); e != a {
+		t.Fatalf("expected %d tool calls, got %d", e, a)
+	}
+
+	for i, e := range expected.ToolCalls() {
+		a := actual.ToolCalls()[i]
+		if e.ID() != a.ID() || e.Name() != a.Name() || e.Parameters() != a.Parameters() {
+			t.Errorf("expected tool call %v %v %v, got %v %v %v", e.ID(), e.Name(), e.Parameters(), a.ID(), a.Name(), a.Parameters())
+		}
+	}
+
+	if e, a := expected.Usage().TotalTokens(), actual.Usage().TotalTokens(); e != a {
+		t.Errorf("expected %d tokens, got %d", e, a)
+	}
+}
@@ -0,0 +1,120 @@
+package cassette
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/pkg/errors"
+)
+
+// Player is an llm.Client serving the chat completions and the embeddings
+// recorded in a cassette. Identical requests are served their recorded
+// responses in order; once they are exhausted, the last one is served again.
+type Player struct {
+	recordedAt time.Time
+
+	mu        sync.Mutex
+	responses map[string][]Response
+	served    map[string]int
+}
+
+// NewPlayer returns a Player serving the interactions of the cassette at path.
+func NewPlayer(path string) (*Player, error) {
+	interactions, err := Load(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return NewPlayerFromInteractions(interactions), nil
+}
+
+// NewPlayerFromInteractions returns a Player serving interactions.
+func NewPlayerFromInteractions(interactions []Interaction) *Player {
+	p := &Player{
+		recordedAt: RecordedAt(interactions),
+		responses:  make(map[string][]Response),
+		served:     make(map[string]int),
+	}
+
+	for _, i := range interactions {
+		p.responses[i.Hash] = append(p.responses[i.Hash], i.Response)
+	}
+
+	return p
+}
+
+// RecordedAt returns the time the cassette was recorded at.
+func (p *Player) RecordedAt() time.Time {
+	return p.recordedAt
+}
+
+// ChatCompletion implements llm.Client.
+func (p *Player) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	res, err := p.next(funcs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return res.ChatCompletionResponse(), nil
+}
+
+// ChatCompletionStream implements llm.Client.
+func (p *Player) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	res, err := p.next(funcs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	chunks := res.StreamChunks()
+
+	stream := make(chan llm.StreamChunk, len(chunks))
+	for _, c := range chunks {
+		stream <- c
+	}
+	close(stream)
+
+	return stream, nil
+}
+
+// Embeddings implements llm.Client.
+func (p *Player) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	res, err := p.serve(NewEmbeddingsRequest(inputs, llm.NewEmbeddingsOptions(funcs...)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return res.EmbeddingsResponse(), nil
+}
+
+func (p *Player) next(funcs []llm.ChatCompletionOptionFunc) (Response, error) {
+	req, err := NewRequest(llm.NewChatCompletionOptions(funcs...))
+	if err != nil {
+		return Response{}, errors.WithStack(err)
+	}
+
+	return p.serve(req)
+}
+
+func (p *Player) serve(req Request) (Response, error) {
+	hash, err := req.Hash()
+	if err != nil {
+		return Response{}, errors.WithStack(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	responses := p.responses[hash]
+	if len(responses) == 0 {
+		return Response{}, errors.Wrapf(ErrNotRecorded, "request %s (%s)", hash[:12], describe(req))
+	}
+
+	idx := min(p.served[hash], len(responses)-1)
+	p.served[hash]++
+
+	return responses[idx], nil
+}
+
+var _ llm.Client = &Player{}
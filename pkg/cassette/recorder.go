@@ -0,0 +1,232 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/pkg/errors"
+)
+
+// Recorder is an llm.Client appending every chat completion and embeddings
+// call of the client it wraps to a cassette file.
+type Recorder struct {
+	client llm.Client
+	path   string
+
+	mu *sync.Mutex
+}
+
+// NewRecorder returns a Recorder writing to path. An existing cassette is
+// truncated.
+func NewRecorder(client llm.Client, path string) (*Recorder, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, errors.Wrapf(err, "could not create directory of cassette %q", path)
+		}
+	}
+
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		return nil, errors.Wrapf(err, "could not create cassette %q", path)
+	}
+
+	return &Recorder{client: client, path: path, mu: &sync.Mutex{}}, nil
+}
+
+// Wrap returns a Recorder of another client writing to the same cassette, e.g.
+// to record the calls of a dedicated embeddings client.
+func (r *Recorder) Wrap(client llm.Client) *Recorder {
+	return &Recorder{client: client, path: r.path, mu: r.mu}
+}
+
+// ChatCompletion implements llm.Client.
+func (r *Recorder) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	req, err := NewRequest(llm.NewChatCompletionOptions(funcs...))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res, err := r.client.ChatCompletion(ctx, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	response, err := NewResponse(res)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := r.record(req, response); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return res, nil
+}
+
+// ChatCompletionStream implements llm.Client. The chunks are forwarded as
+// they arrive and the response is recorded once the stream completes.
+func (r *Recorder) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	req, err := NewRequest(llm.NewChatCompletionOptions(funcs...))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	stream, err := r.client.ChatCompletionStream(ctx, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return teeStream(ctx, stream, func(res Response) error {
+		return r.record(req, res)
+	}), nil
+}
+
+// Embeddings implements llm.Client.
+func (r *Recorder) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	req := NewEmbeddingsRequest(inputs, llm.NewEmbeddingsOptions(funcs...))
+
+	res, err := r.client.Embeddings(ctx, inputs, funcs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := r.record(req, NewEmbeddingsResponse(res)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return res, nil
+}
+
+func (r *Recorder) record(req Request, res Response) error {
+	hash, err := req.Hash()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	data, err := json.Marshal(Interaction{
+		Hash:       hash,
+		RecordedAt: time.Now(),
+		Request:    req,
+		Response:   res,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not marshal interaction")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return errors.Wrapf(err, "could not open cassette %q", r.path)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return errors.Wrapf(err, "could not write cassette %q", r.path)
+	}
+
+	return nil
+}
+
+var _ llm.Client = &Recorder{}
+
+// teeStream forwards the chunks of stream as they arrive and calls done with
+// the response they build once the stream completes. An error returned by
+// done replaces the completion chunk.
+func teeStream(ctx context.Context, stream <-chan llm.StreamChunk, done func(Response) error) <-chan llm.StreamChunk {
+	forwarded := make(chan llm.StreamChunk)
+	go func() {
+		defer close(forwarded)
+
+		acc := &streamAccumulator{}
+		for chunk := range stream {
+			if chunk.IsComplete() {
+				if err := done(acc.response(chunk.Usage())); err != nil {
+					chunk = llm.NewErrorStreamChunk(errors.WithStack(err))
+				}
+			} else {
+				acc.add(chunk)
+			}
+
+			select {
+			case forwarded <- chunk:
+			case <-ctx.Done():
+				// Drain the stream so that the provider goroutine can exit
+				for range stream {
+				}
+				return
+			}
+		}
+	}()
+
+	return forwarded
+}
+
+// streamAccumulator rebuilds a response from the deltas of a stream.
+type streamAccumulator struct {
+	content   strings.Builder
+	reasoning strings.Builder
+	toolCalls map[int]*ToolCall
+}
+
+func (a *streamAccumulator) add(chunk llm.StreamChunk) {
+	delta := chunk.Delta()
+	if chunk.Type() != llm.StreamChunkTypeDelta || delta == nil {
+		return
+	}
+
+	a.content.WriteString(delta.Content())
+
+	if rd, ok := delta.(llm.ReasoningStreamDelta); ok {
+		a.reasoning.WriteString(rd.Reasoning())
+	}
+
+	for _, tc := range delta.ToolCalls() {
+		if a.toolCalls == nil {
+			a.toolCalls = make(map[int]*ToolCall)
+		}
+		recorded, exists := a.toolCalls[tc.Index()]
+		if !exists {
+			recorded = &ToolCall{}
+			a.toolCalls[tc.Index()] = recorded
+		}
+		if id := tc.ID(); id != "" {
+			recorded.ID = id
+		}
+		if name := tc.Name(); name != "" {
+			recorded.Name = name
+		}
+		recorded.Parameters += tc.ParametersDelta()
+	}
+}
+
+func (a *streamAccumulator) response(usage llm.ChatCompletionUsage) Response {
+	res := Response{
+		Content:   a.content.String(),
+		Reasoning: a.reasoning.String(),
+	}
+
+	// Tool calls are rebuilt in index order, as the agent loop does
+	indices := slices.Sorted(maps.Keys(a.toolCalls))
+	for _, idx := range indices {
+		res.ToolCalls = append(res.ToolCalls, *a.toolCalls[idx])
+	}
+
+	if usage != nil {
+		res.Usage = &Usage{
+			PromptTokens:     usage.PromptTokens(),
+			CompletionTokens: usage.CompletionTokens(),
+			TotalTokens:      usage.TotalTokens(),
+		}
+	}
+
+	return res
+}
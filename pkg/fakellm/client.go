@@ -0,0 +1,263 @@
+// Package fakellm provides a deterministic llm.Client for the end-to-end tests
+// of the writing pipelines.
+//
+// The responses are derived from the requests: plans matching the response
+// schema of the planners, prose of the word count requested by the writers
+// and editors, and the JSON expected by the reviewers and the coherence
+// editor. The agent role found in the context and the prompts select the
+// response, so that the fake follows the pipelines without being scripted.
+package fakellm
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/cassette"
+	"github.com/pkg/errors"
+)
+
+// Call is a chat completion received by the Client.
+type Call struct {
+	Role   article.AgentRole
+	Schema string // name of the response schema, if any
+	Prompt string // first user message
+	Tools  int    // number of tool calls in the response
+}
+
+// Client is a deterministic llm.Client.
+type Client struct {
+	opts *Options
+
+	mu      sync.Mutex
+	calls   []Call
+	roles   map[article.AgentRole]int
+	reviews map[string]int
+}
+
+// NewClient returns a Client configured with funcs.
+func NewClient(funcs ...OptionFunc) *Client {
+	return &Client{
+		opts:    NewOptions(funcs...),
+		roles:   make(map[article.AgentRole]int),
+		reviews: make(map[string]int),
+	}
+}
+
+// Calls returns the chat completions received so far, in order.
+func (c *Client) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	calls := make([]Call, len(c.calls))
+	copy(calls, c.calls)
+
+	return calls
+}
+
+// CallsFor returns the number of chat completions received for role.
+func (c *Client) CallsFor(role article.AgentRole) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.roles[role]
+}
+
+// ChatCompletion implements llm.Client.
+func (c *Client) ChatCompletion(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (llm.ChatCompletionResponse, error) {
+	res, err := c.complete(ctx, llm.NewChatCompletionOptions(funcs...))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return res.ChatCompletionResponse(), nil
+}
+
+// ChatCompletionStream implements llm.Client.
+func (c *Client) ChatCompletionStream(ctx context.Context, funcs ...llm.ChatCompletionOptionFunc) (<-chan llm.StreamChunk, error) {
+	res, err := c.complete(ctx, llm.NewChatCompletionOptions(funcs...))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	chunks := res.StreamChunks()
+
+	stream := make(chan llm.StreamChunk, len(chunks))
+	for _, chunk := range chunks {
+		stream <- chunk
+	}
+	close(stream)
+
+	return stream, nil
+}
+
+// Embeddings implements llm.Client.
+func (c *Client) Embeddings(ctx context.Context, inputs []string, funcs ...llm.EmbeddingsOptionFunc) (llm.EmbeddingsResponse, error) {
+	return nil, errors.New("embeddings are not supported by the fake client")
+}
+
+func (c *Client) complete(ctx context.Context, opts *llm.ChatCompletionOptions) (cassette.Response, error) {
+	role := article.ContextAgentRole(ctx, "")
+	prompt := firstUserMessage(opts.Messages)
+
+	call := Call{Role: role, Prompt: prompt}
+	if opts.ResponseSchema != nil {
+		call.Schema = opts.ResponseSchema.Name()
+	}
+
+	c.mu.Lock()
+	served := c.roles[role]
+	c.roles[role]++
+	c.mu.Unlock()
+
+	res, err := c.respond(role, served, call.Schema, prompt, opts)
+
+	call.Tools = len(res.ToolCalls)
+	c.mu.Lock()
+	c.calls = append(c.calls, call)
+	c.mu.Unlock()
+
+	if err != nil {
+		return cassette.Response{}, errors.WithStack(err)
+	}
+
+	res.Usage = usageOf(opts.Messages, res)
+
+	return res, nil
+}
+
+func (c *Client) respond(role article.AgentRole, served int, schema string, prompt string, opts *llm.ChatCompletionOptions) (cassette.Response, error) {
+	if f, exists := c.opts.Failures[role]; exists && served >= f.After {
+		return cassette.Response{}, errors.WithStack(f.Err)
+	}
+
+	switch schema {
+	case "":
+	case "search_queries":
+		return jsonResponse(searchQueries(field(prompt, "**Subject:**")))
+	case "white_paper_plan":
+		return jsonResponse(newWhitePaperPlan(field(prompt, "**Subject:**"), wordCount(prompt, 0), c.opts.Chapters))
+	case "document_plan":
+		return jsonResponse(newDocumentPlan(field(prompt, "**Subject:**"), wordCount(prompt, 0), c.opts.Chapters))
+	default:
+		return cassette.Response{}, errors.Errorf("unexpected response schema %q", schema)
+	}
+
+	if res, ok := c.searchKnowledgeBase(prompt, opts); ok {
+		return res, nil
+	}
+
+	switch role {
+	case article.RoleCoherenceEditor:
+		return jsonResponse(newCoherenceResult(prompt))
+
+	case article.RoleCitationLinker:
+		return cassette.Response{Content: linkCitation(prompt)}, nil
+
+	case article.RoleDiagramInserter:
+		return cassette.Response{Content: insertDiagram(prompt)}, nil
+
+	case article.RoleEditor:
+		if strings.Contains(prompt, "Return ONLY a valid JSON object") {
+			return jsonResponse(c.review(prompt))
+		}
+		return cassette.Response{Content: editChapter(prompt)}, nil
+	}
+
+	return cassette.Response{Content: Prose(field(prompt, "- **Title:**"), wordCount(prompt, defaultWordCount))}, nil
+}
+
+// searchKnowledgeBase returns a call to the knowledge base search tool when it
+// is offered and has not been called yet, as the writers are asked to do.
+func (c *Client) searchKnowledgeBase(prompt string, opts *llm.ChatCompletionOptions) (cassette.Response, bool) {
+	if c.opts.NoToolCalls {
+		return cassette.Response{}, false
+	}
+
+	for _, m := range opts.Messages {
+		if _, ok := m.(llm.ToolCallsMessage); ok {
+			return cassette.Response{}, false
+		}
+	}
+
+	for _, t := range opts.Tools {
+		if t.Name() != "search_knowledge_base" {
+			continue
+		}
+
+		query := field(prompt, "- **Title:**")
+
+		// The white paper and article tools do not take the same parameters
+		var params map[string]any
+		if properties, _ := t.Parameters()["properties"].(map[string]any); properties["queries"] != nil {
+			params = map[string]any{"queries": []string{query}}
+		} else {
+			params = map[string]any{"query": query}
+		}
+
+		res, err := jsonResponse(params)
+		if err != nil {
+			return cassette.Response{}, false
+		}
+
+		return cassette.Response{
+			ToolCalls: []cassette.ToolCall{{ID: "call_search_knowledge_base", Name: t.Name(), Parameters: res.Content}},
+		}, true
+	}
+
+	return cassette.Response{}, false
+}
+
+// review approves the reviewed section once it has been rejected
+// Options.RejectedReviews times.
+func (c *Client) review(prompt string) article.SectionReview {
+	title := field(prompt, "- **Title:**")
+
+	c.mu.Lock()
+	rejected := c.reviews[title]
+	c.reviews[title]++
+	c.mu.Unlock()
+
+	if rejected < c.opts.RejectedReviews {
+		return article.SectionReview{Feedback: "Add more evidence from the research on " + title + "."}
+	}
+
+	return article.SectionReview{
+		Approved: true,
+		Content:  between(prompt, "**Section content to review:**\n\n", "\n\n---\n\n"),
+	}
+}
+
+func firstUserMessage(messages []llm.Message) string {
+	for _, m := range messages {
+		if m.Role() == llm.RoleUser {
+			return m.Content()
+		}
+	}
+	return ""
+}
+
+// usageOf estimates the usage of a response at four characters per token.
+func usageOf(messages []llm.Message, res cassette.Response) *cassette.Usage {
+	var prompt int
+	for _, m := range messages {
+		prompt += len(m.Content())
+	}
+
+	completion := len(res.Content)
+	for _, tc := range res.ToolCalls {
+		completion += len(tc.Parameters)
+	}
+
+	usage := &cassette.Usage{
+		PromptTokens:     int64(prompt / 4),
+		CompletionTokens: int64(completion / 4),
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	return usage
+}
+
+var _ llm.Client = &Client{}
@@ -0,0 +1,67 @@
+package fakellm
+
+import "github.com/bornholm/ghostwriter/pkg/article"
+
+// Failure makes the chat completions of a role fail.
+type Failure struct {
+	// After is the number of chat completions of the role served before
+	// failing.
+	After int
+	Err   error
+}
+
+type Options struct {
+	// Chapters is the number of chapters, or sections, of the generated plans.
+	Chapters int
+	// RejectedReviews is the number of times each article section is rejected
+	// by the reviewer before being approved.
+	RejectedReviews int
+	// NoToolCalls disables the knowledge base searches of the writers.
+	NoToolCalls bool
+	Failures    map[article.AgentRole]Failure
+}
+
+type OptionFunc func(opts *Options)
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Chapters: 3,
+		Failures: make(map[article.AgentRole]Failure),
+	}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithChapters(n int) OptionFunc {
+	return func(opts *Options) {
+		opts.Chapters = n
+	}
+}
+
+func WithRejectedReviews(n int) OptionFunc {
+	return func(opts *Options) {
+		opts.RejectedReviews = n
+	}
+}
+
+func WithNoToolCalls() OptionFunc {
+	return func(opts *Options) {
+		opts.NoToolCalls = true
+	}
+}
+
+// WithError makes every chat completion of role fail with err.
+func WithError(role article.AgentRole, err error) OptionFunc {
+	return WithErrorAfter(role, 0, err)
+}
+
+// WithErrorAfter makes the chat completions of role fail with err once n of
+// them have been served. The writers send two chat completions per chapter
+// unless WithNoToolCalls is used.
+func WithErrorAfter(role article.AgentRole, n int, err error) OptionFunc {
+	return func(opts *Options) {
+		opts.Failures[role] = Failure{After: n, Err: err}
+	}
+}
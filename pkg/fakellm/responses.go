@@ -0,0 +1,329 @@
+package fakellm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/cassette"
+	"github.com/pkg/errors"
+)
+
+// defaultWordCount is the length of the prose written when the prompt does
+// not request one.
+const defaultWordCount = 100
+
+var sentences = []string{
+	"The research on %s shows how organisations adapt their practices.",
+	"Practitioners report measurable benefits when %s is planned early.",
+	"Costs, risks and governance remain the main questions raised about %s.",
+	"Several sources compare the approaches to %s adopted across industries.",
+	"Each recommendation builds on the evidence gathered about %s.",
+}
+
+// Prose returns n words of deterministic prose about topic, in paragraphs of
+// a few sentences.
+func Prose(topic string, n int) string {
+	if topic == "" {
+		topic = "the subject"
+	}
+
+	var b strings.Builder
+	words := 0
+	for i := 0; words < n; i++ {
+		sentence := strings.Fields(fmt.Sprintf(sentences[i%len(sentences)], topic))
+		sentence = sentence[:min(len(sentence), n-words)]
+
+		if i > 0 {
+			if i%len(sentences) == 0 {
+				b.WriteString("\n\n")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+
+		b.WriteString(strings.Join(sentence, " "))
+		words += len(sentence)
+	}
+
+	text := b.String()
+	if text != "" && !strings.HasSuffix(text, ".") {
+		text += "."
+	}
+
+	return text
+}
+
+var chapterTitles = []string{
+	"Context and Stakes",
+	"Current Landscape",
+	"Challenges and Risks",
+	"Recommendations",
+	"Outlook",
+}
+
+func chapterTitle(i int) string {
+	if i < len(chapterTitles) {
+		return chapterTitles[i]
+	}
+	return fmt.Sprintf("Perspective %d", i+1)
+}
+
+func searchQueries(subject string) article.SearchQueriesResponse {
+	return article.SearchQueriesResponse{
+		Queries: []article.SearchQuery{
+			{Query: subject, Keywords: keywords(subject), Priority: 5, Rationale: "Foundational coverage of the subject"},
+			{Query: subject + " challenges", Keywords: keywords(subject), Priority: 3, Rationale: "Known limits of the subject", Language: "en", TimeRange: "year"},
+		},
+		Focus: subject,
+	}
+}
+
+// whitePaperPlan mirrors the JSON of whitepaper.WhitePaperPlan, which cannot
+// be imported by the tests of the whitepaper package.
+type whitePaperPlan struct {
+	Title                    string                  `json:"title"`
+	Subtitle                 string                  `json:"subtitle"`
+	TargetAudience           string                  `json:"target_audience"`
+	CentralArgument          string                  `json:"central_argument"`
+	Objectives               []string                `json:"objectives"`
+	ExecutiveSummaryGuidance []string                `json:"executive_summary_guidance"`
+	Chapters                 []whitePaperPlanChapter `json:"chapters"`
+	AppendixTitles           []string                `json:"appendix_titles"`
+	Keywords                 []string                `json:"keywords"`
+	TotalWords               int                     `json:"total_words"`
+}
+
+type whitePaperPlanChapter struct {
+	Number      int      `json:"number"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	KeyPoints   []string `json:"key_points"`
+	WordCount   int      `json:"word_count"`
+}
+
+func newWhitePaperPlan(subject string, totalWords int, chapters int) whitePaperPlan {
+	plan := whitePaperPlan{
+		Title:                    subject,
+		Subtitle:                 "A white paper",
+		TargetAudience:           "Decision makers",
+		CentralArgument:          subject + " deserves a deliberate strategy.",
+		Objectives:               []string{"Understand " + subject, "Assess the risks", "Plan the next steps"},
+		ExecutiveSummaryGuidance: []string{"State the stakes", "Summarise the recommendations"},
+		Chapters:                 make([]whitePaperPlanChapter, 0, chapters),
+		AppendixTitles:           []string{"Methodology"},
+		Keywords:                 keywords(subject),
+	}
+
+	for i := range chapters {
+		title := chapterTitle(i)
+		plan.Chapters = append(plan.Chapters, whitePaperPlanChapter{
+			Number:      i + 1,
+			Title:       title,
+			Description: title + " of " + subject,
+			KeyPoints:   []string{title + " of " + subject},
+			WordCount:   sectionWordCount(totalWords, chapters),
+		})
+		plan.TotalWords += sectionWordCount(totalWords, chapters)
+	}
+
+	return plan
+}
+
+func newDocumentPlan(subject string, totalWords int, sections int) article.DocumentPlan {
+	plan := article.DocumentPlan{
+		Title:    subject,
+		Sections: make([]*article.DocumentSection, 0, sections),
+		Keywords: keywords(subject),
+	}
+
+	for i := range sections {
+		title := chapterTitle(i)
+		plan.Sections = append(plan.Sections, &article.DocumentSection{
+			Title:       title,
+			Description: title + " of " + subject,
+			KeyPoints:   []string{title + " of " + subject},
+			WordCount:   sectionWordCount(totalWords, sections),
+		})
+		plan.TotalWords += sectionWordCount(totalWords, sections)
+	}
+
+	return plan
+}
+
+func sectionWordCount(totalWords int, sections int) int {
+	if totalWords <= 0 {
+		return defaultWordCount
+	}
+	return max(1, totalWords/max(1, sections))
+}
+
+// coherenceResult mirrors the JSON of whitepaper.CoherenceEditResult.
+type coherenceResult struct {
+	ExecutiveSummary string             `json:"executive_summary"`
+	Abstract         string             `json:"abstract"`
+	Bibliography     []coherenceBibItem `json:"bibliography"`
+	Appendices       []coherenceSection `json:"appendices"`
+}
+
+type coherenceBibItem struct {
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	SourceType string `json:"source_type"`
+}
+
+type coherenceSection struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+func newCoherenceResult(prompt string) coherenceResult {
+	title := field(prompt, "**Title:**")
+
+	result := coherenceResult{
+		ExecutiveSummary: Prose(title, 60),
+		Abstract:         Prose(title, 30),
+		Bibliography:     make([]coherenceBibItem, 0),
+		Appendices:       make([]coherenceSection, 0),
+	}
+
+	for _, source := range sources(prompt) {
+		result.Bibliography = append(result.Bibliography, coherenceBibItem{
+			URL:        source.url,
+			Title:      source.title,
+			SourceType: source.sourceType,
+		})
+	}
+
+	for _, appendix := range listItems(prompt, "**Planned Appendices:**") {
+		result.Appendices = append(result.Appendices, coherenceSection{
+			Title:   appendix,
+			Content: Prose(appendix, 40),
+		})
+	}
+
+	return result
+}
+
+// editChapter addresses the reviewer annotations of the white paper editor
+// prompt or completes the chapter up to its target word count.
+func editChapter(prompt string) string {
+	content := between(prompt, "**Chapter Content to Edit:**\n\n", "\n\nProvide ONLY")
+
+	if annotations := listItems(prompt, "**Reviewer Annotations to Address (PRIORITY):**"); len(annotations) > 0 {
+		return "This revision addresses the reviewer annotations: " + strings.Join(annotations, "; ") + "\n\n" + content
+	}
+
+	if gap := wordCount(prompt, 0) - len(strings.Fields(content)); gap > 0 {
+		content += "\n\n" + Prose(field(prompt, "- **Title:**"), gap)
+	}
+
+	return content
+}
+
+// linkCitation cites the first available source at the end of the chapter.
+func linkCitation(prompt string) string {
+	content := between(prompt, "**Chapter Content:**\n\n", "\n\nProvide ONLY")
+
+	if s := sources(prompt); len(s) > 0 {
+		content += fmt.Sprintf("\n\nSee [%s](%s).", s[0].title, s[0].url)
+	}
+
+	return content
+}
+
+func insertDiagram(prompt string) string {
+	content := between(prompt, "**Chapter Content:**\n\n", "\n\nProvide ONLY")
+
+	return content + "\n\n```mermaid\nflowchart LR\n    A[Research] --> B[Analysis] --> C[Recommendations]\n```"
+}
+
+type source struct {
+	title, url, sourceType string
+}
+
+var sourcePattern = regexp.MustCompile(`^\[(.*)\]\((\S+)\)(?: — ([^ ]+))?`)
+
+// sources parses the "- [Title](URL) — type" list of the available sources.
+func sources(prompt string) []source {
+	var parsed []source
+	for _, item := range listItems(prompt, "**Available Sources:**") {
+		if m := sourcePattern.FindStringSubmatch(item); m != nil {
+			parsed = append(parsed, source{title: m[1], url: m[2], sourceType: m[3]})
+		}
+	}
+	return parsed
+}
+
+// field returns the rest of the first line of prompt starting with prefix.
+func field(prompt string, prefix string) string {
+	for _, line := range strings.Split(prompt, "\n") {
+		if rest, ok := strings.CutPrefix(line, prefix); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return ""
+}
+
+// between returns the part of prompt between start and end.
+func between(prompt string, start string, end string) string {
+	_, rest, found := strings.Cut(prompt, start)
+	if !found {
+		return ""
+	}
+	content, _, _ := strings.Cut(rest, end)
+	return strings.TrimSpace(content)
+}
+
+var listItemPrefix = regexp.MustCompile(`^(?:- |\d+\. )`)
+
+// listItems returns the items of the list following heading in prompt.
+func listItems(prompt string, heading string) []string {
+	_, rest, found := strings.Cut(prompt, heading+"\n")
+	if !found {
+		return nil
+	}
+
+	var items []string
+	for _, line := range strings.Split(rest, "\n") {
+		if !listItemPrefix.MatchString(line) {
+			break
+		}
+		items = append(items, listItemPrefix.ReplaceAllString(line, ""))
+	}
+
+	return items
+}
+
+var wordCountPattern = regexp.MustCompile(`(?i)target word count:(?:\*\*)? (\d+) words`)
+
+// wordCount returns the target word count requested by prompt.
+func wordCount(prompt string, defaultCount int) int {
+	m := wordCountPattern.FindStringSubmatch(prompt)
+	if m == nil {
+		return defaultCount
+	}
+
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return defaultCount
+	}
+
+	return n
+}
+
+func keywords(subject string) []string {
+	return strings.Fields(strings.ToLower(subject))
+}
+
+func jsonResponse(v any) (cassette.Response, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return cassette.Response{}, errors.WithStack(err)
+	}
+
+	return cassette.Response{Content: string(data)}, nil
+}
@@ -0,0 +1,150 @@
+// Package fakeweb serves web pages from an httptest server, with the search
+// client and the scraper the research agents use to find and fetch them.
+package fakeweb
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/bornholm/ghostwriter/pkg/scraper"
+	"github.com/bornholm/ghostwriter/pkg/search"
+	"github.com/pkg/errors"
+)
+
+// Page is a web page served by a Web.
+type Page struct {
+	Path        string
+	Title       string
+	Description string // returned by the search client
+	Body        string // text of the page
+	// Status is the HTTP status of the page, 200 if unset.
+	Status int
+}
+
+// Web is a fake web: every search returns all its pages, in order.
+type Web struct {
+	server *httptest.Server
+	pages  []Page
+
+	mu        sync.Mutex
+	queries   []string
+	options   []search.Options
+	requested []string
+	searchErr error
+}
+
+// New starts a Web serving pages. It must be closed with Close.
+func New(pages ...Page) *Web {
+	w := &Web{pages: pages}
+	w.server = httptest.NewServer(http.HandlerFunc(w.serve))
+	return w
+}
+
+// Close shuts the server down.
+func (w *Web) Close() {
+	w.server.Close()
+}
+
+// URL returns the URL of the page at path.
+func (w *Web) URL(path string) string {
+	return w.server.URL + path
+}
+
+// SearchClient returns a search.Client searching the pages of w.
+func (w *Web) SearchClient() search.Client {
+	return &searchClient{web: w}
+}
+
+// Scraper returns a scraper fetching the pages of w.
+func (w *Web) Scraper() scraper.Scraper {
+	return scraper.NewHTTPScraper(w.server.Client())
+}
+
+// FailSearches makes the following searches fail with err, or succeed again
+// if err is nil.
+func (w *Web) FailSearches(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.searchErr = err
+}
+
+// Queries returns the search queries received so far.
+func (w *Web) Queries() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.queries...)
+}
+
+// SearchOptions returns the options of the search queries received so far.
+func (w *Web) SearchOptions() []search.Options {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]search.Options(nil), w.options...)
+}
+
+// Requested returns the paths of the pages requested so far.
+func (w *Web) Requested() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.requested...)
+}
+
+func (w *Web) serve(res http.ResponseWriter, req *http.Request) {
+	w.mu.Lock()
+	w.requested = append(w.requested, req.URL.Path)
+	w.mu.Unlock()
+
+	for _, p := range w.pages {
+		if p.Path != req.URL.Path {
+			continue
+		}
+
+		res.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if p.Status != 0 {
+			res.WriteHeader(p.Status)
+		}
+
+		fmt.Fprintf(res, "<html><head><title>%s</title></head><body><h1>%s</h1><p>%s</p></body></html>",
+			html.EscapeString(p.Title), html.EscapeString(p.Title), html.EscapeString(p.Body))
+
+		return
+	}
+
+	http.NotFound(res, req)
+}
+
+type searchClient struct {
+	web *Web
+}
+
+// Search implements search.Client.
+func (c *searchClient) Search(ctx context.Context, query string, funcs ...search.OptionFunc) ([]search.Result, error) {
+	opts := search.NewOptions(funcs...)
+
+	c.web.mu.Lock()
+	c.web.queries = append(c.web.queries, query)
+	c.web.options = append(c.web.options, *opts)
+	err := c.web.searchErr
+	c.web.mu.Unlock()
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	results := make([]search.Result, 0, len(c.web.pages))
+	for _, p := range c.web.pages {
+		results = append(results, search.Result{
+			Title:       p.Title,
+			URL:         c.web.URL(p.Path),
+			Description: p.Description,
+		})
+	}
+
+	return opts.Truncate(results), nil
+}
+
+var _ search.Client = &searchClient{}
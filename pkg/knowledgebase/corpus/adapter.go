@@ -2,8 +2,14 @@ package corpus
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +27,10 @@ const (
 	searchTimeout        = 5 * time.Minute
 )
 
+// ErrDocumentNotFound is returned when removing a document that is not in the
+// knowledge base.
+var ErrDocumentNotFound = errors.New("document not found")
+
 // Adapter implements article.KnowledgeBase using a Corpus instance.
 // Documents are indexed into Corpus for semantic search; a local cache
 // provides GetAllDocuments and enables doc reconstruction after Search.
@@ -28,16 +38,40 @@ type Adapter struct {
 	c            *corpus.Corpus
 	collectionID model.CollectionID
 	docs         map[string]article.ResearchDocument
-	mu           sync.RWMutex
+	cacheFile    string
+	// dirty reports whether docs changed since the cache file was written.
+	dirty bool
+	mu    sync.RWMutex
+	// flushMu serializes the writes of the cache file.
+	flushMu sync.Mutex
+}
+
+// OptionFunc configures an Adapter.
+type OptionFunc func(a *Adapter)
+
+// WithCacheFile persists the local document cache to the given JSON file so
+// that documents indexed by a previous run stay available after a restart.
+// The file is written by Flush and Close.
+func WithCacheFile(path string) OptionFunc {
+	return func(a *Adapter) { a.cacheFile = path }
 }
 
 // New returns an Adapter backed by the given Corpus and collection.
-func New(c *corpus.Corpus, collectionID model.CollectionID) *Adapter {
-	return &Adapter{
+func New(c *corpus.Corpus, collectionID model.CollectionID, funcs ...OptionFunc) *Adapter {
+	a := &Adapter{
 		c:            c,
 		collectionID: collectionID,
 		docs:         make(map[string]article.ResearchDocument),
 	}
+	for _, fn := range funcs {
+		fn(a)
+	}
+
+	if err := a.loadCache(); err != nil {
+		slog.Warn("could not load knowledge base cache", slog.String("path", a.cacheFile), slog.Any("error", err))
+	}
+
+	return a
 }
 
 // HasDocument reports whether a document with the given URL is already cached.
@@ -78,8 +112,39 @@ func (a *Adapter) AddDocument(doc article.ResearchDocument) error {
 	}
 
 	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	a.docs[sourceURL.String()] = doc
-	a.mu.Unlock()
+	a.dirty = true
+
+	return nil
+}
+
+// RemoveDocument deletes the document with the given URL from Corpus and from
+// the local cache.
+func (a *Adapter) RemoveDocument(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid document url %q", rawURL)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := u.String()
+	if _, exists := a.docs[key]; !exists {
+		return errors.Wrapf(ErrDocumentNotFound, "no document with url %q", rawURL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), indexingTimeout)
+	defer cancel()
+
+	if err := a.c.DeleteBySource(ctx, u); err != nil {
+		return errors.WithStack(err)
+	}
+
+	delete(a.docs, key)
+	a.dirty = true
 
 	return nil
 }
@@ -127,14 +192,14 @@ func (a *Adapter) Search(query string, limit int) ([]article.ResearchDocument, e
 	return docs, nil
 }
 
-// GetAllDocuments returns all cached documents.
+// GetAllDocuments returns all cached documents, ordered by URL.
 func (a *Adapter) GetAllDocuments() []article.ResearchDocument {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
 	docs := make([]article.ResearchDocument, 0, len(a.docs))
-	for _, doc := range a.docs {
-		docs = append(docs, doc)
+	for _, key := range slices.Sorted(maps.Keys(a.docs)) {
+		docs = append(docs, a.docs[key])
 	}
 	return docs
 }
@@ -155,11 +220,87 @@ func (a *Adapter) GetStats() map[string]interface{} {
 	}
 }
 
-// Close is a no-op; Corpus manages its own resources.
+// Flush writes the local document cache to the cache file if it changed.
+func (a *Adapter) Flush() error {
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+
+	a.mu.Lock()
+	if !a.dirty || a.cacheFile == "" {
+		a.mu.Unlock()
+		return nil
+	}
+	docs := make([]article.ResearchDocument, 0, len(a.docs))
+	for _, key := range slices.Sorted(maps.Keys(a.docs)) {
+		docs = append(docs, a.docs[key])
+	}
+	a.dirty = false
+	a.mu.Unlock()
+
+	// The file is written without holding the lock so that readers are not
+	// blocked by the I/O.
+	if err := a.saveCache(docs); err != nil {
+		a.mu.Lock()
+		a.dirty = true
+		a.mu.Unlock()
+		return errors.Wrap(err, "could not save knowledge base cache")
+	}
+
+	return nil
+}
+
+// Close flushes the local document cache; Corpus manages its own resources.
 func (a *Adapter) Close() error {
+	return errors.WithStack(a.Flush())
+}
+
+// loadCache restores the local document cache from the cache file, if any.
+func (a *Adapter) loadCache() error {
+	if a.cacheFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(a.cacheFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+
+	var docs []article.ResearchDocument
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return errors.WithStack(err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, doc := range docs {
+		a.docs[docSourceURL(doc).String()] = doc
+	}
+
 	return nil
 }
 
+// saveCache writes the given documents, ordered by URL, to the cache file.
+func (a *Adapter) saveCache(docs []article.ResearchDocument) error {
+	data, err := json.MarshalIndent(docs, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tmp := a.cacheFile + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(a.cacheFile), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmp, a.cacheFile))
+}
+
 // docSourceURL returns a URL for the document, generating a synthetic one when
 // the document has no URL (e.g. documents added from plain text).
 func docSourceURL(doc article.ResearchDocument) *url.URL {
@@ -0,0 +1,60 @@
+package corpus
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bornholm/ghostwriter/pkg/article"
+)
+
+func TestAdapterFlush(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "documents.json")
+
+	adapter := New(nil, "", WithCacheFile(cacheFile))
+
+	if err := adapter.Flush(); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+	if _, err := os.Stat(cacheFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no cache file before any change, got: %v", err)
+	}
+
+	// Documents are added directly, as indexing requires a Corpus instance
+	for _, doc := range []article.ResearchDocument{
+		{URL: "https://c.org", Title: "C"},
+		{URL: "https://a.org", Title: "A"},
+		{URL: "https://b.org", Title: "B"},
+	} {
+		adapter.docs[docSourceURL(doc).String()] = doc
+	}
+	adapter.dirty = true
+
+	if err := adapter.Close(); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	var docs []article.ResearchDocument
+	if err := json.Unmarshal(data, &docs); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	titles := ""
+	for _, doc := range docs {
+		titles += doc.Title
+	}
+	if titles != "ABC" {
+		t.Errorf("expected the documents to be ordered by url, got %q", titles)
+	}
+
+	reloaded := New(nil, "", WithCacheFile(cacheFile))
+	if len(reloaded.GetAllDocuments()) != 3 {
+		t.Errorf("expected 3 documents once reloaded, got %d", len(reloaded.GetAllDocuments()))
+	}
+}
@@ -0,0 +1,253 @@
+package locale
+
+// Key identifies a message of the catalogs. Messages may be fmt format
+// strings; their arguments are listed next to the key.
+type Key string
+
+// Headings of the generated documents.
+const (
+	TableOfContents      Key = "document.table_of_contents"
+	Abstract             Key = "document.abstract"
+	ExecutiveSummary     Key = "document.executive_summary"
+	Bibliography         Key = "document.bibliography"
+	NoSources            Key = "document.no_sources"
+	IncompleteWhitePaper Key = "document.incomplete_white_paper" // written chapters, planned chapters
+)
+
+// Names of the white paper pipeline phases.
+const (
+	PhaseResearch   Key = "phase.research"
+	PhasePlanning   Key = "phase.planning"
+	PhaseWriting    Key = "phase.writing"
+	PhaseCoherence  Key = "phase.coherence"
+	PhaseEnrichment Key = "phase.enrichment"
+	PhaseFixes      Key = "phase.fixes"
+	PhaseRewrite    Key = "phase.rewrite"
+)
+
+// Details of the white paper pipeline progress.
+const (
+	PlanSummary         Key = "progress.plan_summary"       // title, chapters, words
+	PlanProvided        Key = "progress.plan_provided"      // title, chapters, words
+	PlanResumed         Key = "progress.plan_resumed"       // title, chapters, words
+	ResearchSkipped     Key = "progress.research_skipped"   // documents
+	ResearchResumed     Key = "progress.research_resumed"   // documents
+	ResearchDone        Key = "progress.research_done"      // documents
+	CoherenceSummary    Key = "progress.coherence_summary"  // sources, appendices
+	CoherenceResumed    Key = "progress.coherence_resumed"  // sources, appendices
+	CoherenceChapters   Key = "progress.coherence_chapters" // chapters
+	CoherenceChapter    Key = "progress.coherence_chapter"  // number, title
+	CoherenceGenerating Key = "progress.coherence_generating"
+	EnrichmentDone      Key = "progress.enrichment_done" // chapters
+	FixesNoAnnotation   Key = "progress.fixes_no_annotation"
+	FixesDone           Key = "progress.fixes_done"    // files
+	FixesSkipped        Key = "progress.fixes_skipped" // files
+	RewriteDone         Key = "progress.rewrite_done"  // number, words
+)
+
+// Messages of the terminal output.
+const (
+	ChapterStart          Key = "ui.chapter_start"  // number, total, title
+	ChapterTarget         Key = "ui.chapter_target" // words
+	ChapterDone           Key = "ui.chapter_done"   // number, total, words
+	Completed             Key = "ui.completed"
+	Tasks                 Key = "ui.tasks"
+	Reasoning             Key = "ui.reasoning"
+	Error                 Key = "ui.error"
+	FixHeader             Key = "ui.fix_header"             // directory
+	WhitePaperHeader      Key = "ui.whitepaper_header"      // subject
+	WhitePaperDone        Key = "ui.whitepaper_done"        // directory
+	WhitePaperInterrupted Key = "ui.whitepaper_interrupted" // directory
+	WhitePaperOverBudget  Key = "ui.whitepaper_over_budget" // directory
+	FixOverBudget         Key = "ui.fix_over_budget"
+	PlanHeader            Key = "ui.plan_header"             // subject
+	PlanDone              Key = "ui.plan_done"               // directory
+	PlanFile              Key = "ui.plan_file"               // path
+	PlanPreview           Key = "ui.plan_preview"            // path
+	PlanNextStep          Key = "ui.plan_next_step"          // command
+	ResearchHeader        Key = "ui.research_header"         // subject
+	ResearchCompleted     Key = "ui.research_completed"      // queries, documents
+	ResearchFailures      Key = "ui.research_failures"       // searches, scrapes
+	ResearchReport        Key = "ui.research_report"         // path
+	ResearchKnowledgeBase Key = "ui.research_knowledge_base" // path
+	ArticleHeader         Key = "ui.article_header"          // subject
+	ArticleDone           Key = "ui.article_done"            // path, words, sources
+	RewriteHeader         Key = "ui.rewrite_header"          // number, directory
+	RewriteCompleted      Key = "ui.rewrite_completed"       // number, words, path
+	BatchHeader           Key = "ui.batch_header"            // jobs, concurrency
+	BatchColumns          Key = "ui.batch_columns"
+	BatchSummary          Key = "ui.batch_summary"      // succeeded, failed
+	ServeStarted          Key = "ui.serve_started"      // address
+	RenderDone            Key = "ui.render_done"        // format, path
+	KBAlreadyPresent      Key = "ui.kb_already_present" // url
+	KBAdded               Key = "ui.kb_added"           // documents
+	KBRemoved             Key = "ui.kb_removed"         // url
+	KBStats               Key = "ui.kb_stats"           // documents, words
+	KBEmpty               Key = "ui.kb_empty"
+	KBColumns             Key = "ui.kb_columns"
+	UsageSummary          Key = "ui.usage_summary"  // calls, tokens, prompt tokens, completion tokens
+	UsageRole             Key = "ui.usage_role"     // role, tokens
+	UsageCost             Key = "ui.usage_cost"     // cost
+	UsageUnpriced         Key = "ui.usage_unpriced" // models
+	UsageFile             Key = "ui.usage_file"     // path
+	UsageFallback         Key = "ui.usage_fallback" // from model, to model, reason
+)
+
+var catalogs = map[Locale]map[Key]string{
+	English: {
+		TableOfContents:  "Table of contents",
+		Abstract:         "Abstract",
+		ExecutiveSummary: "Executive Summary",
+		Bibliography:     "Bibliography",
+		NoSources:        "No sources available.",
+
+		IncompleteWhitePaper: "⚠️ **Incomplete white paper**: its generation was stopped when its budget ran out, with %d of %d chapters written.",
+
+		PhaseResearch:   "Research",
+		PhasePlanning:   "Planning",
+		PhaseWriting:    "Writing",
+		PhaseCoherence:  "Coherence",
+		PhaseEnrichment: "Enrichment",
+		PhaseFixes:      "Fixes",
+		PhaseRewrite:    "Rewrite",
+
+		PlanSummary:           "%q — %d chapters, %d words",
+		PlanProvided:          "%q — %d chapters, %d words (provided plan)",
+		PlanResumed:           "%q — %d chapters, %d words (resumed)",
+		ResearchSkipped:       "skipped, %d documents already indexed",
+		ResearchResumed:       "resumed, %d documents indexed",
+		ResearchDone:          "%d documents indexed",
+		CoherenceSummary:      "%d sources, %d appendices",
+		CoherenceResumed:      "%d sources, %d appendices (resumed)",
+		CoherenceChapters:     "%d chapters to analyze:",
+		CoherenceChapter:      "— Chapter %d: %s",
+		CoherenceGenerating:   "Generating the abstract, the executive summary and the bibliography…",
+		EnrichmentDone:        "%d chapters enriched",
+		FixesNoAnnotation:     "no annotation found",
+		FixesDone:             "%d file(s) fixed",
+		FixesSkipped:          "%d skipped",
+		RewriteDone:           "chapter %d, %d words",
+		ChapterStart:          "Chapter %d/%d: %s",
+		ChapterTarget:         "target: %d words",
+		ChapterDone:           "Chapter %d/%d — %d words",
+		Completed:             "Done",
+		Tasks:                 "Tasks",
+		Reasoning:             "Reasoning",
+		Error:                 "Error",
+		FixHeader:             "Fixes: %q",
+		WhitePaperHeader:      "White paper: %q",
+		WhitePaperDone:        "White paper generated in %s/",
+		WhitePaperInterrupted: "Generation interrupted, run again with --resume to resume from %s/",
+		WhitePaperOverBudget:  "Budget reached: incomplete white paper in %s/, run again with --resume and a higher budget to finish it",
+		FixOverBudget:         "Budget reached: the remaining annotations are kept, run fix again with a higher budget to apply them",
+		PlanHeader:            "Plan: %q",
+		PlanDone:              "Plan generated in %s/",
+		PlanFile:              "Plan:     %s",
+		PlanPreview:           "Preview:  %s",
+		PlanNextStep:          "After review: %s",
+		ResearchHeader:        "Research: %q",
+		ResearchCompleted:     "Research done: %d query(ies), %d document(s) indexed",
+		ResearchFailures:      "%d failed search(es) and %d failed extraction(s)",
+		ResearchReport:        "Report: %s",
+		ResearchKnowledgeBase: "Knowledge base: %s",
+		ArticleHeader:         "Article: %q",
+		ArticleDone:           "Article generated: %s (%d words, %d source(s))",
+		RewriteHeader:         "Rewriting chapter %d: %q",
+		RewriteCompleted:      "Chapter %d rewritten (%d words): %s",
+		BatchHeader:           "Batch: %d job(s), %d in parallel",
+		BatchColumns:          "#\tSTATUS\tTYPE\tSUBJECT\tDURATION\tRESULT",
+		BatchSummary:          "%d succeeded, %d failed",
+		ServeStarted:          "Server listening on %s",
+		RenderDone:            "%s rendered: %s",
+		KBAlreadyPresent:      "already present: %s",
+		KBAdded:               "%d document(s) added",
+		KBRemoved:             "Removed: %s",
+		KBStats:               "%d document(s), %d word(s)",
+		KBEmpty:               "No documents",
+		KBColumns:             "TITLE\tTYPE\tRELEVANCE\tURL",
+		UsageSummary:          "Usage: %d call(s), %d tokens (%d prompt, %d completion)",
+		UsageRole:             "%s: %d tokens",
+		UsageCost:             "estimated cost: %.4f",
+		UsageUnpriced:         "no price for: %s",
+		UsageFile:             "Details: %s",
+		UsageFallback:         "%s unavailable, switching to %s (%s)",
+	},
+	French: {
+		TableOfContents:  "Table des matières",
+		Abstract:         "Résumé",
+		ExecutiveSummary: "Synthèse",
+		Bibliography:     "Bibliographie",
+		NoSources:        "Aucune source disponible.",
+
+		IncompleteWhitePaper: "⚠️ **Livre blanc incomplet** : sa génération a été arrêtée à l'épuisement de son budget, avec %d chapitres rédigés sur %d.",
+
+		PhaseResearch:   "Recherche",
+		PhasePlanning:   "Planification",
+		PhaseWriting:    "Rédaction",
+		PhaseCoherence:  "Cohérence",
+		PhaseEnrichment: "Enrichissement",
+		PhaseFixes:      "Corrections",
+		PhaseRewrite:    "Réécriture",
+
+		PlanSummary:           "%q — %d chapitres, %d mots",
+		PlanProvided:          "%q — %d chapitres, %d mots (plan fourni)",
+		PlanResumed:           "%q — %d chapitres, %d mots (reprise)",
+		ResearchSkipped:       "ignorée, %d documents déjà indexés",
+		ResearchResumed:       "reprise, %d documents indexés",
+		ResearchDone:          "%d documents indexés",
+		CoherenceSummary:      "%d sources, %d annexes",
+		CoherenceResumed:      "%d sources, %d annexes (reprise)",
+		CoherenceChapters:     "%d chapitres à analyser :",
+		CoherenceChapter:      "— Chapitre %d : %s",
+		CoherenceGenerating:   "Génération de l'abstract, du résumé exécutif et de la bibliographie…",
+		EnrichmentDone:        "%d chapitres enrichis",
+		FixesNoAnnotation:     "aucune annotation trouvée",
+		FixesDone:             "%d fichier(s) corrigé(s)",
+		FixesSkipped:          "%d ignoré(s)",
+		RewriteDone:           "chapitre %d, %d mots",
+		ChapterStart:          "Chapitre %d/%d : %s",
+		ChapterTarget:         "cible : %d mots",
+		ChapterDone:           "Chapitre %d/%d — %d mots",
+		Completed:             "Terminé",
+		Tasks:                 "Tâches",
+		Reasoning:             "Raisonnement",
+		Error:                 "Erreur",
+		FixHeader:             "Corrections : %q",
+		WhitePaperHeader:      "Livre blanc : %q",
+		WhitePaperDone:        "Livre blanc généré dans %s/",
+		WhitePaperInterrupted: "Génération interrompue, relancez avec --resume pour reprendre depuis %s/",
+		WhitePaperOverBudget:  "Budget atteint : livre blanc incomplet dans %s/, relancez avec --resume et un budget plus élevé pour le terminer",
+		FixOverBudget:         "Budget atteint : les annotations restantes sont conservées, relancez fix avec un budget plus élevé pour les appliquer",
+		PlanHeader:            "Plan : %q",
+		PlanDone:              "Plan généré dans %s/",
+		PlanFile:              "Plan     : %s",
+		PlanPreview:           "Lecture  : %s",
+		PlanNextStep:          "Après relecture : %s",
+		ResearchHeader:        "Recherche : %q",
+		ResearchCompleted:     "Recherche terminée : %d requête(s), %d document(s) indexé(s)",
+		ResearchFailures:      "%d recherche(s) et %d extraction(s) en échec",
+		ResearchReport:        "Rapport : %s",
+		ResearchKnowledgeBase: "Base de connaissances : %s",
+		ArticleHeader:         "Article : %q",
+		ArticleDone:           "Article généré : %s (%d mots, %d source(s))",
+		RewriteHeader:         "Réécriture du chapitre %d : %q",
+		RewriteCompleted:      "Chapitre %d réécrit (%d mots) : %s",
+		BatchHeader:           "Lot : %d tâche(s), %d en parallèle",
+		BatchColumns:          "#\tSTATUT\tTYPE\tSUJET\tDURÉE\tRÉSULTAT",
+		BatchSummary:          "%d réussie(s), %d échouée(s)",
+		ServeStarted:          "Serveur démarré sur %s",
+		RenderDone:            "%s généré : %s",
+		KBAlreadyPresent:      "déjà présent : %s",
+		KBAdded:               "%d document(s) ajouté(s)",
+		KBRemoved:             "Supprimé : %s",
+		KBStats:               "%d document(s), %d mot(s)",
+		KBEmpty:               "Aucun document",
+		KBColumns:             "TITRE\tTYPE\tPERTINENCE\tURL",
+		UsageSummary:          "Consommation : %d appel(s), %d jetons (%d en entrée, %d en sortie)",
+		UsageRole:             "%s : %d jetons",
+		UsageCost:             "coût estimé : %.4f",
+		UsageUnpriced:         "sans tarif : %s",
+		UsageFile:             "Détail : %s",
+		UsageFallback:         "%s indisponible, bascule vers %s (%s)",
+	},
+}
@@ -0,0 +1,69 @@
+// Package locale provides the message catalogs of the texts shown to users:
+// the progress messages of the terminal and the headings of the generated
+// documents.
+package locale
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Locale is a language supported by the catalogs.
+type Locale string
+
+const (
+	English Locale = "en"
+	French  Locale = "fr"
+)
+
+// Default is the locale used when none is given.
+const Default = French
+
+var ErrUnsupportedLocale = errors.New("unsupported locale")
+
+// Supported returns the locales having a catalog.
+func Supported() []Locale {
+	return []Locale{English, French}
+}
+
+// Parse returns the locale matching s. Region and encoding suffixes are
+// ignored, so that "fr_FR.UTF-8" or "en-US" are accepted. An empty string
+// returns Default.
+func Parse(s string) (Locale, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Default, nil
+	}
+
+	if i := strings.IndexAny(s, "_-."); i > 0 {
+		s = s[:i]
+	}
+
+	locale := Locale(s)
+	if _, exists := catalogs[locale]; !exists {
+		return "", errors.Wrapf(ErrUnsupportedLocale, "%q (expected %q or %q)", s, English, French)
+	}
+
+	return locale, nil
+}
+
+// T returns the message for key, formatted with args. The English message is
+// used when the locale has no translation, and the key itself as a last
+// resort.
+func (l Locale) T(key Key, args ...any) string {
+	message, exists := catalogs[l][key]
+	if !exists {
+		message, exists = catalogs[English][key]
+	}
+	if !exists {
+		message = string(key)
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+
+	return fmt.Sprintf(message, args...)
+}
@@ -0,0 +1,61 @@
+package locale
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]Locale{
+		"":            Default,
+		"en":          English,
+		"EN":          English,
+		"en-US":       English,
+		"fr_FR.UTF-8": French,
+		" fr ":        French,
+	}
+
+	for value, expected := range cases {
+		locale, err := Parse(value)
+		if err != nil {
+			t.Errorf("Parse(%q): expected no error, got: %v", value, err)
+			continue
+		}
+		if locale != expected {
+			t.Errorf("Parse(%q): expected %q, got %q", value, expected, locale)
+		}
+	}
+
+	if _, err := Parse("de"); !errors.Is(err, ErrUnsupportedLocale) {
+		t.Errorf("expected ErrUnsupportedLocale, got: %v", err)
+	}
+}
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for _, locale := range Supported() {
+		for key, message := range catalogs[English] {
+			translated, exists := catalogs[locale][key]
+			if !exists {
+				t.Errorf("%s: missing message %q", locale, key)
+				continue
+			}
+			if strings.Count(translated, "%") != strings.Count(message, "%") {
+				t.Errorf("%s: message %q does not have the same arguments as the English one", locale, key)
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := French.T(ChapterDone, 2, 8, 1500); got != "Chapitre 2/8 — 1500 mots" {
+		t.Errorf("unexpected message: %q", got)
+	}
+	if got := English.T(Bibliography); got != "Bibliography" {
+		t.Errorf("unexpected message: %q", got)
+	}
+	if got := Locale("de").T(Bibliography); got != "Bibliography" {
+		t.Errorf("expected English fallback, got %q", got)
+	}
+}
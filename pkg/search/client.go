@@ -1,13 +1,24 @@
 package search
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Client interface {
-	Search(ctx context.Context, search string) ([]Result, error)
+	Search(ctx context.Context, search string, funcs ...OptionFunc) ([]Result, error)
 }
 
 type Result struct {
 	Title       string
 	URL         string
 	Description string
+	// Engine, Score and PublishedAt are only known to some search engines,
+	// e.g. the SearXNG JSON API, and left empty otherwise.
+	Engine      string
+	Score       float64
+	PublishedAt time.Time
+	// Engines are the engines that returned the result, set by the meta
+	// client.
+	Engines []string
 }
@@ -17,7 +17,20 @@ type Client struct {
 	scraper scraper.Scraper
 }
 
-func (c *Client) Search(ctx context.Context, search string) ([]search.Result, error) {
+// timeRanges are the values of the df parameter of the time ranges.
+var timeRanges = map[searchEngine.TimeRange]string{
+	searchEngine.TimeRangeDay:   "d",
+	searchEngine.TimeRangeWeek:  "w",
+	searchEngine.TimeRangeMonth: "m",
+	searchEngine.TimeRangeYear:  "y",
+}
+
+// Search implements search.Client. Sites and file types are given as query
+// operators; the language and the region select the closest DuckDuckGo
+// region.
+func (c *Client) Search(ctx context.Context, search string, funcs ...searchEngine.OptionFunc) ([]searchEngine.Result, error) {
+	opts := searchEngine.NewOptions(funcs...)
+
 	url := &url.URL{
 		Scheme: "https",
 		Host:   "duckduckgo.com",
@@ -25,7 +38,13 @@ func (c *Client) Search(ctx context.Context, search string) ([]search.Result, er
 	}
 
 	query := url.Query()
-	query.Set("q", search)
+	query.Set("q", opts.QueryWithOperators(search))
+	if region := region(opts); region != "" {
+		query.Set("kl", region)
+	}
+	if df, exists := timeRanges[opts.TimeRange]; exists {
+		query.Set("df", df)
+	}
 	url.RawQuery = query.Encode()
 
 	slog.DebugContext(ctx, "scraping duckduckgo results", slog.String("url", url.String()))
@@ -82,7 +101,23 @@ func (c *Client) Search(ctx context.Context, search string) ([]search.Result, er
 		})
 	})
 
-	return results, nil
+	return opts.Truncate(results), nil
+}
+
+// region returns the kl parameter of the options, e.g. "fr-fr" or "us-en".
+func region(opts *searchEngine.Options) string {
+	switch {
+	case opts.Region != "" && opts.Language != "":
+		return opts.Region + "-" + opts.Language
+	case opts.Region != "":
+		return opts.Region + "-" + opts.Region
+	case opts.Language == "en":
+		return "us-en"
+	case opts.Language != "":
+		return opts.Language + "-" + opts.Language
+	default:
+		return ""
+	}
 }
 
 func NewClient(scraper scraper.Scraper) *Client {
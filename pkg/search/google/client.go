@@ -17,8 +17,22 @@ type Client struct {
 	cx     string
 }
 
+// dateRestricts are the values of the dateRestrict parameter of the time
+// ranges.
+var dateRestricts = map[search.TimeRange]string{
+	search.TimeRangeDay:   "d1",
+	search.TimeRangeWeek:  "w1",
+	search.TimeRangeMonth: "m1",
+	search.TimeRangeYear:  "y1",
+}
+
+// maxResults is the maximum number of results of a Custom Search API call.
+const maxResults = 10
+
 // Search implements the search.Engine interface.
-func (c *Client) Search(ctx context.Context, query string) ([]search.Result, error) {
+func (c *Client) Search(ctx context.Context, query string, funcs ...search.OptionFunc) ([]search.Result, error) {
+	opts := search.NewOptions(funcs...)
+
 	service, err := customsearch.NewService(ctx, option.WithAPIKey(c.apiKey))
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -26,11 +40,39 @@ func (c *Client) Search(ctx context.Context, query string) ([]search.Result, err
 
 	slog.DebugContext(ctx, "executing search", slog.String("query", query))
 
+	num := maxResults
+	if opts.MaxResults > 0 && opts.MaxResults < num {
+		num = opts.MaxResults
+	}
+
 	// Create a search call with the query and search engine ID
 	search := service.Cse.List()
-	search.Q(query)
 	search.Cx(c.cx)
-	search.Num(10) // Retrieve 10 results by default
+	search.Num(int64(num))
+
+	// A single site is restricted natively, several through operators
+	if len(opts.Sites) == 1 {
+		search.SiteSearch(opts.Sites[0])
+		search.SiteSearchFilter("i")
+		search.Q(query)
+	} else {
+		sites := *opts
+		sites.FileType = ""
+		search.Q(sites.QueryWithOperators(query))
+	}
+
+	if opts.FileType != "" {
+		search.FileType(opts.FileType)
+	}
+	if opts.Language != "" {
+		search.Lr("lang_" + opts.Language)
+	}
+	if opts.Region != "" {
+		search.Gl(opts.Region)
+	}
+	if restrict, exists := dateRestricts[opts.TimeRange]; exists {
+		search.DateRestrict(restrict)
+	}
 
 	// Execute the search
 	searchResult, err := search.Do()
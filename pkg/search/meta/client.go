@@ -2,76 +2,327 @@ package meta
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	se "github.com/bornholm/ghostwriter/pkg/search"
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 )
 
-type Client struct {
-	clients []se.Client
+// Fusion is the method used to merge the rankings of the engines.
+type Fusion string
+
+const (
+	// FusionRRF merges the rankings with reciprocal rank fusion: a result
+	// scores the sum of weight / (k + rank) over the engines returning it.
+	FusionRRF Fusion = "rrf"
+	// FusionScore merges the scores given by the engines, normalized by the
+	// best score of each engine and weighted. Engines giving no score are
+	// scored by rank.
+	FusionScore Fusion = "score"
+)
+
+const (
+	DefaultTimeout      = time.Minute
+	DefaultRankConstant = 60
+)
+
+// Engine is a search engine queried by the meta client.
+type Engine struct {
+	// Name identifies the engine in the Engines of the merged results.
+	Name   string
+	Client se.Client
+	// Weight is the weight of the engine in the fusion, 1 when zero.
+	Weight float64
+}
+
+type Options struct {
+	// Timeout bounds the search of each engine.
+	Timeout time.Duration
+	Fusion  Fusion
+	// RankConstant is the k constant of reciprocal rank fusion, damping the
+	// advantage of the first ranks.
+	RankConstant float64
+}
+
+type OptionFunc func(opts *Options)
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Timeout:      DefaultTimeout,
+		Fusion:       FusionRRF,
+		RankConstant: DefaultRankConstant,
+	}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithTimeout(timeout time.Duration) OptionFunc {
+	return func(opts *Options) {
+		opts.Timeout = timeout
+	}
 }
 
-// Search implements search.Engine.
-func (s *Client) Search(ctx context.Context, search string) ([]se.Result, error) {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+func WithFusion(fusion Fusion) OptionFunc {
+	return func(opts *Options) {
+		opts.Fusion = fusion
+	}
+}
 
-	results := make(chan se.Result)
+func WithRankConstant(k float64) OptionFunc {
+	return func(opts *Options) {
+		opts.RankConstant = k
+	}
+}
 
-	var errLock sync.Mutex
-	var aggregatedErr error
+// Client queries several search engines concurrently and merges their
+// results into a single ranking. The merge only depends on the results of
+// the engines, not on the order they answer in.
+type Client struct {
+	engines []Engine
+	opts    *Options
+}
 
-	defer close(results)
+// engineResults are the results of the engine of the given index.
+type engineResults struct {
+	index   int
+	results []se.Result
+}
 
-	var wg sync.WaitGroup
+// Search implements search.Client. The options are passed on to every engine.
+// Engines failing or timing out are ignored; an error is only returned when
+// all of them fail.
+func (c *Client) Search(ctx context.Context, search string, funcs ...se.OptionFunc) ([]se.Result, error) {
+	responses := make([]engineResults, 0, len(c.engines))
 
-	wg.Add(len(s.clients))
+	var (
+		mu            sync.Mutex
+		aggregatedErr error
+		wg            sync.WaitGroup
+	)
 
-	for _, e := range s.clients {
-		go func(engine se.Client) {
+	for i, engine := range c.engines {
+		wg.Add(1)
+		go func(index int, engine Engine) {
 			defer wg.Done()
 
-			engineResults, err := engine.Search(ctx, search)
+			engineCtx := ctx
+			if c.opts.Timeout > 0 {
+				var cancel context.CancelFunc
+				engineCtx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+				defer cancel()
+			}
+
+			results, err := engine.Client.Search(engineCtx, search, funcs...)
+
+			mu.Lock()
+			defer mu.Unlock()
+
 			if err != nil {
-				errLock.Lock()
-				aggregatedErr = multierror.Append(aggregatedErr, errors.WithStack(err))
-				errLock.Unlock()
+				slog.WarnContext(ctx, "search engine failed", slog.String("engine", engine.Name), slog.Any("error", err))
+				aggregatedErr = multierror.Append(aggregatedErr, errors.Wrapf(err, "search engine %q", engine.Name))
 				return
 			}
 
-			for _, r := range engineResults {
-				results <- r
-			}
-		}(e)
+			responses = append(responses, engineResults{index: index, results: results})
+		}(i, engine)
+	}
+
+	wg.Wait()
+
+	if len(responses) == 0 && aggregatedErr != nil {
+		return nil, errors.WithStack(aggregatedErr)
 	}
 
-	mergedResults := make([]se.Result, 0)
-	go func() {
-		resultSet := make(map[string]struct{})
-		for r := range results {
-			if _, exists := resultSet[r.URL]; exists {
+	return se.NewOptions(funcs...).Truncate(c.merge(responses)), nil
+}
+
+// fused is a result being merged.
+type fused struct {
+	result se.Result
+	score  float64
+	// bestRank is the best rank of the result among the engines, and
+	// firstEngine the index of the first engine returning it, used to break
+	// ties.
+	bestRank    int
+	firstEngine int
+	key         string
+	engines     map[int]struct{}
+}
+
+func (c *Client) merge(responses []engineResults) []se.Result {
+	// Process the engines in their configured order so that the fields kept
+	// for a result do not depend on the order the engines answered in.
+	sort.Slice(responses, func(i, j int) bool { return responses[i].index < responses[j].index })
+
+	merged := make(map[string]*fused)
+
+	for _, res := range responses {
+		engine := c.engines[res.index]
+
+		weight := engine.Weight
+		if weight == 0 {
+			weight = 1
+		}
+
+		maxScore := 0.0
+		for _, r := range res.results {
+			maxScore = max(maxScore, r.Score)
+		}
+
+		rank := 0
+		for _, r := range res.results {
+			key := normalizeURL(r.URL)
+			if key == "" {
 				continue
 			}
 
-			mergedResults = append(mergedResults, r)
-			resultSet[r.URL] = struct{}{}
+			f, exists := merged[key]
+			if exists {
+				if _, seen := f.engines[res.index]; seen {
+					// Duplicate within the results of the same engine
+					continue
+				}
+			}
+
+			rank++
+
+			if !exists {
+				f = &fused{
+					result:      r,
+					bestRank:    rank,
+					firstEngine: res.index,
+					key:         key,
+					engines:     make(map[int]struct{}),
+				}
+				f.result.Engines = nil
+				merged[key] = f
+			} else {
+				if f.result.Description == "" {
+					f.result.Description = r.Description
+				}
+				if f.result.PublishedAt.IsZero() {
+					f.result.PublishedAt = r.PublishedAt
+				}
+				f.bestRank = min(f.bestRank, rank)
+			}
+
+			f.engines[res.index] = struct{}{}
+			f.score += weight * c.score(rank, len(res.results), r.Score, maxScore)
 		}
-	}()
+	}
 
-	wg.Wait()
+	all := make([]*fused, 0, len(merged))
+	for _, f := range merged {
+		all = append(all, f)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.bestRank != b.bestRank {
+			return a.bestRank < b.bestRank
+		}
+		if a.firstEngine != b.firstEngine {
+			return a.firstEngine < b.firstEngine
+		}
+		return a.key < b.key
+	})
+
+	results := make([]se.Result, 0, len(all))
+	for _, f := range all {
+		r := f.result
+		r.Score = f.score
+		for i, engine := range c.engines {
+			if _, returned := f.engines[i]; returned {
+				r.Engines = append(r.Engines, engine.Name)
+			}
+		}
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// score returns the contribution of a result of the given rank among total
+// results to its fused score, before weighting.
+func (c *Client) score(rank int, total int, engineScore float64, maxScore float64) float64 {
+	if c.opts.Fusion == FusionScore {
+		if maxScore > 0 {
+			return engineScore / maxScore
+		}
+		return float64(total-rank+1) / float64(total)
+	}
+
+	return 1 / (c.opts.RankConstant + float64(rank))
+}
+
+// normalizeURL returns the key results are de-duplicated by: the URL without
+// its fragment, tracking parameters, default port, "www." prefix and trailing
+// slash, with a lower-case host and sorted query parameters.
+func normalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
 
-	if aggregatedErr != nil {
-		return mergedResults, aggregatedErr
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "http" {
+		// Engines disagree on the scheme of sites served on both
+		scheme = "https"
 	}
 
-	return mergedResults, nil
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := u.Query()
+	for name := range query {
+		if strings.HasPrefix(strings.ToLower(name), "utm_") {
+			query.Del(name)
+		}
+	}
+
+	key := fmt.Sprintf("%s://%s%s", scheme, host, strings.TrimSuffix(u.EscapedPath(), "/"))
+	if encoded := query.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+
+	return key
 }
 
+// NewClient returns a meta client querying clients with the same weight,
+// named after their position.
 func NewClient(clients ...se.Client) *Client {
+	engines := make([]Engine, 0, len(clients))
+	for i, client := range clients {
+		engines = append(engines, Engine{Name: fmt.Sprintf("engine-%d", i+1), Client: client})
+	}
+	return NewClientWithEngines(engines)
+}
+
+// NewClientWithEngines returns a meta client querying named and weighted
+// engines.
+func NewClientWithEngines(engines []Engine, funcs ...OptionFunc) *Client {
 	return &Client{
-		clients: clients,
+		engines: engines,
+		opts:    NewOptions(funcs...),
 	}
 }
 
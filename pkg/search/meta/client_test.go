@@ -0,0 +1,135 @@
+package meta
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	se "github.com/bornholm/ghostwriter/pkg/search"
+	"github.com/pkg/errors"
+)
+
+type stubClient struct {
+	results []se.Result
+	err     error
+	delay   time.Duration
+}
+
+func (c *stubClient) Search(ctx context.Context, search string, funcs ...se.OptionFunc) ([]se.Result, error) {
+	if c.delay > 0 {
+		select {
+		case <-time.After(c.delay):
+		case <-ctx.Done():
+			return nil, errors.WithStack(ctx.Err())
+		}
+	}
+	return c.results, c.err
+}
+
+func results(urls ...string) []se.Result {
+	res := make([]se.Result, 0, len(urls))
+	for _, u := range urls {
+		res = append(res, se.Result{Title: u, URL: u})
+	}
+	return res
+}
+
+func urls(results []se.Result) []string {
+	urls := make([]string, 0, len(results))
+	for _, r := range results {
+		urls = append(urls, r.URL)
+	}
+	return urls
+}
+
+func TestClientRRF(t *testing.T) {
+	// The slow engine answers last, the merge must not depend on it
+	first := &stubClient{results: results("https://a.org", "https://b.org", "https://c.org"), delay: 20 * time.Millisecond}
+	second := &stubClient{results: results("https://www.c.org/", "http://b.org#top", "https://d.org")}
+	failing := &stubClient{err: errors.New("unavailable")}
+
+	client := NewClientWithEngines([]Engine{
+		{Name: "first", Client: first},
+		{Name: "second", Client: second},
+		{Name: "failing", Client: failing},
+	})
+
+	for i := 0; i < 5; i++ {
+		merged, err := client.Search(context.Background(), "query")
+		if err != nil {
+			t.Fatalf("expected no error, got: %+v", err)
+		}
+
+		// c: 1/63 + 1/61, b: 1/62 + 1/62, a: 1/61, d: 1/63
+		expected := []string{"https://c.org", "https://b.org", "https://a.org", "https://d.org"}
+		if got := urls(merged); !slices.Equal(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+
+		if engines := merged[0].Engines; !slices.Equal(engines, []string{"first", "second"}) {
+			t.Errorf("expected the first result to come from both engines, got %v", engines)
+		}
+		if engines := merged[3].Engines; !slices.Equal(engines, []string{"second"}) {
+			t.Errorf("expected the last result to come from the second engine, got %v", engines)
+		}
+		if merged[0].Score <= merged[1].Score {
+			t.Errorf("expected decreasing scores, got %f and %f", merged[0].Score, merged[1].Score)
+		}
+	}
+}
+
+func TestClientWeightedScores(t *testing.T) {
+	scored := &stubClient{results: []se.Result{
+		{Title: "a", URL: "https://a.org", Score: 1},
+		{Title: "b", URL: "https://b.org", Score: 4},
+	}}
+	unscored := &stubClient{results: results("https://a.org", "https://c.org")}
+
+	client := NewClientWithEngines([]Engine{
+		{Name: "scored", Client: scored, Weight: 2},
+		{Name: "unscored", Client: unscored},
+	}, WithFusion(FusionScore))
+
+	merged, err := client.Search(context.Background(), "query")
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	// a: 2*0.25 + 1, b: 2*1, c: 0.5
+	expected := []string{"https://b.org", "https://a.org", "https://c.org"}
+	if got := urls(merged); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	merged, err = client.Search(context.Background(), "query", se.WithMaxResults(2))
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+	if got := urls(merged); !slices.Equal(got, expected[:2]) {
+		t.Errorf("expected %v, got %v", expected[:2], got)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	slow := &stubClient{results: results("https://slow.org"), delay: time.Second}
+	fast := &stubClient{results: results("https://fast.org")}
+
+	client := NewClientWithEngines([]Engine{
+		{Name: "slow", Client: slow},
+		{Name: "fast", Client: fast},
+	}, WithTimeout(10*time.Millisecond))
+
+	merged, err := client.Search(context.Background(), "query")
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+	if got := urls(merged); !slices.Equal(got, []string{"https://fast.org"}) {
+		t.Errorf("expected the results of the fast engine, got %v", got)
+	}
+
+	allFailing := NewClient(&stubClient{err: errors.New("first")}, &stubClient{err: errors.New("second")})
+	if _, err := allFailing.Search(context.Background(), "query"); err == nil {
+		t.Error("expected an error when all engines fail")
+	}
+}
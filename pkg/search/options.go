@@ -0,0 +1,127 @@
+package search
+
+import (
+	"strings"
+)
+
+// TimeRange restricts a search to the results published recently.
+type TimeRange string
+
+const (
+	TimeRangeDay   TimeRange = "day"
+	TimeRangeWeek  TimeRange = "week"
+	TimeRangeMonth TimeRange = "month"
+	TimeRangeYear  TimeRange = "year"
+)
+
+// Valid returns whether t is one of the known time ranges. Engines ignore
+// unknown time ranges.
+func (t TimeRange) Valid() bool {
+	switch t {
+	case TimeRangeDay, TimeRangeWeek, TimeRangeMonth, TimeRangeYear:
+		return true
+	default:
+		return false
+	}
+}
+
+// Options refine a search. Engines apply the options they support natively
+// and fall back to query operators, or ignore them, otherwise.
+type Options struct {
+	// Language of the results, as an ISO 639-1 code, e.g. "en".
+	Language string
+	// Region of the results, as an ISO 3166-1 alpha-2 code, e.g. "us".
+	Region    string
+	TimeRange TimeRange
+	// MaxResults limits the number of results, 0 for the default of the
+	// engine.
+	MaxResults int
+	// Sites restricts the results to these domains, e.g. "europa.eu".
+	Sites []string
+	// FileType restricts the results to documents of this extension, e.g.
+	// "pdf".
+	FileType string
+}
+
+type OptionFunc func(opts *Options)
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithLanguage(language string) OptionFunc {
+	return func(opts *Options) {
+		opts.Language = strings.ToLower(strings.TrimSpace(language))
+	}
+}
+
+func WithRegion(region string) OptionFunc {
+	return func(opts *Options) {
+		opts.Region = strings.ToLower(strings.TrimSpace(region))
+	}
+}
+
+func WithTimeRange(timeRange TimeRange) OptionFunc {
+	return func(opts *Options) {
+		opts.TimeRange = timeRange
+	}
+}
+
+func WithMaxResults(max int) OptionFunc {
+	return func(opts *Options) {
+		opts.MaxResults = max
+	}
+}
+
+func WithSites(sites ...string) OptionFunc {
+	return func(opts *Options) {
+		opts.Sites = sites
+	}
+}
+
+func WithFileType(fileType string) OptionFunc {
+	return func(opts *Options) {
+		opts.FileType = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(fileType)), ".")
+	}
+}
+
+// Funcs returns the option funcs setting opts, to pass them on to another
+// client.
+func (o *Options) Funcs() []OptionFunc {
+	return []OptionFunc{func(opts *Options) { *opts = *o }}
+}
+
+// QueryWithOperators returns query with the site: and filetype: operators of
+// the options, for the engines without native support for them.
+func (o *Options) QueryWithOperators(query string) string {
+	sites := make([]string, 0, len(o.Sites))
+	for _, site := range o.Sites {
+		sites = append(sites, "site:"+site)
+	}
+
+	switch len(sites) {
+	case 0:
+	case 1:
+		query += " " + sites[0]
+	default:
+		query += " (" + strings.Join(sites, " OR ") + ")"
+	}
+
+	if o.FileType != "" {
+		query += " filetype:" + o.FileType
+	}
+
+	return query
+}
+
+// Truncate returns the first MaxResults results.
+func (o *Options) Truncate(results []Result) []Result {
+	if o.MaxResults > 0 && len(results) > o.MaxResults {
+		return results[:o.MaxResults]
+	}
+	return results
+}
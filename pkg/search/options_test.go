@@ -0,0 +1,37 @@
+package search
+
+import (
+	"testing"
+)
+
+func TestOptionsQueryWithOperators(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     *Options
+		expected string
+	}{
+		{"no operator", NewOptions(WithLanguage("EN")), "energy policy"},
+		{"single site", NewOptions(WithSites("europa.eu")), "energy policy site:europa.eu"},
+		{"several sites", NewOptions(WithSites("europa.eu", "iea.org")), "energy policy (site:europa.eu OR site:iea.org)"},
+		{"file type", NewOptions(WithSites("europa.eu"), WithFileType(".XLSX")), "energy policy site:europa.eu filetype:xlsx"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.opts.QueryWithOperators("energy policy"); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestOptionsTruncate(t *testing.T) {
+	results := []Result{{URL: "a"}, {URL: "b"}, {URL: "c"}}
+
+	if got := NewOptions().Truncate(results); len(got) != 3 {
+		t.Errorf("expected all the results without a maximum, got %d", len(got))
+	}
+	if got := NewOptions(WithMaxResults(2)).Truncate(results); len(got) != 2 || got[1].URL != "b" {
+		t.Errorf("expected the first 2 results, got %+v", got)
+	}
+}
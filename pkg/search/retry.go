@@ -16,11 +16,11 @@ type Retry struct {
 }
 
 // Search implements Client.
-func (r *Retry) Search(ctx context.Context, search string) ([]Result, error) {
+func (r *Retry) Search(ctx context.Context, search string, funcs ...OptionFunc) ([]Result, error) {
 	backoff := r.baseDelay
 	retries := 0
 	for {
-		results, err := r.client.Search(ctx, search)
+		results, err := r.client.Search(ctx, search, funcs...)
 		if err != nil {
 			if retries < r.maxRetries {
 				slog.WarnContext(ctx, "search failed, will retry", slog.Duration("backoff", backoff), slog.Int("retries", retries), slog.Any("error", errors.WithStack(err)))
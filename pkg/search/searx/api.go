@@ -0,0 +1,181 @@
+package searx
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	searchEngine "github.com/bornholm/ghostwriter/pkg/search"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+// apiResponse is the subset of the response of the SearXNG JSON API used by
+// the client.
+type apiResponse struct {
+	Results []apiResult `json:"results"`
+}
+
+type apiResult struct {
+	URL           string  `json:"url"`
+	Title         string  `json:"title"`
+	Content       string  `json:"content"`
+	Engine        string  `json:"engine"`
+	Score         float64 `json:"score"`
+	PublishedDate *string `json:"publishedDate"`
+}
+
+// publishedDateLayouts are the layouts of the published dates returned by the
+// engines of SearXNG, which do not agree on a single one.
+var publishedDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.DateOnly,
+}
+
+// searchAPI queries the JSON API of the configured instances in order, until
+// one of them answers.
+func (c *Client) searchAPI(ctx context.Context, search string, searchOpts *searchEngine.Options) ([]searchEngine.Result, error) {
+	if len(c.opts.Instances) == 0 {
+		return nil, errors.New("the searxng json api requires at least one instance url")
+	}
+
+	var aggregatedErr error
+	for _, instance := range c.opts.Instances {
+		results, err := c.doSearchAPI(ctx, instance, search, searchOpts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.WithStack(err)
+			}
+			slog.WarnContext(ctx, "searxng instance failed", slog.String("instance", instance), slog.Any("error", err))
+			aggregatedErr = multierror.Append(aggregatedErr, err)
+			continue
+		}
+
+		return results, nil
+	}
+
+	return nil, errors.WithStack(aggregatedErr)
+}
+
+func (c *Client) doSearchAPI(ctx context.Context, instance string, search string, searchOpts *searchEngine.Options) ([]searchEngine.Result, error) {
+	serverURL, err := url.Parse(instance)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid searxng instance url %q", instance)
+	}
+
+	searchURL := serverURL.JoinPath("/search")
+
+	query := searchURL.Query()
+	query.Set("q", search)
+	query.Set("format", "json")
+	c.setSearchParams(query, searchOpts)
+	searchURL.RawQuery = query.Encode()
+
+	slog.DebugContext(ctx, "executing search", slog.String("url", searchURL.String()))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL.String(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for name, values := range c.opts.Headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusForbidden {
+		return nil, errors.Errorf("searxng instance %q refused the request (status %d): is the json format enabled and the authentication valid?", instance, res.StatusCode)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, errors.Errorf("searxng instance %q returned status %d: %s", instance, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload apiResponse
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		return nil, errors.Wrapf(err, "could not decode the response of searxng instance %q", instance)
+	}
+
+	results := make([]searchEngine.Result, 0, len(payload.Results))
+	for _, r := range payload.Results {
+		if r.URL == "" || r.Title == "" {
+			continue
+		}
+
+		result := searchEngine.Result{
+			Title:       strings.TrimSpace(r.Title),
+			URL:         r.URL,
+			Description: strings.TrimSpace(r.Content),
+			Engine:      r.Engine,
+			Score:       r.Score,
+		}
+
+		if r.PublishedDate != nil {
+			result.PublishedAt = parsePublishedDate(*r.PublishedDate)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// setSearchParams sets the language and the filters of the client options,
+// overridden by those of the search options, on the query of a search.
+func (c *Client) setSearchParams(query url.Values, searchOpts *searchEngine.Options) {
+	language := c.opts.Language
+	if searchOpts.Language != "" {
+		language = searchOpts.Language
+		if searchOpts.Region != "" {
+			language += "-" + strings.ToUpper(searchOpts.Region)
+		}
+	}
+	if language != "" {
+		query.Set("language", language)
+	}
+
+	if len(c.opts.Categories) > 0 {
+		query.Set("categories", strings.Join(c.opts.Categories, ","))
+	}
+
+	timeRange := c.opts.TimeRange
+	if searchOpts.TimeRange.Valid() {
+		timeRange = string(searchOpts.TimeRange)
+	}
+	if timeRange != "" {
+		query.Set("time_range", timeRange)
+	}
+
+	if c.opts.SafeSearch >= 0 {
+		query.Set("safesearch", strconv.Itoa(c.opts.SafeSearch))
+	}
+}
+
+// parsePublishedDate returns the zero time for an empty or unknown date.
+func parsePublishedDate(raw string) time.Time {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range publishedDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
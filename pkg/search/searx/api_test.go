@@ -0,0 +1,95 @@
+package searx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bornholm/ghostwriter/pkg/search"
+)
+
+func TestClientJSON(t *testing.T) {
+	var query map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		query = map[string]string{}
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"query": "solar panels",
+			"results": [
+				{"url": "https://example.org/solar", "title": "Solar panels", "content": " How they work ", "engine": "bing", "score": 2.5, "publishedDate": "2024-03-01T10:00:00"},
+				{"url": "https://example.org/costs", "title": "Costs", "content": "Prices", "engine": "brave", "score": 1, "publishedDate": null},
+				{"url": "", "title": "No url"}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	client := NewClient(
+		WithInstances(down.URL, server.URL),
+		WithJSON(true),
+		WithHeader("Authorization", "Bearer secret"),
+		WithLanguage("en"),
+		WithCategories("general", "news"),
+		WithTimeRange("month"),
+		WithSafeSearch(1),
+	)
+
+	results, err := client.Search(context.Background(), "solar panels",
+		search.WithTimeRange(search.TimeRangeYear),
+		search.WithSites("example.org"),
+		search.WithMaxResults(2),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	expectedQuery := map[string]string{
+		"q":          "solar panels site:example.org",
+		"format":     "json",
+		"language":   "en",
+		"categories": "general,news",
+		"time_range": "year",
+		"safesearch": "1",
+	}
+	for k, v := range expectedQuery {
+		if query[k] != v {
+			t.Errorf("expected %s=%q, got %q", k, v, query[k])
+		}
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	first := results[0]
+	if first.Description != "How they work" || first.Engine != "bing" || first.Score != 2.5 {
+		t.Errorf("unexpected first result %+v", first)
+	}
+	if expected := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC); !first.PublishedAt.Equal(expected) {
+		t.Errorf("expected published date %s, got %s", expected, first.PublishedAt)
+	}
+	if !results[1].PublishedAt.IsZero() {
+		t.Errorf("expected no published date, got %s", results[1].PublishedAt)
+	}
+
+	if _, err := NewClient(WithInstances(server.URL), WithJSON(true)).Search(context.Background(), "solar panels"); err == nil {
+		t.Error("expected an error without authentication")
+	}
+}
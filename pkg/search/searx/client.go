@@ -19,11 +19,117 @@ import (
 	"github.com/pkg/errors"
 )
 
-type Client struct{}
+type Client struct {
+	opts *Options
+}
+
+type Options struct {
+	// Instances are the SearXNG instances to query, in order of preference.
+	// Public instances are discovered through searx.space when empty.
+	Instances []string
+	// JSON queries the JSON API of the instances instead of scraping their
+	// result pages. The instances must enable the json format.
+	JSON bool
+	// Headers are added to the requests of the JSON API, e.g. to
+	// authenticate with a private instance.
+	Headers http.Header
+	// Language is the language of the results, e.g. "fr" or "en-US".
+	Language string
+	// Categories restricts the search to these categories, e.g. "general" or
+	// "news".
+	Categories []string
+	// TimeRange restricts the search to the results of the last "day",
+	// "week", "month" or "year".
+	TimeRange string
+	// SafeSearch filters the results: 0 for none, 1 for moderate, 2 for
+	// strict. Negative to use the setting of the instance.
+	SafeSearch int
+	HTTPClient *http.Client
+}
+
+type OptionFunc func(opts *Options)
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Language:   "fr",
+		SafeSearch: -1,
+		Headers:    http.Header{},
+		HTTPClient: http.DefaultClient,
+	}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+// WithInstances sets the SearXNG instances to query, e.g. a self-hosted one,
+// instead of discovering public instances.
+func WithInstances(urls ...string) OptionFunc {
+	return func(opts *Options) {
+		opts.Instances = urls
+	}
+}
+
+// WithJSON enables the JSON API of the instances.
+func WithJSON(enabled bool) OptionFunc {
+	return func(opts *Options) {
+		opts.JSON = enabled
+	}
+}
+
+// WithHeader adds a header to the requests of the JSON API.
+func WithHeader(name, value string) OptionFunc {
+	return func(opts *Options) {
+		opts.Headers.Add(name, value)
+	}
+}
+
+func WithLanguage(language string) OptionFunc {
+	return func(opts *Options) {
+		opts.Language = language
+	}
+}
+
+func WithCategories(categories ...string) OptionFunc {
+	return func(opts *Options) {
+		opts.Categories = categories
+	}
+}
+
+func WithTimeRange(timeRange string) OptionFunc {
+	return func(opts *Options) {
+		opts.TimeRange = timeRange
+	}
+}
+
+func WithSafeSearch(level int) OptionFunc {
+	return func(opts *Options) {
+		opts.SafeSearch = level
+	}
+}
+
+func WithHTTPClient(client *http.Client) OptionFunc {
+	return func(opts *Options) {
+		opts.HTTPClient = client
+	}
+}
 
 const instancesURL = "https://searx.space/data/instances.json"
 
 func (c *Client) getInstanceURL(query string, ignored ...string) (*url.URL, error) {
+	if len(c.opts.Instances) > 0 {
+		for _, raw := range c.opts.Instances {
+			u, err := url.Parse(raw)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid searxng instance url %q", raw)
+			}
+			if !slices.Contains(ignored, u.String()) {
+				return u, nil
+			}
+		}
+		return nil, errors.New("no available instance")
+	}
+
 	res, err := http.Get(instancesURL)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -89,7 +195,21 @@ func (c *Client) getInstanceURL(query string, ignored ...string) (*url.URL, erro
 	return url, nil
 }
 
-func (c *Client) Search(ctx context.Context, search string) ([]searchEngine.Result, error) {
+// Search implements search.Client. The language and the time range of the
+// search options override those of the client; sites and file types are
+// given as query operators.
+func (c *Client) Search(ctx context.Context, search string, funcs ...searchEngine.OptionFunc) ([]searchEngine.Result, error) {
+	searchOpts := searchEngine.NewOptions(funcs...)
+	search = searchOpts.QueryWithOperators(search)
+
+	if c.opts.JSON {
+		results, err := c.searchAPI(ctx, search, searchOpts)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return searchOpts.Truncate(results), nil
+	}
+
 	maxRetries := 3
 	ignored := make([]string, 0)
 	retries := 0
@@ -99,7 +219,7 @@ func (c *Client) Search(ctx context.Context, search string) ([]searchEngine.Resu
 			return nil, errors.WithStack(err)
 		}
 
-		results, err := c.doSearch(ctx, serverURL, search)
+		results, err := c.doSearch(ctx, serverURL, search, searchOpts)
 		if err != nil {
 			if retries >= maxRetries {
 				return nil, errors.WithStack(err)
@@ -117,17 +237,17 @@ func (c *Client) Search(ctx context.Context, search string) ([]searchEngine.Resu
 			continue
 		}
 
-		return results, nil
+		return searchOpts.Truncate(results), nil
 	}
 
 }
 
-func (c *Client) doSearch(ctx context.Context, serverURL *url.URL, search string) ([]searchEngine.Result, error) {
+func (c *Client) doSearch(ctx context.Context, serverURL *url.URL, search string, searchOpts *searchEngine.Options) ([]searchEngine.Result, error) {
 	searchURL := serverURL.JoinPath("/search")
 
 	query := searchURL.Query()
 	query.Set("q", search)
-	query.Set("language", "fr")
+	c.setSearchParams(query, searchOpts)
 	searchURL.RawQuery = query.Encode()
 
 	slog.DebugContext(ctx, "executing search", slog.String("url", searchURL.String()))
@@ -205,8 +325,8 @@ func (c *Client) doSearch(ctx context.Context, serverURL *url.URL, search string
 	return results, nil
 }
 
-func NewClient() *Client {
-	return &Client{}
+func NewClient(funcs ...OptionFunc) *Client {
+	return &Client{opts: NewOptions(funcs...)}
 }
 
 var _ search.Client = &Client{}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bornholm/genai/llm"
 	"github.com/bornholm/ghostwriter/pkg/search"
@@ -15,14 +16,27 @@ func NewWebSearchTool(client search.Client) llm.Tool {
 		"web_search",
 		"execute a research on the web about a topic",
 		llm.NewJSONSchema().
-			RequiredProperty("topic", "the topic to research", "string"),
+			RequiredProperty("topic", "the topic to research", "string").
+			Property("language", "optional ISO 639-1 code of the language of the results, e.g. en", "string").
+			Property("region", "optional ISO 3166-1 alpha-2 code of the region of the results, e.g. us", "string").
+			Property("time_range", "optional period the results must be published within: day, week, month or year", "string").
+			Property("site", "optional domain to restrict the results to, e.g. europa.eu", "string"),
 		func(ctx context.Context, params map[string]any) (llm.ToolResult, error) {
 			topic, err := llm.ToolParam[string](params, "topic")
 			if err != nil {
 				return nil, errors.WithStack(err)
 			}
 
-			results, err := client.Search(ctx, topic)
+			opts := []search.OptionFunc{
+				search.WithLanguage(optionalParam(params, "language")),
+				search.WithRegion(optionalParam(params, "region")),
+				search.WithTimeRange(search.TimeRange(optionalParam(params, "time_range"))),
+			}
+			if site := optionalParam(params, "site"); site != "" {
+				opts = append(opts, search.WithSites(site))
+			}
+
+			results, err := client.Search(ctx, topic, opts...)
 			if err != nil {
 				return nil, errors.WithStack(err)
 			}
@@ -34,6 +48,9 @@ func NewWebSearchTool(client search.Client) llm.Tool {
 			for i, r := range results {
 				sb.WriteString(fmt.Sprintf("## %d. %s\n\n", i+1, r.Title))
 				sb.WriteString(fmt.Sprintf("**URL**: %s\n", r.URL))
+				if !r.PublishedAt.IsZero() {
+					sb.WriteString(fmt.Sprintf("**Published**: %s\n", r.PublishedAt.Format(time.DateOnly)))
+				}
 				sb.WriteString(fmt.Sprintf("**Description**:\n%s\n\n", r.Description))
 			}
 
@@ -41,3 +58,10 @@ func NewWebSearchTool(client search.Client) llm.Tool {
 		},
 	)
 }
+
+// optionalParam returns the string parameter name, or an empty string if it
+// is missing or not a string.
+func optionalParam(params map[string]any, name string) string {
+	value, _ := params[name].(string)
+	return value
+}
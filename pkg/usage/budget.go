@@ -0,0 +1,95 @@
+package usage
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrBudgetExceeded is returned when a run used up its Budget: the LLM
+// client refuses new calls and the orchestrators stop starting new steps.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget limits the usage of a run. Zero values are unlimited.
+type Budget struct {
+	MaxTokens int64
+	// MaxCost is expressed in the currency of the pricing table. The calls to
+	// unpriced models do not count against it.
+	MaxCost float64
+}
+
+func (b Budget) IsZero() bool {
+	return b.MaxTokens <= 0 && b.MaxCost <= 0
+}
+
+type TrackerOptions struct {
+	Budget Budget
+}
+
+type TrackerOptionFunc func(opts *TrackerOptions)
+
+func NewTrackerOptions(funcs ...TrackerOptionFunc) *TrackerOptions {
+	opts := &TrackerOptions{}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithBudget(budget Budget) TrackerOptionFunc {
+	return func(opts *TrackerOptions) {
+		opts.Budget = budget
+	}
+}
+
+// CheckBudget returns an error wrapping ErrBudgetExceeded when the budget of
+// the tracker is used up. A nil tracker has no budget.
+func (t *Tracker) CheckBudget() error {
+	return t.CheckBudgetFor(0, 0)
+}
+
+// CheckBudgetFor returns an error wrapping ErrBudgetExceeded when spending
+// the given tokens and cost on top of the recorded usage would use up the
+// budget. It lets the orchestrators avoid starting a step they could not
+// finish.
+func (t *Tracker) CheckBudgetFor(tokens int64, cost float64) error {
+	if t == nil || t.budget.IsZero() {
+		return nil
+	}
+
+	t.mu.Lock()
+	spentTokens, spentCost := t.spentTokens, t.spentCost
+	t.mu.Unlock()
+
+	if limit := t.budget.MaxTokens; limit > 0 && spentTokens+tokens >= limit {
+		return errors.Wrapf(ErrBudgetExceeded, "%d tokens used (+%d expected) of a maximum of %d", spentTokens, tokens, limit)
+	}
+
+	if limit := t.budget.MaxCost; limit > 0 && spentCost+cost >= limit {
+		return errors.Wrapf(ErrBudgetExceeded, "estimated cost of %.4f (+%.4f expected) of a maximum of %.4f", spentCost, cost, limit)
+	}
+
+	return nil
+}
+
+// ChapterEstimate returns the average usage of the chapters recorded so far,
+// or zero values if none was.
+func (t *Tracker) ChapterEstimate() (tokens int64, cost float64) {
+	if t == nil {
+		return 0, 0
+	}
+
+	report := t.Report()
+	if len(report.Chapters) == 0 {
+		return 0, 0
+	}
+
+	for _, ch := range report.Chapters {
+		tokens += ch.TotalTokens
+		if ch.Cost != nil {
+			cost += *ch.Cost
+		}
+	}
+
+	n := len(report.Chapters)
+
+	return tokens / int64(n), cost / float64(n)
+}
@@ -0,0 +1,29 @@
+package usage
+
+import "github.com/bornholm/genai/agent"
+
+// EventTypeSummary is emitted at the end of a run with its usage report.
+const EventTypeSummary agent.EventType = "usage.summary"
+
+// SummaryData is the payload of EventTypeSummary.
+type SummaryData struct {
+	Report
+	// File is the path of the usage report written for the run, if any.
+	File string `json:"file,omitempty"`
+}
+
+func NewSummaryEvent(report Report, file string) agent.Event {
+	return agent.NewEvent(EventTypeSummary, &SummaryData{Report: report, File: file})
+}
+
+// EventTypeFallback is emitted when the LLM calls switch to a fallback model.
+const EventTypeFallback agent.EventType = "usage.fallback"
+
+// FallbackData is the payload of EventTypeFallback.
+type FallbackData struct {
+	Fallback
+}
+
+func NewFallbackEvent(fallback Fallback) agent.Event {
+	return agent.NewEvent(EventTypeFallback, &FallbackData{Fallback: fallback})
+}
@@ -0,0 +1,63 @@
+package usage
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Price is the price of a model per million tokens.
+type Price struct {
+	Prompt     float64 `json:"prompt" yaml:"prompt"`
+	Completion float64 `json:"completion" yaml:"completion"`
+}
+
+// Pricing maps model identifiers to their price. Keys are either
+// "provider/model" identifiers (e.g. "openrouter/google/gemini-2.5-flash") or
+// bare model names (e.g. "google/gemini-2.5-flash").
+type Pricing map[string]Price
+
+// ParsePricing parses a JSON pricing table:
+//
+//	{"openrouter/google/gemini-2.5-flash": {"prompt": 0.3, "completion": 2.5}}
+//
+// An empty string returns an empty table.
+func ParsePricing(s string) (Pricing, error) {
+	pricing := Pricing{}
+
+	if strings.TrimSpace(s) == "" {
+		return pricing, nil
+	}
+
+	if err := json.Unmarshal([]byte(s), &pricing); err != nil {
+		return nil, errors.Wrap(err, "could not parse pricing table")
+	}
+
+	return pricing, nil
+}
+
+// Price returns the price of model. The "provider/" prefix of the identifier
+// is dropped when the full identifier has no price.
+func (p Pricing) Price(model string) (Price, bool) {
+	if price, exists := p[model]; exists {
+		return price, true
+	}
+
+	if _, name, found := strings.Cut(model, "/"); found {
+		price, exists := p[name]
+		return price, exists
+	}
+
+	return Price{}, false
+}
+
+// Cost returns the estimated cost of a call, and false if model has no price.
+func (p Pricing) Cost(model string, promptTokens, completionTokens int64) (float64, bool) {
+	price, exists := p.Price(model)
+	if !exists {
+		return 0, false
+	}
+
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1_000_000, true
+}
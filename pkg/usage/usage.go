@@ -0,0 +1,298 @@
+// Package usage accounts for the tokens consumed by the LLM calls of a run and
+// estimates their cost.
+//
+// A Tracker is attached to the context of a run with WithContextTracker; the
+// LLM client middleware records every call in it, tagged with the agent role
+// and the chapter found in the context.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/pkg/errors"
+)
+
+// OtherRole is the role of the calls made outside of a tagged agent.
+const OtherRole = "other"
+
+// Call is the usage of a single chat completion.
+type Call struct {
+	Role             string
+	Chapter          int
+	Model            string
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// Fallback is the switch of the LLM calls of a run from an unavailable model
+// to the next model of its fallback chain.
+type Fallback struct {
+	Time    time.Time `json:"time"`
+	Role    string    `json:"role"`
+	Chapter int       `json:"chapter,omitempty"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	// Reason is the error returned by the unavailable model.
+	Reason string `json:"reason"`
+}
+
+// Tracker accumulates the usage of the calls of a run. It is safe for
+// concurrent use.
+type Tracker struct {
+	pricing Pricing
+	budget  Budget
+
+	mu          sync.Mutex
+	calls       []Call
+	fallbacks   []Fallback
+	spentTokens int64
+	spentCost   float64
+	emit        agent.EmitFunc
+}
+
+func NewTracker(pricing Pricing, funcs ...TrackerOptionFunc) *Tracker {
+	opts := NewTrackerOptions(funcs...)
+	return &Tracker{
+		pricing: pricing,
+		budget:  opts.Budget,
+		calls:   make([]Call, 0),
+	}
+}
+
+// Record adds a call to the tracker. A nil tracker ignores it.
+func (t *Tracker) Record(call Call) {
+	if t == nil {
+		return
+	}
+
+	if call.Role == "" {
+		call.Role = OtherRole
+	}
+
+	cost, _ := t.pricing.Cost(call.Model, call.PromptTokens, call.CompletionTokens)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.calls = append(t.calls, call)
+	t.spentTokens += call.PromptTokens + call.CompletionTokens
+	t.spentCost += cost
+}
+
+// RecordFallback adds a fallback to the tracker and emits it, see EmitTo. A
+// nil tracker ignores it.
+func (t *Tracker) RecordFallback(fallback Fallback) {
+	if t == nil {
+		return
+	}
+
+	if fallback.Role == "" {
+		fallback.Role = OtherRole
+	}
+
+	t.mu.Lock()
+	t.fallbacks = append(t.fallbacks, fallback)
+	emit := t.emit
+	t.mu.Unlock()
+
+	if emit != nil {
+		_ = emit(NewFallbackEvent(fallback))
+	}
+}
+
+// EmitTo sets the function emitting the events of the run as the tracker
+// records them, such as the fallbacks of the LLM client.
+func (t *Tracker) EmitTo(emit agent.EmitFunc) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.emit = emit
+}
+
+// Totals is the usage of a set of calls.
+type Totals struct {
+	Calls            int   `json:"calls"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	// Cost is the estimated cost of the priced calls, in the currency of the
+	// pricing table. It is omitted when no call could be priced.
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+type ModelUsage struct {
+	Model string `json:"model"`
+	Totals
+}
+
+type RoleUsage struct {
+	Role string `json:"role"`
+	Totals
+}
+
+type ChapterUsage struct {
+	Chapter int `json:"chapter"`
+	Totals
+}
+
+// Report is the usage of a run, as written to usage.json.
+type Report struct {
+	Totals
+	Models   []ModelUsage   `json:"models"`
+	Roles    []RoleUsage    `json:"roles"`
+	Chapters []ChapterUsage `json:"chapters,omitempty"`
+	// UnpricedModels lists the models missing from the pricing table: the
+	// cost does not include their calls.
+	UnpricedModels []string `json:"unpriced_models,omitempty"`
+	// Fallbacks lists the switches to a fallback model, in order.
+	Fallbacks []Fallback `json:"fallbacks,omitempty"`
+}
+
+// Report returns the usage recorded so far. Models, roles and chapters are
+// sorted by name and number.
+func (t *Tracker) Report() Report {
+	if t == nil {
+		return Report{}
+	}
+
+	t.mu.Lock()
+	calls := make([]Call, len(t.calls))
+	copy(calls, t.calls)
+	var report Report
+	if len(t.fallbacks) > 0 {
+		report.Fallbacks = make([]Fallback, len(t.fallbacks))
+		copy(report.Fallbacks, t.fallbacks)
+	}
+	t.mu.Unlock()
+
+	models := make(map[string]*Totals)
+	roles := make(map[string]*Totals)
+	chapters := make(map[int]*Totals)
+	unpriced := make(map[string]struct{})
+
+	for _, call := range calls {
+		cost, priced := t.pricing.Cost(call.Model, call.PromptTokens, call.CompletionTokens)
+		if !priced {
+			unpriced[call.Model] = struct{}{}
+		}
+
+		add := func(totals *Totals) {
+			totals.add(call, cost, priced)
+		}
+
+		add(&report.Totals)
+		add(totals(models, call.Model))
+		add(totals(roles, call.Role))
+		if call.Chapter > 0 {
+			add(totals(chapters, call.Chapter))
+		}
+	}
+
+	for _, model := range sortedKeys(models) {
+		report.Models = append(report.Models, ModelUsage{Model: model, Totals: *models[model]})
+	}
+	for _, role := range sortedKeys(roles) {
+		report.Roles = append(report.Roles, RoleUsage{Role: role, Totals: *roles[role]})
+	}
+	for _, chapter := range sortedKeys(chapters) {
+		report.Chapters = append(report.Chapters, ChapterUsage{Chapter: chapter, Totals: *chapters[chapter]})
+	}
+	for _, model := range sortedKeys(unpriced) {
+		report.UnpricedModels = append(report.UnpricedModels, model)
+	}
+
+	return report
+}
+
+func (t *Totals) add(call Call, cost float64, priced bool) {
+	t.Calls++
+	t.PromptTokens += call.PromptTokens
+	t.CompletionTokens += call.CompletionTokens
+	t.TotalTokens += call.PromptTokens + call.CompletionTokens
+
+	if !priced {
+		return
+	}
+	if t.Cost == nil {
+		t.Cost = new(float64)
+	}
+	*t.Cost += cost
+}
+
+func totals[K comparable](m map[K]*Totals, key K) *Totals {
+	t, exists := m[key]
+	if !exists {
+		t = &Totals{}
+		m[key] = t
+	}
+	return t
+}
+
+func sortedKeys[K int | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// FileName is the name of the usage report written in the output directory
+// of a run.
+const FileName = "usage.json"
+
+// WriteFile writes the report as indented JSON.
+func (r Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not serialize usage report")
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.Wrap(err, "could not create usage report directory")
+		}
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return errors.Wrap(err, "could not write usage report")
+	}
+
+	return nil
+}
+
+const (
+	contextKeyTracker agent.ContextKey = "usage_tracker"
+	contextKeyChapter agent.ContextKey = "usage_chapter"
+)
+
+// WithContextTracker attaches the tracker recording the calls made with ctx.
+func WithContextTracker(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, contextKeyTracker, t)
+}
+
+// ContextTracker returns the tracker of ctx, or nil.
+func ContextTracker(ctx context.Context) *Tracker {
+	return agent.ContextValue[*Tracker](ctx, contextKeyTracker, nil)
+}
+
+// WithContextChapter tags the calls made with ctx with a chapter number.
+func WithContextChapter(ctx context.Context, number int) context.Context {
+	return context.WithValue(ctx, contextKeyChapter, number)
+}
+
+// ContextChapter returns the chapter number of ctx, or 0.
+func ContextChapter(ctx context.Context) int {
+	return agent.ContextValue(ctx, contextKeyChapter, 0)
+}
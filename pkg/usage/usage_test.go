@@ -0,0 +1,213 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/pkg/errors"
+)
+
+func TestTrackerReport(t *testing.T) {
+	pricing := Pricing{
+		"google/gemini-2.5-flash": {Prompt: 0.3, Completion: 2.5},
+	}
+
+	tracker := NewTracker(pricing)
+	tracker.Record(Call{Role: "planner", Model: "openrouter/google/gemini-2.5-flash", PromptTokens: 1000, CompletionTokens: 500})
+	tracker.Record(Call{Role: "writer", Chapter: 2, Model: "openrouter/google/gemini-2.5-flash", PromptTokens: 2000, CompletionTokens: 1000})
+	tracker.Record(Call{Role: "writer", Chapter: 1, Model: "openrouter/google/gemini-2.5-flash", PromptTokens: 3000, CompletionTokens: 1000})
+	tracker.Record(Call{Model: "ollama/llama3", PromptTokens: 100, CompletionTokens: 10})
+
+	report := tracker.Report()
+
+	if report.Calls != 4 {
+		t.Errorf("expected 4 calls, got %d", report.Calls)
+	}
+	if report.TotalTokens != 8610 {
+		t.Errorf("expected 8610 tokens, got %d", report.TotalTokens)
+	}
+
+	expectedCost := (6000*0.3 + 2500*2.5) / 1_000_000
+	if report.Cost == nil || math.Abs(*report.Cost-expectedCost) > 1e-12 {
+		t.Errorf("expected cost %f, got %v", expectedCost, report.Cost)
+	}
+
+	if len(report.UnpricedModels) != 1 || report.UnpricedModels[0] != "ollama/llama3" {
+		t.Errorf("unexpected unpriced models: %v", report.UnpricedModels)
+	}
+
+	roles := make([]string, 0, len(report.Roles))
+	for _, r := range report.Roles {
+		roles = append(roles, r.Role)
+	}
+	if expected := []string{OtherRole, "planner", "writer"}; !slices.Equal(roles, expected) {
+		t.Errorf("expected roles %v, got %v", expected, roles)
+	}
+	if report.Roles[0].Cost != nil {
+		t.Errorf("expected no cost for unpriced calls, got %f", *report.Roles[0].Cost)
+	}
+
+	if len(report.Chapters) != 2 || report.Chapters[0].Chapter != 1 || report.Chapters[1].Chapter != 2 {
+		t.Fatalf("unexpected chapters: %+v", report.Chapters)
+	}
+	if report.Chapters[0].PromptTokens != 3000 {
+		t.Errorf("expected 3000 prompt tokens for chapter 1, got %d", report.Chapters[0].PromptTokens)
+	}
+}
+
+func TestTrackerConcurrentRecord(t *testing.T) {
+	tracker := NewTracker(nil)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker.Record(Call{Role: "writer", PromptTokens: 1})
+		}()
+	}
+	wg.Wait()
+
+	if report := tracker.Report(); report.Calls != 50 || report.Cost != nil {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestTrackerFallbacks(t *testing.T) {
+	tracker := NewTracker(nil)
+
+	// Recorded before the events are watched
+	tracker.RecordFallback(Fallback{From: "openrouter/a", To: "mistral/b", Reason: "circuit breaker is open"})
+
+	var emitted []Fallback
+	tracker.EmitTo(func(evt agent.Event) error {
+		if evt.Type() != EventTypeFallback {
+			t.Errorf("expected a %s event, got %s", EventTypeFallback, evt.Type())
+		}
+		emitted = append(emitted, evt.Data().(*FallbackData).Fallback)
+		return nil
+	})
+	tracker.RecordFallback(Fallback{Role: "writer", Chapter: 2, From: "mistral/b", To: "ollama/c", Reason: "unavailable"})
+
+	report := tracker.Report()
+	if len(report.Fallbacks) != 2 {
+		t.Fatalf("expected 2 fallbacks, got %+v", report.Fallbacks)
+	}
+	if f := report.Fallbacks[0]; f.Role != OtherRole || f.To != "mistral/b" {
+		t.Errorf("unexpected first fallback: %+v", f)
+	}
+	if len(emitted) != 1 || emitted[0].To != "ollama/c" || emitted[0].Chapter != 2 {
+		t.Errorf("expected the second fallback to be emitted, got %+v", emitted)
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.Record(Call{PromptTokens: 1})
+	tracker.RecordFallback(Fallback{From: "a", To: "b"})
+
+	if report := tracker.Report(); report.Calls != 0 {
+		t.Errorf("expected an empty report, got %+v", report)
+	}
+
+	if ContextTracker(context.Background()) != nil {
+		t.Error("expected no tracker in an empty context")
+	}
+}
+
+func TestParsePricing(t *testing.T) {
+	pricing, err := ParsePricing(`{"openai/gpt-4o-mini": {"prompt": 0.15, "completion": 0.6}}`)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	cost, priced := pricing.Cost("openai/gpt-4o-mini", 1_000_000, 1_000_000)
+	if !priced || math.Abs(cost-0.75) > 1e-12 {
+		t.Errorf("expected a cost of 0.75, got %f (priced: %v)", cost, priced)
+	}
+
+	if _, err := ParsePricing("{"); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+
+	if pricing, err := ParsePricing(""); err != nil || len(pricing) != 0 {
+		t.Errorf("expected an empty table, got %v, %v", pricing, err)
+	}
+}
+
+func TestReportWriteFile(t *testing.T) {
+	tracker := NewTracker(nil)
+	tracker.Record(Call{Role: "editor", Chapter: 3, Model: "openai/gpt-4o", PromptTokens: 10, CompletionTokens: 5})
+
+	path := filepath.Join(t.TempDir(), "out", FileName)
+	if err := tracker.Report().WriteFile(path); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("could not parse usage report: %v", err)
+	}
+	if report.TotalTokens != 15 || len(report.Chapters) != 1 || report.Chapters[0].Chapter != 3 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestTrackerBudget(t *testing.T) {
+	pricing := Pricing{"openai/gpt-4o-mini": {Prompt: 1, Completion: 1}}
+
+	tracker := NewTracker(pricing, WithBudget(Budget{MaxTokens: 1000}))
+	if err := tracker.CheckBudget(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	tracker.Record(Call{Chapter: 1, Model: "openai/gpt-4o-mini", PromptTokens: 300, CompletionTokens: 100})
+	if err := tracker.CheckBudget(); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+
+	tokens, _ := tracker.ChapterEstimate()
+	if tokens != 400 {
+		t.Errorf("expected an estimate of 400 tokens, got %d", tokens)
+	}
+	if err := tracker.CheckBudgetFor(tokens, 0); err != nil {
+		t.Errorf("expected a second chapter to fit, got: %v", err)
+	}
+
+	tracker.Record(Call{Chapter: 2, Model: "openai/gpt-4o-mini", PromptTokens: 300, CompletionTokens: 100})
+	if err := tracker.CheckBudgetFor(400, 0); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected a third chapter not to fit, got: %v", err)
+	}
+
+	tracker.Record(Call{Model: "openai/gpt-4o-mini", PromptTokens: 200})
+	if err := tracker.CheckBudget(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded, got: %v", err)
+	}
+
+	costTracker := NewTracker(pricing, WithBudget(Budget{MaxCost: 0.001}))
+	costTracker.Record(Call{Model: "ollama/llama3", PromptTokens: 10_000})
+	if err := costTracker.CheckBudget(); err != nil {
+		t.Errorf("expected unpriced calls not to count against the cost, got: %v", err)
+	}
+	costTracker.Record(Call{Model: "openai/gpt-4o-mini", PromptTokens: 2000})
+	if err := costTracker.CheckBudget(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded, got: %v", err)
+	}
+
+	var nilTracker *Tracker
+	if err := nilTracker.CheckBudget(); err != nil {
+		t.Errorf("expected no budget for a nil tracker, got: %v", err)
+	}
+}
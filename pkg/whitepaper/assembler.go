@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bornholm/ghostwriter/pkg/locale"
 	"github.com/gosimple/slug"
 	"github.com/pkg/errors"
 )
@@ -15,10 +16,23 @@ import (
 // AssembleOptions configures the document assembly.
 type AssembleOptions struct {
 	OutputDir string
+	// Locale is the language of the headings. Defaults to locale.Default.
+	Locale locale.Locale
+	// Incomplete marks index.md as a white paper whose generation was
+	// stopped before its end, e.g. by its budget.
+	Incomplete bool
+	// Rewritten restricts the chapter files written to the chapter with this
+	// number: the others, loaded from OutputDir, are left as they are on disk
+	// under the name given by their ChapterID. Zero writes them all.
+	Rewritten int
 }
 
 // Assemble writes all white paper files to outputDir and returns the WhitePaper result.
 func Assemble(plan WhitePaperPlan, chapters []ChapterContent, coherence CoherenceEditResult, opts AssembleOptions) (WhitePaper, error) {
+	if opts.Locale == "" {
+		opts.Locale = locale.Default
+	}
+
 	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
 		return WhitePaper{}, errors.Wrap(err, "could not create output directory")
 	}
@@ -27,6 +41,11 @@ func Assemble(plan WhitePaperPlan, chapters []ChapterContent, coherence Coherenc
 
 	// Write individual chapter files
 	for _, ch := range chapters {
+		if opts.Rewritten != 0 && ch.Number != opts.Rewritten {
+			chapterFiles = append(chapterFiles, ch.ChapterID+".md")
+			continue
+		}
+
 		filename := fmt.Sprintf("chapter-%02d-%s.md", ch.Number, slug.Make(ch.Title))
 		path := filepath.Join(opts.OutputDir, filename)
 
@@ -43,7 +62,7 @@ func Assemble(plan WhitePaperPlan, chapters []ChapterContent, coherence Coherenc
 
 	// Write bibliography
 	bibPath := filepath.Join(opts.OutputDir, "bibliography.md")
-	if err := os.WriteFile(bibPath, []byte(buildBibliography(coherence.Bibliography)), 0644); err != nil {
+	if err := os.WriteFile(bibPath, []byte(buildBibliography(opts.Locale, coherence.Bibliography)), 0644); err != nil {
 		return WhitePaper{}, errors.Wrap(err, "could not write bibliography")
 	}
 
@@ -59,7 +78,7 @@ func Assemble(plan WhitePaperPlan, chapters []ChapterContent, coherence Coherenc
 
 	// Write index.md (amatl entrypoint)
 	indexPath := filepath.Join(opts.OutputDir, "index.md")
-	indexContent := buildIndex(plan, coherence, chapterFiles, len(coherence.Appendices))
+	indexContent := buildIndex(opts.Locale, plan, coherence, chapterFiles, len(coherence.Appendices), opts.Incomplete)
 	if err := os.WriteFile(indexPath, []byte(indexContent), 0644); err != nil {
 		return WhitePaper{}, errors.Wrap(err, "could not write index.md")
 	}
@@ -84,7 +103,7 @@ func Assemble(plan WhitePaperPlan, chapters []ChapterContent, coherence Coherenc
 	}, nil
 }
 
-func buildIndex(plan WhitePaperPlan, coherence CoherenceEditResult, chapterFiles []string, appendixCount int) string {
+func buildIndex(l locale.Locale, plan WhitePaperPlan, coherence CoherenceEditResult, chapterFiles []string, appendixCount int, incomplete bool) string {
 	var b strings.Builder
 
 	// YAML frontmatter
@@ -98,21 +117,25 @@ func buildIndex(plan WhitePaperPlan, coherence CoherenceEditResult, chapterFiles
 
 	fmt.Fprintf(&b, "# %s\n\n", plan.Title)
 
-	b.WriteString("## Table of contents\n\n")
+	if incomplete {
+		fmt.Fprintf(&b, "> %s\n\n", l.T(locale.IncompleteWhitePaper, len(chapterFiles), len(plan.allChapters())))
+	}
+
+	fmt.Fprintf(&b, "## %s\n\n", l.T(locale.TableOfContents))
 
 	// Table of contents directive
 	b.WriteString(":toc{minLevel=\"2\", maxLevel=\"3\"}\n\n")
 
 	// Abstract
 	if coherence.Abstract != "" {
-		b.WriteString("## Abstract\n\n")
+		fmt.Fprintf(&b, "## %s\n\n", l.T(locale.Abstract))
 		b.WriteString(coherence.Abstract)
 		b.WriteString("\n\n")
 	}
 
 	// Executive Summary
 	if coherence.ExecutiveSummary != "" {
-		b.WriteString("## Executive Summary\n\n")
+		fmt.Fprintf(&b, "## %s\n\n", l.T(locale.ExecutiveSummary))
 		b.WriteString(coherence.ExecutiveSummary)
 		b.WriteString("\n\n")
 	}
@@ -135,13 +158,13 @@ func buildIndex(plan WhitePaperPlan, coherence CoherenceEditResult, chapterFiles
 	return b.String()
 }
 
-func buildBibliography(entries []BibEntry) string {
+func buildBibliography(l locale.Locale, entries []BibEntry) string {
 	if len(entries) == 0 {
-		return "# Bibliography\n\nNo sources available.\n"
+		return fmt.Sprintf("# %s\n\n%s\n", l.T(locale.Bibliography), l.T(locale.NoSources))
 	}
 
 	var b strings.Builder
-	b.WriteString("# Bibliography\n\n")
+	fmt.Fprintf(&b, "# %s\n\n", l.T(locale.Bibliography))
 
 	for i, e := range entries {
 		title := e.Title
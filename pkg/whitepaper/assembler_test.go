@@ -0,0 +1,70 @@
+package whitepaper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bornholm/ghostwriter/pkg/locale"
+)
+
+func TestBuildIndexHeadings(t *testing.T) {
+	plan := WhitePaperPlan{Title: "Souveraineté numérique"}
+	coherence := CoherenceEditResult{Abstract: "abstract", ExecutiveSummary: "summary"}
+
+	cases := map[locale.Locale][]string{
+		locale.English: {"## Table of contents\n", "## Abstract\n", "## Executive Summary\n"},
+		locale.French:  {"## Table des matières\n", "## Résumé\n", "## Synthèse\n"},
+	}
+
+	for l, headings := range cases {
+		index := buildIndex(l, plan, coherence, nil, 0, false)
+		for _, heading := range headings {
+			if !strings.Contains(index, heading) {
+				t.Errorf("%s: expected index to contain %q, got:\n%s", l, heading, index)
+			}
+		}
+	}
+}
+
+func TestAssembleIncomplete(t *testing.T) {
+	plan := WhitePaperPlan{
+		Title: "Interstellar objects",
+		Parts: []*Part{{Chapters: []*Chapter{
+			{ID: "ch1", Number: 1, Title: "Detection"},
+			{ID: "ch2", Number: 2, Title: "Origins"},
+		}}},
+	}
+	chapters := []ChapterContent{{ChapterID: "ch1", Number: 1, Title: "Detection", Content: "..."}}
+
+	dir := t.TempDir()
+	if _, err := Assemble(plan, chapters, CoherenceEditResult{}, AssembleOptions{OutputDir: dir, Locale: locale.English, Incomplete: true}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(index), "**Incomplete white paper**") || !strings.Contains(string(index), "1 of 2 chapters") {
+		t.Errorf("expected index to be flagged as incomplete, got:\n%s", index)
+	}
+
+	complete := buildIndex(locale.English, plan, CoherenceEditResult{}, nil, 0, false)
+	if strings.Contains(complete, "Incomplete") {
+		t.Errorf("expected no incomplete marker, got:\n%s", complete)
+	}
+}
+
+func TestBuildBibliographyHeadings(t *testing.T) {
+	if got := buildBibliography(locale.French, nil); got != "# Bibliographie\n\nAucune source disponible.\n" {
+		t.Errorf("unexpected empty bibliography: %q", got)
+	}
+
+	got := buildBibliography(locale.English, []BibEntry{{URL: "https://example.com", Title: "Example"}})
+	if !strings.HasPrefix(got, "# Bibliography\n\n1. [Example](https://example.com)\n") {
+		t.Errorf("unexpected bibliography: %q", got)
+	}
+}
@@ -0,0 +1,298 @@
+package whitepaper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/pkg/errors"
+)
+
+const (
+	checkpointFile    = "checkpoint.json"
+	checkpointVersion = 1
+)
+
+// ErrIncompatibleCheckpoint is returned when resuming from a checkpoint
+// produced with different options or model.
+var ErrIncompatibleCheckpoint = errors.New("incompatible checkpoint")
+
+// CheckpointFingerprint identifies the options and model that produced a
+// checkpoint. Free-form inputs are stored as hashes.
+type CheckpointFingerprint struct {
+	Subject           string                `json:"subject"`
+	Model             string                `json:"model,omitempty"`
+	TargetWordCount   int                   `json:"target_word_count"`
+	ResearchDepth     article.ResearchDepth `json:"research_depth"`
+	MaxReviewRounds   int                   `json:"max_review_rounds"`
+	Parts             bool                  `json:"parts,omitempty"`
+	Locale            locale.Locale         `json:"locale,omitempty"`
+	StyleGuidelines   string                `json:"style_guidelines,omitempty"`
+	AdditionalContext string                `json:"additional_context,omitempty"`
+	Plan              string                `json:"plan,omitempty"`
+}
+
+// diff returns the names of the fields that differ between two fingerprints.
+func (f CheckpointFingerprint) diff(other CheckpointFingerprint) []string {
+	var fields []string
+	if f.Subject != other.Subject {
+		fields = append(fields, "subject")
+	}
+	if f.Model != other.Model {
+		fields = append(fields, fmt.Sprintf("model (%q ≠ %q)", f.Model, other.Model))
+	}
+	if f.TargetWordCount != other.TargetWordCount {
+		fields = append(fields, fmt.Sprintf("target word count (%d ≠ %d)", f.TargetWordCount, other.TargetWordCount))
+	}
+	if f.ResearchDepth != other.ResearchDepth {
+		fields = append(fields, fmt.Sprintf("research depth (%q ≠ %q)", f.ResearchDepth, other.ResearchDepth))
+	}
+	if f.MaxReviewRounds != other.MaxReviewRounds {
+		fields = append(fields, fmt.Sprintf("max review rounds (%d ≠ %d)", f.MaxReviewRounds, other.MaxReviewRounds))
+	}
+	if f.Parts != other.Parts {
+		fields = append(fields, fmt.Sprintf("parts (%t ≠ %t)", f.Parts, other.Parts))
+	}
+	if f.Locale != other.Locale {
+		fields = append(fields, fmt.Sprintf("locale (%q ≠ %q)", f.Locale, other.Locale))
+	}
+	if f.StyleGuidelines != other.StyleGuidelines {
+		fields = append(fields, "style guidelines")
+	}
+	if f.AdditionalContext != other.AdditionalContext {
+		fields = append(fields, "additional context")
+	}
+	if f.Plan != other.Plan {
+		fields = append(fields, "plan")
+	}
+	return fields
+}
+
+func newCheckpointFingerprint(subject string, opts *OrchestratorOptions) CheckpointFingerprint {
+	fp := CheckpointFingerprint{
+		Subject:           subject,
+		Model:             opts.ModelName,
+		TargetWordCount:   opts.TargetWordCount,
+		ResearchDepth:     opts.ResearchDepth,
+		MaxReviewRounds:   opts.MaxReviewRounds,
+		Parts:             opts.Parts,
+		Locale:            opts.Locale,
+		StyleGuidelines:   hashString(opts.StyleGuidelines),
+		AdditionalContext: hashString(opts.AdditionalContext),
+	}
+	if opts.Plan != nil {
+		data, _ := json.Marshal(opts.Plan)
+		fp.Plan = hashString(string(data))
+	}
+	return fp
+}
+
+func hashString(s string) string {
+	if s == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChapterCheckpoint records the progress of a single chapter.
+type ChapterCheckpoint struct {
+	// Content is the latest version of the chapter: the writer output when
+	// Rounds is 0, the output of the last completed editing round otherwise.
+	Content ChapterContent `json:"content"`
+	Rounds  int            `json:"rounds"`
+	Done    bool           `json:"done"`
+}
+
+// Checkpoint is the persisted state of a white paper run, saved in the
+// output directory after each phase and chapter.
+type Checkpoint struct {
+	Version      int                           `json:"version"`
+	Fingerprint  CheckpointFingerprint         `json:"fingerprint"`
+	UpdatedAt    time.Time                     `json:"updated_at"`
+	ResearchDone bool                          `json:"research_done"`
+	Plan         *WhitePaperPlan               `json:"plan,omitempty"`
+	Chapters     map[string]*ChapterCheckpoint `json:"chapters"`
+	Coherence    *CoherenceEditResult          `json:"coherence,omitempty"`
+	Enriched     map[string]ChapterContent     `json:"enriched"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// OpenCheckpoint prepares the checkpoint of the run writing to dir. When
+// resume is true and a checkpoint exists, it is loaded and must have been
+// produced with the same fingerprint; otherwise a fresh checkpoint is started.
+func OpenCheckpoint(dir string, fp CheckpointFingerprint, resume bool) (*Checkpoint, error) {
+	cp := &Checkpoint{
+		Version:     checkpointVersion,
+		Fingerprint: fp,
+		Chapters:    make(map[string]*ChapterCheckpoint),
+		Enriched:    make(map[string]ChapterContent),
+		path:        filepath.Join(dir, checkpointFile),
+	}
+
+	if !resume {
+		return cp, nil
+	}
+
+	data, err := os.ReadFile(cp.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cp, nil
+		}
+		return nil, errors.Wrap(err, "could not read checkpoint")
+	}
+
+	var stored Checkpoint
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, errors.Wrapf(err, "could not parse checkpoint %q", cp.path)
+	}
+
+	if stored.Version != checkpointVersion {
+		return nil, errors.Wrapf(ErrIncompatibleCheckpoint, "checkpoint %q has version %d, expected %d", cp.path, stored.Version, checkpointVersion)
+	}
+
+	if diff := stored.Fingerprint.diff(fp); len(diff) > 0 {
+		return nil, errors.Wrapf(ErrIncompatibleCheckpoint, "checkpoint %q was produced with different settings: %v", cp.path, diff)
+	}
+
+	cp.ResearchDone = stored.ResearchDone
+	cp.Plan = stored.Plan
+	cp.Coherence = stored.Coherence
+	if stored.Chapters != nil {
+		cp.Chapters = stored.Chapters
+	}
+	if stored.Enriched != nil {
+		cp.Enriched = stored.Enriched
+	}
+
+	return cp, nil
+}
+
+// Remove deletes the checkpoint file once the run is complete.
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (c *Checkpoint) isResearchDone() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ResearchDone
+}
+
+func (c *Checkpoint) markResearchDone() {
+	c.update(func() { c.ResearchDone = true })
+}
+
+func (c *Checkpoint) plan() *WhitePaperPlan {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Plan
+}
+
+func (c *Checkpoint) setPlan(plan WhitePaperPlan) {
+	c.update(func() { c.Plan = &plan })
+}
+
+func (c *Checkpoint) chapter(id string) (ChapterCheckpoint, bool) {
+	if c == nil {
+		return ChapterCheckpoint{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch, ok := c.Chapters[id]
+	if !ok {
+		return ChapterCheckpoint{}, false
+	}
+	return *ch, true
+}
+
+func (c *Checkpoint) setChapter(id string, content ChapterContent, rounds int, done bool) {
+	c.update(func() {
+		c.Chapters[id] = &ChapterCheckpoint{Content: content, Rounds: rounds, Done: done}
+	})
+}
+
+func (c *Checkpoint) coherence() *CoherenceEditResult {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Coherence
+}
+
+func (c *Checkpoint) setCoherence(result CoherenceEditResult) {
+	c.update(func() { c.Coherence = &result })
+}
+
+func (c *Checkpoint) enriched(id string) (ChapterContent, bool) {
+	if c == nil {
+		return ChapterContent{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	content, ok := c.Enriched[id]
+	return content, ok
+}
+
+func (c *Checkpoint) setEnriched(id string, content ChapterContent) {
+	c.update(func() { c.Enriched[id] = content })
+}
+
+// update applies fn and persists the checkpoint. Persistence failures are
+// logged rather than returned: losing a checkpoint must not abort the run.
+func (c *Checkpoint) update(fn func()) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn()
+	c.UpdatedAt = time.Now()
+
+	if err := c.save(); err != nil {
+		slog.Warn("could not save checkpoint", slog.String("path", c.path), slog.Any("error", err))
+	}
+}
+
+func (c *Checkpoint) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmp, c.path))
+}
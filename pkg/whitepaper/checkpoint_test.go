@@ -0,0 +1,133 @@
+package whitepaper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/pkg/errors"
+)
+
+func TestCheckpoint(t *testing.T) {
+	fp := CheckpointFingerprint{Subject: "subject", Model: "openai/gpt", TargetWordCount: 1000, MaxReviewRounds: 2}
+
+	t.Run("resume restores progress", func(t *testing.T) {
+		dir := t.TempDir()
+
+		cp, err := OpenCheckpoint(dir, fp, false)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		cp.markResearchDone()
+		cp.setPlan(WhitePaperPlan{Title: "Plan"})
+		cp.setChapter("intro", ChapterContent{ChapterID: "intro", Content: "draft"}, 1, false)
+		cp.setCoherence(CoherenceEditResult{Abstract: "abstract"})
+		cp.setEnriched("intro", ChapterContent{ChapterID: "intro", Content: "enriched"})
+
+		resumed, err := OpenCheckpoint(dir, fp, true)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if !resumed.isResearchDone() {
+			t.Error("expected research to be done")
+		}
+		if p := resumed.plan(); p == nil || p.Title != "Plan" {
+			t.Errorf("unexpected plan: %+v", p)
+		}
+		if ch, ok := resumed.chapter("intro"); !ok || ch.Rounds != 1 || ch.Done || ch.Content.Content != "draft" {
+			t.Errorf("unexpected chapter checkpoint: %+v", ch)
+		}
+		if c := resumed.coherence(); c == nil || c.Abstract != "abstract" {
+			t.Errorf("unexpected coherence: %+v", c)
+		}
+		if e, ok := resumed.enriched("intro"); !ok || e.Content != "enriched" {
+			t.Errorf("unexpected enriched chapter: %+v", e)
+		}
+
+		if err := resumed.Remove(); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, checkpointFile)); !os.IsNotExist(err) {
+			t.Error("expected checkpoint file to be removed")
+		}
+	})
+
+	t.Run("fresh run ignores existing checkpoint", func(t *testing.T) {
+		dir := t.TempDir()
+		cp, _ := OpenCheckpoint(dir, fp, false)
+		cp.markResearchDone()
+
+		fresh, err := OpenCheckpoint(dir, fp, false)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if fresh.isResearchDone() {
+			t.Error("expected a fresh checkpoint")
+		}
+	})
+
+	t.Run("incompatible fingerprint is refused", func(t *testing.T) {
+		dir := t.TempDir()
+		cp, _ := OpenCheckpoint(dir, fp, false)
+		cp.markResearchDone()
+
+		other := fp
+		other.Model = "mistral/large"
+		if _, err := OpenCheckpoint(dir, other, true); !errors.Is(err, ErrIncompatibleCheckpoint) {
+			t.Errorf("expected ErrIncompatibleCheckpoint, got: %v", err)
+		}
+	})
+
+	t.Run("parts option is part of the fingerprint", func(t *testing.T) {
+		dir := t.TempDir()
+
+		flat := newCheckpointFingerprint("subject", NewOrchestratorOptions())
+		cp, _ := OpenCheckpoint(dir, flat, false)
+		cp.markResearchDone()
+
+		parts := newCheckpointFingerprint("subject", NewOrchestratorOptions(WithParts(true)))
+		if !parts.Parts {
+			t.Fatal("expected the fingerprint to record the parts option")
+		}
+
+		_, err := OpenCheckpoint(dir, parts, true)
+		if !errors.Is(err, ErrIncompatibleCheckpoint) {
+			t.Fatalf("expected ErrIncompatibleCheckpoint, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "parts (false ≠ true)") {
+			t.Errorf("expected the error to name the parts option, got: %v", err)
+		}
+	})
+
+	t.Run("locale is part of the fingerprint", func(t *testing.T) {
+		dir := t.TempDir()
+
+		english := newCheckpointFingerprint("subject", NewOrchestratorOptions(WithLocale(locale.English)))
+		cp, _ := OpenCheckpoint(dir, english, false)
+		cp.markResearchDone()
+
+		french := newCheckpointFingerprint("subject", NewOrchestratorOptions(WithLocale(locale.French)))
+
+		_, err := OpenCheckpoint(dir, french, true)
+		if !errors.Is(err, ErrIncompatibleCheckpoint) {
+			t.Fatalf("expected ErrIncompatibleCheckpoint, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), `locale ("en" ≠ "fr")`) {
+			t.Errorf("expected the error to name the locale, got: %v", err)
+		}
+	})
+
+	t.Run("nil checkpoint is a no-op", func(t *testing.T) {
+		var cp *Checkpoint
+		cp.markResearchDone()
+		cp.setChapter("intro", ChapterContent{}, 1, true)
+		if _, ok := cp.chapter("intro"); ok {
+			t.Error("expected no chapter on nil checkpoint")
+		}
+		if err := cp.Remove(); err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/bornholm/genai/agent"
@@ -146,6 +147,36 @@ func extractSources(ctx context.Context) []article.Source {
 	return sources
 }
 
+// collectSources returns the sources of the knowledge base, the most relevant
+// first, and fills the bibliography with them when the coherence pass did not
+// produce any.
+func collectSources(ctx context.Context, coherence *CoherenceEditResult) []article.Source {
+	sources := extractSources(ctx)
+	slices.SortStableFunc(sources, func(a, b article.Source) int {
+		if a.Relevance > b.Relevance {
+			return -1
+		}
+		if a.Relevance < b.Relevance {
+			return 1
+		}
+		return 0
+	})
+
+	if len(coherence.Bibliography) == 0 {
+		for _, s := range sources {
+			if s.URL != "" {
+				coherence.Bibliography = append(coherence.Bibliography, BibEntry{
+					URL:        s.URL,
+					Title:      s.Title,
+					SourceType: s.SourceType,
+				})
+			}
+		}
+	}
+
+	return sources
+}
+
 func NewCoherenceEditorHandler(client llm.ChatCompletionClient) *CoherenceEditorHandler {
 	return &CoherenceEditorHandler{client: client}
 }
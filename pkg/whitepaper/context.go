@@ -5,6 +5,8 @@ import (
 
 	"github.com/bornholm/genai/agent"
 	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
 )
 
 const (
@@ -18,8 +20,13 @@ const (
 	ctxKeyPlan             agent.ContextKey = "whitepaper_plan"
 	ctxKeyChapter          agent.ContextKey = "whitepaper_chapter"
 	ctxKeyPreviousChapter  agent.ContextKey = "whitepaper_previous_chapter"
+	ctxKeyNextChapter      agent.ContextKey = "whitepaper_next_chapter"
+	ctxKeyInstructions     agent.ContextKey = "whitepaper_instructions"
 	ctxKeyAllChapters      agent.ContextKey = "whitepaper_all_chapters"
 	ctxKeyAnnotations      agent.ContextKey = "whitepaper_annotations"
+	ctxKeyCheckpoint       agent.ContextKey = "whitepaper_checkpoint"
+	ctxKeyLocale           agent.ContextKey = "whitepaper_locale"
+	ctxKeyParts            agent.ContextKey = "whitepaper_parts"
 )
 
 func withCtxSubject(ctx context.Context, subject string) context.Context {
@@ -62,6 +69,15 @@ func ctxAdditionalContext(ctx context.Context) string {
 	return agent.ContextValue(ctx, ctxKeyAdditionalCtx, "")
 }
 
+func withCtxParts(ctx context.Context, parts bool) context.Context {
+	return context.WithValue(ctx, ctxKeyParts, parts)
+}
+
+// ctxParts returns true when the chapters of the plan are grouped into parts.
+func ctxParts(ctx context.Context) bool {
+	return agent.ContextValue(ctx, ctxKeyParts, false)
+}
+
 func withCtxKnowledgeBase(ctx context.Context, kb article.KnowledgeBase) context.Context {
 	return context.WithValue(ctx, ctxKeyKnowledgeBase, kb)
 }
@@ -89,7 +105,10 @@ func ctxPlan(ctx context.Context) (WhitePaperPlan, bool) {
 	return plan, ok
 }
 
+// withCtxChapter also tags the LLM calls made with ctx with the chapter number,
+// for the usage accounting.
 func withCtxChapter(ctx context.Context, ch *Chapter) context.Context {
+	ctx = usage.WithContextChapter(ctx, int(ch.Number))
 	return context.WithValue(ctx, ctxKeyChapter, ch)
 }
 
@@ -107,6 +126,24 @@ func ctxPreviousChapter(ctx context.Context) *ChapterContent {
 	return cc
 }
 
+func withCtxNextChapter(ctx context.Context, cc *ChapterContent) context.Context {
+	return context.WithValue(ctx, ctxKeyNextChapter, cc)
+}
+
+func ctxNextChapter(ctx context.Context) *ChapterContent {
+	cc, _ := ctx.Value(ctxKeyNextChapter).(*ChapterContent)
+	return cc
+}
+
+func withCtxInstructions(ctx context.Context, instructions string) context.Context {
+	return context.WithValue(ctx, ctxKeyInstructions, instructions)
+}
+
+func ctxInstructions(ctx context.Context) string {
+	instructions, _ := ctx.Value(ctxKeyInstructions).(string)
+	return instructions
+}
+
 func withCtxAllChapters(ctx context.Context, ccs []ChapterContent) context.Context {
 	return context.WithValue(ctx, ctxKeyAllChapters, ccs)
 }
@@ -124,3 +161,23 @@ func ctxAnnotations(ctx context.Context) []string {
 	annotations, _ := ctx.Value(ctxKeyAnnotations).([]string)
 	return annotations
 }
+
+func withCtxCheckpoint(ctx context.Context, cp *Checkpoint) context.Context {
+	return context.WithValue(ctx, ctxKeyCheckpoint, cp)
+}
+
+// ctxCheckpoint returns the run checkpoint, or nil when checkpointing is disabled.
+// All Checkpoint methods are no-ops on a nil receiver.
+func ctxCheckpoint(ctx context.Context) *Checkpoint {
+	cp, _ := ctx.Value(ctxKeyCheckpoint).(*Checkpoint)
+	return cp
+}
+
+func withCtxLocale(ctx context.Context, l locale.Locale) context.Context {
+	return context.WithValue(ctx, ctxKeyLocale, l)
+}
+
+// ctxLocale returns the locale of the progress messages and document headings.
+func ctxLocale(ctx context.Context) locale.Locale {
+	return agent.ContextValue(ctx, ctxKeyLocale, locale.Default)
+}
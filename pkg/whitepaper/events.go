@@ -13,23 +13,23 @@ const (
 
 // PhaseData carries information about a pipeline phase transition.
 type PhaseData struct {
-	Name string
-	Done bool
-	Info string // optional extra info for done events
+	Name string `json:"name"`
+	Done bool   `json:"done"`
+	Info string `json:"info,omitempty"` // optional extra info for done events
 }
 
 // ChapterStartData is emitted when writing begins for a chapter.
 type ChapterStartData struct {
-	Number int
-	Total  int
-	Title  string
-	Target int // target word count
+	Number int    `json:"number"`
+	Total  int    `json:"total"`
+	Title  string `json:"title"`
+	Target int    `json:"target"` // target word count
 }
 
 // ChapterDoneData is emitted when a chapter is fully written and edited.
 type ChapterDoneData struct {
-	Number    int
-	Total     int
-	Title     string
-	WordCount int
+	Number    int    `json:"number"`
+	Total     int    `json:"total"`
+	Title     string `json:"title"`
+	WordCount int    `json:"word_count"`
 }
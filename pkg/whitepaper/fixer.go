@@ -2,12 +2,15 @@ package whitepaper
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 var (
@@ -87,6 +90,20 @@ func ParseAnnotatedFile(filePath string) (AnnotatedFile, error) {
 	}, nil
 }
 
+// loadPlanFromDir reads the plan.json saved by Assemble in a whitepaper
+// output directory.
+func loadPlanFromDir(dir string) (WhitePaperPlan, error) {
+	planData, err := os.ReadFile(filepath.Join(dir, "plan.json"))
+	if err != nil {
+		return WhitePaperPlan{}, errors.Wrap(err, "could not read plan.json — was the whitepaper generated with a recent version of ghostwriter?")
+	}
+	var plan WhitePaperPlan
+	if err := json.Unmarshal(planData, &plan); err != nil {
+		return WhitePaperPlan{}, errors.Wrap(err, "could not parse plan.json")
+	}
+	return plan, nil
+}
+
 // loadChaptersFromDir reads all chapter-*.md files from a directory,
 // strips any > EDITOR: annotations, and returns ChapterContent sorted by number.
 func loadChaptersFromDir(dir string) ([]ChapterContent, error) {
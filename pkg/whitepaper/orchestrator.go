@@ -4,16 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"slices"
 	"strings"
 
 	"github.com/bornholm/genai/agent"
 	"github.com/bornholm/genai/llm"
 	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/scraper"
 	"github.com/bornholm/ghostwriter/pkg/scraper/surf"
+	"github.com/bornholm/ghostwriter/pkg/search"
 	"github.com/bornholm/ghostwriter/pkg/search/duckduckgo"
+	"github.com/bornholm/ghostwriter/pkg/usage"
 	"github.com/pkg/errors"
 )
 
@@ -32,6 +36,14 @@ type OrchestratorOptions struct {
 	KnowledgeBase     article.KnowledgeBase
 	Tools             []llm.Tool
 	MaxReviewRounds   int
+	Parts             bool // group the chapters of the generated plan into parts
+	SkipResearch      bool
+	Plan              *WhitePaperPlan // pre-validated plan, skips the planning phase
+	Resume            bool            // resume from the checkpoint found in OutputDir
+	ModelName         string          // recorded in checkpoints to refuse incompatible resumes
+	SearchClient      search.Client   // defaults to DuckDuckGo
+	Scraper           scraper.Scraper // defaults to surf
+	Locale            locale.Locale   // progress messages and document headings
 }
 
 // OrchestratorOptionFunc configures OrchestratorOptions.
@@ -43,6 +55,7 @@ func NewOrchestratorOptions(fns ...OrchestratorOptionFunc) *OrchestratorOptions
 		ResearchDepth:   article.ResearchDeep,
 		Tools:           make([]llm.Tool, 0),
 		MaxReviewRounds: 2,
+		Locale:          locale.Default,
 	}
 	for _, fn := range fns {
 		fn(opts)
@@ -104,6 +117,49 @@ func WithMaxReviewRounds(n int) OrchestratorOptionFunc {
 	}
 }
 
+// WithParts groups the chapters of the generated plan into parts.
+func WithParts(v bool) OrchestratorOptionFunc {
+	return func(o *OrchestratorOptions) { o.Parts = v }
+}
+
+// WithPlan uses the given plan instead of generating one. The plan is
+// expected to have been validated, e.g. with LoadPlan.
+func WithPlan(plan *WhitePaperPlan) OrchestratorOptionFunc {
+	return func(o *OrchestratorOptions) { o.Plan = plan }
+}
+
+// WithResume resumes the run from the checkpoint saved in the output directory.
+// The checkpoint must have been produced with the same options and model.
+func WithResume(v bool) OrchestratorOptionFunc {
+	return func(o *OrchestratorOptions) { o.Resume = v }
+}
+
+// WithModelName records the name of the model in checkpoints.
+func WithModelName(name string) OrchestratorOptionFunc {
+	return func(o *OrchestratorOptions) { o.ModelName = name }
+}
+
+// WithSkipResearch skips the research phase and relies on an already populated knowledge base.
+func WithSkipResearch(v bool) OrchestratorOptionFunc {
+	return func(o *OrchestratorOptions) { o.SkipResearch = v }
+}
+
+// WithSearchClient sets the search engine used by the research phase.
+func WithSearchClient(client search.Client) OrchestratorOptionFunc {
+	return func(o *OrchestratorOptions) { o.SearchClient = client }
+}
+
+// WithScraper sets the scraper used to fetch web pages.
+func WithScraper(s scraper.Scraper) OrchestratorOptionFunc {
+	return func(o *OrchestratorOptions) { o.Scraper = s }
+}
+
+// WithLocale sets the language of the progress messages and of the headings
+// of the generated document.
+func WithLocale(l locale.Locale) OrchestratorOptionFunc {
+	return func(o *OrchestratorOptions) { o.Locale = l }
+}
+
 // Orchestrator coordinates the white paper writing pipeline.
 type Orchestrator struct {
 	researcher      *article.ResearchAgent
@@ -119,89 +175,93 @@ type Orchestrator struct {
 func (o *Orchestrator) WriteWhitePaper(ctx context.Context, subject string, emit agent.EmitFunc, optFuncs ...OrchestratorOptionFunc) (WhitePaper, error) {
 	opts := NewOrchestratorOptions(optFuncs...)
 
-	kb := opts.KnowledgeBase
-	if kb == nil {
-		var err error
-		kb, err = article.NewKnowledgeBase()
-		if err != nil {
-			return WhitePaper{}, errors.WithStack(err)
-		}
+	ctx, kb, err := o.prepareContext(ctx, subject, opts)
+	if err != nil {
+		return WhitePaper{}, errors.WithStack(err)
 	}
 
-	// Build base context
-	ctx = withCtxSubject(ctx, subject)
-	ctx = withCtxTargetWordCount(ctx, opts.TargetWordCount)
-	ctx = withCtxResearchDepth(ctx, opts.ResearchDepth)
-	if opts.StyleGuidelines != "" {
-		ctx = withCtxStyleGuidelines(ctx, opts.StyleGuidelines)
-	}
-	if opts.AdditionalContext != "" {
-		ctx = withCtxAdditionalContext(ctx, opts.AdditionalContext)
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = "."
 	}
-	ctx = withCtxKnowledgeBase(ctx, kb)
 
-	searcher := NewKnowledgeBaseAdapter(kb)
-	ctx = withCtxSearcher(ctx, searcher)
+	checkpoint, err := OpenCheckpoint(outputDir, newCheckpointFingerprint(subject, opts), opts.Resume)
+	if err != nil {
+		return WhitePaper{}, errors.WithStack(err)
+	}
+	ctx = withCtxCheckpoint(ctx, checkpoint)
 
 	// Step 1: Research
-	if err := o.conductResearch(ctx, subject, opts.ResearchDepth, kb, emit); err != nil {
-		return WhitePaper{}, errors.Wrap(err, "research phase failed")
+	if err := o.researchPhase(ctx, subject, opts, kb, emit); err != nil {
+		return WhitePaper{}, errors.WithStack(err)
 	}
 
 	// Step 2: Plan
-	plan, err := o.generatePlan(ctx, subject, opts.TargetWordCount, emit)
-	if err != nil {
-		return WhitePaper{}, errors.Wrap(err, "planning phase failed")
+	var plan WhitePaperPlan
+	if opts.Plan != nil {
+		plan = *opts.Plan
+		_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
+			Name: ctxLocale(ctx).T(locale.PhasePlanning),
+			Done: true,
+			Info: ctxLocale(ctx).T(locale.PlanProvided, plan.Title, len(plan.allChapters()), plan.TotalWords),
+		}))
+	} else if saved := checkpoint.plan(); saved != nil {
+		plan = *saved
+		_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
+			Name: ctxLocale(ctx).T(locale.PhasePlanning),
+			Done: true,
+			Info: ctxLocale(ctx).T(locale.PlanResumed, plan.Title, len(plan.allChapters()), plan.TotalWords),
+		}))
+	} else {
+		plan, err = o.generatePlan(ctx, subject, opts.TargetWordCount, emit)
+		if err != nil {
+			return WhitePaper{}, errors.Wrap(err, "planning phase failed")
+		}
 	}
+	checkpoint.setPlan(plan)
 	ctx = withCtxPlan(ctx, plan)
 
+	// When the budget of the run is used up, the remaining steps are skipped
+	// and what exists is assembled as an incomplete white paper.
+	var budgetErr error
+
 	// Step 3: Write + edit each chapter
 	chapters, err := o.writeAndEditChapters(ctx, plan, opts.MaxReviewRounds, emit)
-	if err != nil {
+	if errors.Is(err, usage.ErrBudgetExceeded) {
+		budgetErr = err
+	} else if err != nil {
 		return WhitePaper{}, errors.Wrap(err, "writing phase failed")
 	}
 
 	// Step 4: Coherence pass
-	coherence, err := o.coherencePass(ctx, plan, chapters, emit)
-	if err != nil {
-		return WhitePaper{}, errors.Wrap(err, "coherence phase failed")
+	var coherence CoherenceEditResult
+	if budgetErr == nil {
+		coherence, err = o.coherencePass(ctx, plan, chapters, emit)
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			budgetErr = err
+			coherence = CoherenceEditResult{}
+		} else if err != nil {
+			return WhitePaper{}, errors.Wrap(err, "coherence phase failed")
+		}
 	}
 
 	// Step 4b: Enrichment pass (citation linking + Mermaid diagrams)
-	chapters, err = o.enrichChapters(ctx, chapters, emit)
-	if err != nil {
-		return WhitePaper{}, errors.Wrap(err, "enrichment phase failed")
-	}
-
-	// Step 5: Collect sources
-	sources := extractSources(ctx)
-	slices.SortFunc(sources, func(a, b article.Source) int {
-		if a.Relevance > b.Relevance {
-			return -1
-		}
-		if a.Relevance < b.Relevance {
-			return 1
-		}
-		return 0
-	})
-	// Merge sources into bibliography if coherence didn't produce any
-	if len(coherence.Bibliography) == 0 {
-		for _, s := range sources {
-			if s.URL != "" {
-				coherence.Bibliography = append(coherence.Bibliography, BibEntry{
-					URL:        s.URL,
-					Title:      s.Title,
-					SourceType: s.SourceType,
-				})
-			}
+	if budgetErr == nil {
+		enriched, err := o.enrichChapters(ctx, chapters, emit)
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			budgetErr = err
+		} else if err != nil {
+			return WhitePaper{}, errors.Wrap(err, "enrichment phase failed")
+		} else {
+			chapters = enriched
 		}
 	}
 
+	// Step 5: Collect sources, merged into bibliography if coherence didn't produce any
+	sources := collectSources(ctx, &coherence)
+
 	// Step 6: Assemble files
-	assembleOpts := AssembleOptions{OutputDir: opts.OutputDir}
-	if assembleOpts.OutputDir == "" {
-		assembleOpts.OutputDir = "."
-	}
+	assembleOpts := AssembleOptions{OutputDir: outputDir, Locale: opts.Locale, Incomplete: budgetErr != nil}
 
 	whitePaper, err := Assemble(plan, chapters, coherence, assembleOpts)
 	if err != nil {
@@ -209,6 +269,17 @@ func (o *Orchestrator) WriteWhitePaper(ctx context.Context, subject string, emit
 	}
 	whitePaper.Metadata.Sources = sources
 
+	// The checkpoint is kept so that the run can be resumed with a higher budget.
+	if budgetErr != nil {
+		slog.WarnContext(ctx, "budget exceeded, white paper assembled as incomplete", slog.Int("chapters", len(chapters)), slog.Any("error", budgetErr))
+		return whitePaper, errors.Wrap(budgetErr, "white paper incomplete")
+	}
+
+	// The white paper is on disk: the checkpoint is no longer needed.
+	if err := checkpoint.Remove(); err != nil {
+		slog.WarnContext(ctx, "could not remove checkpoint", slog.Any("error", err))
+	}
+
 	// Step 7: Optional rendering
 	if opts.RenderHTML != "" {
 		if err := RenderWhitePaper(ctx, whitePaper.Entrypoint, RenderOptions{
@@ -235,8 +306,97 @@ func (o *Orchestrator) WriteWhitePaper(ctx context.Context, subject string, emit
 	return whitePaper, nil
 }
 
+// Plan runs the research and planning phases only and returns the generated
+// plan, so that it can be reviewed and edited before writing.
+func (o *Orchestrator) Plan(ctx context.Context, subject string, emit agent.EmitFunc, optFuncs ...OrchestratorOptionFunc) (WhitePaperPlan, error) {
+	opts := NewOrchestratorOptions(optFuncs...)
+
+	ctx, kb, err := o.prepareContext(ctx, subject, opts)
+	if err != nil {
+		return WhitePaperPlan{}, errors.WithStack(err)
+	}
+
+	if err := o.researchPhase(ctx, subject, opts, kb, emit); err != nil {
+		return WhitePaperPlan{}, errors.WithStack(err)
+	}
+
+	plan, err := o.generatePlan(ctx, subject, opts.TargetWordCount, emit)
+	if err != nil {
+		return WhitePaperPlan{}, errors.Wrap(err, "planning phase failed")
+	}
+
+	return plan, nil
+}
+
+// prepareContext builds the base context shared by all phases.
+func (o *Orchestrator) prepareContext(ctx context.Context, subject string, opts *OrchestratorOptions) (context.Context, article.KnowledgeBase, error) {
+	kb := opts.KnowledgeBase
+	if kb == nil {
+		var err error
+		kb, err = article.NewKnowledgeBase()
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+
+	ctx = withCtxSubject(ctx, subject)
+	ctx = withCtxLocale(ctx, opts.Locale)
+	ctx = withCtxTargetWordCount(ctx, opts.TargetWordCount)
+	ctx = withCtxResearchDepth(ctx, opts.ResearchDepth)
+	ctx = withCtxParts(ctx, opts.Parts)
+	if opts.StyleGuidelines != "" {
+		ctx = withCtxStyleGuidelines(ctx, opts.StyleGuidelines)
+	}
+	if opts.AdditionalContext != "" {
+		ctx = withCtxAdditionalContext(ctx, opts.AdditionalContext)
+	}
+	ctx = withCtxKnowledgeBase(ctx, kb)
+
+	searcher := NewKnowledgeBaseAdapter(kb)
+	ctx = withCtxSearcher(ctx, searcher)
+
+	return ctx, kb, nil
+}
+
+// researchPhase conducts the research unless it has been disabled.
+func (o *Orchestrator) researchPhase(ctx context.Context, subject string, opts *OrchestratorOptions, kb article.KnowledgeBase, emit agent.EmitFunc) error {
+	if opts.SkipResearch {
+		stats := kb.GetStats()
+		total, _ := stats["total_documents"].(int)
+		_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
+			Name: ctxLocale(ctx).T(locale.PhaseResearch),
+			Done: true,
+			Info: ctxLocale(ctx).T(locale.ResearchSkipped, total),
+		}))
+		return nil
+	}
+
+	// On resume, research is only skipped when its documents survived the
+	// previous run (i.e. with a persistent knowledge base).
+	checkpoint := ctxCheckpoint(ctx)
+	if checkpoint.isResearchDone() {
+		stats := kb.GetStats()
+		if total, _ := stats["total_documents"].(int); total > 0 {
+			_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
+				Name: ctxLocale(ctx).T(locale.PhaseResearch),
+				Done: true,
+				Info: ctxLocale(ctx).T(locale.ResearchResumed, total),
+			}))
+			return nil
+		}
+	}
+
+	if err := o.conductResearch(ctx, subject, opts.ResearchDepth, kb, emit); err != nil {
+		return errors.Wrap(err, "research phase failed")
+	}
+
+	checkpoint.markResearchDone()
+
+	return nil
+}
+
 func (o *Orchestrator) conductResearch(ctx context.Context, subject string, depth article.ResearchDepth, kb article.KnowledgeBase, emit agent.EmitFunc) error {
-	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: "Recherche"}))
+	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: ctxLocale(ctx).T(locale.PhaseResearch)}))
 
 	researchCtx := article.WithContextAgentRole(ctx, article.RoleResearcher)
 	researchCtx = article.WithContextSubject(researchCtx, subject)
@@ -270,18 +430,19 @@ func (o *Orchestrator) conductResearch(ctx context.Context, subject string, dept
 	stats := kb.GetStats()
 	total, _ := stats["total_documents"].(int)
 	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
-		Name: "Recherche",
+		Name: ctxLocale(ctx).T(locale.PhaseResearch),
 		Done: true,
-		Info: fmt.Sprintf("%d documents indexés", total),
+		Info: ctxLocale(ctx).T(locale.ResearchDone, total),
 	}))
 
 	return nil
 }
 
 func (o *Orchestrator) generatePlan(ctx context.Context, subject string, targetWordCount int, emit agent.EmitFunc) (WhitePaperPlan, error) {
-	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: "Planification"}))
+	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: ctxLocale(ctx).T(locale.PhasePlanning)}))
 
 	planCtx := withCtxTargetWordCount(ctx, targetWordCount)
+	planCtx = article.WithContextAgentRole(planCtx, article.RolePlanner)
 
 	var planJSON string
 	proxyEmit := func(evt agent.Event) error {
@@ -303,9 +464,9 @@ func (o *Orchestrator) generatePlan(ctx context.Context, subject string, targetW
 
 	chapters := plan.allChapters()
 	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
-		Name: "Planification",
+		Name: ctxLocale(ctx).T(locale.PhasePlanning),
 		Done: true,
-		Info: fmt.Sprintf("%q — %d chapitres, %d mots", plan.Title, len(chapters), plan.TotalWords),
+		Info: ctxLocale(ctx).T(locale.PlanSummary, plan.Title, len(chapters), plan.TotalWords),
 	}))
 
 	return plan, nil
@@ -315,10 +476,13 @@ func (o *Orchestrator) writeAndEditChapters(ctx context.Context, plan WhitePaper
 	chapters := plan.allChapters()
 	results := make([]ChapterContent, 0, len(chapters))
 
-	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: "Rédaction"}))
+	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: ctxLocale(ctx).T(locale.PhaseWriting)}))
 
 	var previousChapter *ChapterContent
 
+	checkpoint := ctxCheckpoint(ctx)
+	tracker := usage.ContextTracker(ctx)
+
 	for _, ch := range chapters {
 		select {
 		case <-ctx.Done():
@@ -326,6 +490,24 @@ func (o *Orchestrator) writeAndEditChapters(ctx context.Context, plan WhitePaper
 		default:
 		}
 
+		saved, hasSaved := checkpoint.chapter(ch.ID)
+		if hasSaved && saved.Done {
+			results = append(results, saved.Content)
+			previousChapter = &saved.Content
+			_ = emit(agent.NewEvent(EventTypeChapterDone, &ChapterDoneData{
+				Number:    saved.Content.Number,
+				Total:     len(chapters),
+				Title:     saved.Content.Title,
+				WordCount: saved.Content.WordCount,
+			}))
+			continue
+		}
+
+		// Do not start a chapter that the budget could not pay for.
+		if err := tracker.CheckBudgetFor(tracker.ChapterEstimate()); err != nil {
+			return results, errors.WithStack(err)
+		}
+
 		_ = emit(agent.NewEvent(EventTypeChapterStart, &ChapterStartData{
 			Number: int(ch.Number),
 			Total:  len(chapters),
@@ -333,65 +515,61 @@ func (o *Orchestrator) writeAndEditChapters(ctx context.Context, plan WhitePaper
 			Target: ch.WordCount,
 		}))
 
-		// Write — suppress LLM streaming text and JSON payload.
-		writeCtx := withCtxChapter(ctx, ch)
-		writeCtx = withCtxPreviousChapter(writeCtx, previousChapter)
+		var currentContent ChapterContent
+		startRound := 0
 
-		var writtenJSON string
-		writeEmit := func(evt agent.Event) error {
-			switch evt.Type() {
-			case agent.EventTypeTextDelta:
-				return nil // suppress LLM streaming content
-			case agent.EventTypeComplete:
-				writtenJSON = evt.Data().(*agent.CompleteData).Message
-				return nil // capture but suppress JSON payload
-			}
-			return emit(evt)
-		}
+		if hasSaved {
+			// Resume after the last completed step of this chapter.
+			currentContent = saved.Content
+			startRound = saved.Rounds
+		} else {
+			writeCtx := withCtxChapter(ctx, ch)
+			writeCtx = withCtxPreviousChapter(writeCtx, previousChapter)
 
-		if err := agent.NewRunner(o.chapterWriter).Run(writeCtx, agent.NewInput(ch.Title), writeEmit); err != nil {
-			return nil, errors.Wrapf(err, "could not write chapter %q", ch.Title)
-		}
+			written, err := o.runChapterWriter(writeCtx, ch, emit)
+			if errors.Is(err, usage.ErrBudgetExceeded) {
+				return results, errors.WithStack(err)
+			}
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			currentContent = written
 
-		var written ChapterContent
-		if err := json.Unmarshal([]byte(writtenJSON), &written); err != nil {
-			return nil, errors.Wrapf(err, "could not parse written chapter %q", ch.Title)
+			checkpoint.setChapter(ch.ID, currentContent, 0, false)
 		}
 
 		// Edit — repeat maxReviewRounds times; each round refines the previous output.
-		currentContent := written
-		for round := range maxReviewRounds {
+		var budgetErr error
+		for round := startRound; round < maxReviewRounds; round++ {
+			// A written chapter is kept with the rounds already done.
+			if budgetErr = tracker.CheckBudget(); budgetErr != nil {
+				break
+			}
+
 			editCtx := withCtxChapter(ctx, ch)
 			editCtx = withCtxPlan(editCtx, plan)
 			// Expose already-finished chapters so the editor can detect redundancies.
 			editCtx = withCtxAllChapters(editCtx, results)
 
-			currentJSON, err := json.Marshal(currentContent)
-			if err != nil {
-				return nil, errors.Wrapf(err, "could not serialize chapter %q for editing (round %d)", ch.Title, round+1)
+			edited, err := o.runChapterEditor(editCtx, ch, currentContent, round, emit)
+			if errors.Is(err, usage.ErrBudgetExceeded) {
+				budgetErr = err
+				break
 			}
-
-			var editedJSON string
-			editEmit := func(evt agent.Event) error {
-				switch evt.Type() {
-				case agent.EventTypeTextDelta:
-					return nil
-				case agent.EventTypeComplete:
-					editedJSON = evt.Data().(*agent.CompleteData).Message
-					return nil
-				}
-				return emit(evt)
+			if err != nil {
+				return nil, errors.WithStack(err)
 			}
+			currentContent = edited
 
-			if err = agent.NewRunner(o.chapterEditor).Run(editCtx, agent.NewInput(string(currentJSON)), editEmit); err != nil {
-				return nil, errors.Wrapf(err, "could not edit chapter %q (round %d)", ch.Title, round+1)
-			}
+			checkpoint.setChapter(ch.ID, currentContent, round+1, false)
+		}
 
-			if err = json.Unmarshal([]byte(editedJSON), &currentContent); err != nil {
-				return nil, errors.Wrapf(err, "could not parse edited chapter %q (round %d)", ch.Title, round+1)
-			}
+		if budgetErr != nil {
+			return append(results, currentContent), errors.WithStack(budgetErr)
 		}
 
+		checkpoint.setChapter(ch.ID, currentContent, maxReviewRounds, true)
+
 		results = append(results, currentContent)
 		previousChapter = &currentContent
 
@@ -406,21 +584,97 @@ func (o *Orchestrator) writeAndEditChapters(ctx context.Context, plan WhitePaper
 	return results, nil
 }
 
+// runChapterWriter writes a chapter with the writer agent. ctx must carry the
+// chapter and, optionally, its neighbours.
+func (o *Orchestrator) runChapterWriter(ctx context.Context, ch *Chapter, emit agent.EmitFunc) (ChapterContent, error) {
+	// Suppress LLM streaming text and JSON payload.
+	var writtenJSON string
+	writeEmit := func(evt agent.Event) error {
+		switch evt.Type() {
+		case agent.EventTypeTextDelta:
+			return nil // suppress LLM streaming content
+		case agent.EventTypeComplete:
+			writtenJSON = evt.Data().(*agent.CompleteData).Message
+			return nil // capture but suppress JSON payload
+		}
+		return emit(evt)
+	}
+
+	ctx = article.WithContextAgentRole(ctx, article.RoleWriter)
+
+	if err := agent.NewRunner(o.chapterWriter).Run(ctx, agent.NewInput(ch.Title), writeEmit); err != nil {
+		return ChapterContent{}, errors.Wrapf(err, "could not write chapter %q", ch.Title)
+	}
+
+	var content ChapterContent
+	if err := json.Unmarshal([]byte(writtenJSON), &content); err != nil {
+		return ChapterContent{}, errors.Wrapf(err, "could not parse written chapter %q", ch.Title)
+	}
+
+	return content, nil
+}
+
+// runChapterEditor runs one review round on the chapter content. ctx must
+// carry the chapter and the plan.
+func (o *Orchestrator) runChapterEditor(ctx context.Context, ch *Chapter, content ChapterContent, round int, emit agent.EmitFunc) (ChapterContent, error) {
+	currentJSON, err := json.Marshal(content)
+	if err != nil {
+		return ChapterContent{}, errors.Wrapf(err, "could not serialize chapter %q for editing (round %d)", ch.Title, round+1)
+	}
+
+	var editedJSON string
+	editEmit := func(evt agent.Event) error {
+		switch evt.Type() {
+		case agent.EventTypeTextDelta:
+			return nil
+		case agent.EventTypeComplete:
+			editedJSON = evt.Data().(*agent.CompleteData).Message
+			return nil
+		}
+		return emit(evt)
+	}
+
+	ctx = article.WithContextAgentRole(ctx, article.RoleEditor)
+
+	if err := agent.NewRunner(o.chapterEditor).Run(ctx, agent.NewInput(string(currentJSON)), editEmit); err != nil {
+		return ChapterContent{}, errors.Wrapf(err, "could not edit chapter %q (round %d)", ch.Title, round+1)
+	}
+
+	var edited ChapterContent
+	if err := json.Unmarshal([]byte(editedJSON), &edited); err != nil {
+		return ChapterContent{}, errors.Wrapf(err, "could not parse edited chapter %q (round %d)", ch.Title, round+1)
+	}
+
+	return edited, nil
+}
+
 func (o *Orchestrator) coherencePass(ctx context.Context, plan WhitePaperPlan, chapters []ChapterContent, emit agent.EmitFunc) (CoherenceEditResult, error) {
-	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: "Cohérence"}))
+	checkpoint := ctxCheckpoint(ctx)
+	if saved := checkpoint.coherence(); saved != nil {
+		_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
+			Name: ctxLocale(ctx).T(locale.PhaseCoherence),
+			Done: true,
+			Info: ctxLocale(ctx).T(locale.CoherenceResumed, len(saved.Bibliography), len(saved.Appendices)),
+		}))
+		return *saved, nil
+	}
+
+	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: ctxLocale(ctx).T(locale.PhaseCoherence)}))
 
 	emitInfo := func(msg string) {
 		_ = emit(agent.NewEvent(agent.EventTypeTextDelta, &agent.TextDeltaData{Delta: msg}))
 	}
 
-	emitInfo(fmt.Sprintf("  %d chapitres à analyser :\n", len(chapters)))
+	l := ctxLocale(ctx)
+	emitInfo("  " + l.T(locale.CoherenceChapters, len(chapters)) + "\n")
 	for _, ch := range chapters {
-		emitInfo(fmt.Sprintf("  — Chapitre %d : %s\n", ch.Number, ch.Title))
+		emitInfo("  " + l.T(locale.CoherenceChapter, ch.Number, ch.Title) + "\n")
 	}
-	emitInfo("  Génération de l'abstract, du résumé exécutif et de la bibliographie…\n")
+	emitInfo("  " + l.T(locale.CoherenceGenerating) + "\n")
 
 	cohCtx := withCtxAllChapters(ctx, chapters)
 	cohCtx = withCtxPlan(cohCtx, plan)
+	cohCtx = article.WithContextAgentRole(cohCtx, article.RoleCoherenceEditor)
 
 	var resultJSON string
 	proxyEmit := func(evt agent.Event) error {
@@ -440,21 +694,25 @@ func (o *Orchestrator) coherencePass(ctx context.Context, plan WhitePaperPlan, c
 		return CoherenceEditResult{}, errors.Wrap(err, "could not parse coherence edit result")
 	}
 
+	checkpoint.setCoherence(result)
+
 	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
-		Name: "Cohérence",
+		Name: ctxLocale(ctx).T(locale.PhaseCoherence),
 		Done: true,
-		Info: fmt.Sprintf("%d sources, %d annexes", len(result.Bibliography), len(result.Appendices)),
+		Info: ctxLocale(ctx).T(locale.CoherenceSummary, len(result.Bibliography), len(result.Appendices)),
 	}))
 
 	return result, nil
 }
 
 func (o *Orchestrator) enrichChapters(ctx context.Context, chapters []ChapterContent, emit agent.EmitFunc) ([]ChapterContent, error) {
-	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: "Enrichissement"}))
+	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: ctxLocale(ctx).T(locale.PhaseEnrichment)}))
 
 	enriched := make([]ChapterContent, len(chapters))
 	copy(enriched, chapters)
 
+	checkpoint := ctxCheckpoint(ctx)
+
 	for i, ch := range enriched {
 		select {
 		case <-ctx.Done():
@@ -462,6 +720,17 @@ func (o *Orchestrator) enrichChapters(ctx context.Context, chapters []ChapterCon
 		default:
 		}
 
+		if saved, ok := checkpoint.enriched(ch.ChapterID); ok {
+			enriched[i] = saved
+			_ = emit(agent.NewEvent(EventTypeChapterDone, &ChapterDoneData{
+				Number:    saved.Number,
+				Total:     len(enriched),
+				Title:     saved.Title,
+				WordCount: saved.WordCount,
+			}))
+			continue
+		}
+
 		_ = emit(agent.NewEvent(EventTypeChapterStart, &ChapterStartData{
 			Number: ch.Number,
 			Total:  len(enriched),
@@ -477,6 +746,7 @@ func (o *Orchestrator) enrichChapters(ctx context.Context, chapters []ChapterCon
 		}
 		citCtx := withCtxChapter(ctx, chapter)
 		citCtx = withCtxAllChapters(citCtx, enriched)
+		citCtx = article.WithContextAgentRole(citCtx, article.RoleCitationLinker)
 
 		var linkedContent string
 		citEmit := func(evt agent.Event) error {
@@ -501,6 +771,7 @@ func (o *Orchestrator) enrichChapters(ctx context.Context, chapters []ChapterCon
 		// Pass 2: diagram insertion (ctxAllChapters contains citation-enriched content up to i)
 		diagCtx := withCtxChapter(ctx, chapter)
 		diagCtx = withCtxAllChapters(diagCtx, enriched)
+		diagCtx = article.WithContextAgentRole(diagCtx, article.RoleDiagramInserter)
 
 		var diagContent string
 		diagEmit := func(evt agent.Event) error {
@@ -522,6 +793,8 @@ func (o *Orchestrator) enrichChapters(ctx context.Context, chapters []ChapterCon
 			enriched[i].WordCount = countWords(diagContent)
 		}
 
+		checkpoint.setEnriched(ch.ChapterID, enriched[i])
+
 		_ = emit(agent.NewEvent(EventTypeChapterDone, &ChapterDoneData{
 			Number:    enriched[i].Number,
 			Total:     len(enriched),
@@ -531,23 +804,35 @@ func (o *Orchestrator) enrichChapters(ctx context.Context, chapters []ChapterCon
 	}
 
 	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
-		Name: "Enrichissement",
+		Name: ctxLocale(ctx).T(locale.PhaseEnrichment),
 		Done: true,
-		Info: fmt.Sprintf("%d chapitres enrichis", len(enriched)),
+		Info: ctxLocale(ctx).T(locale.EnrichmentDone, len(enriched)),
 	}))
 
 	return enriched, nil
 }
 
-// NewOrchestrator creates a new white paper orchestrator.
-func NewOrchestrator(client llm.Client) *Orchestrator {
-	scraper := surf.NewScraper()
-	researchAgent := article.NewResearchAgent(client, duckduckgo.NewClient(scraper), scraper)
+// NewOrchestrator creates a new white paper orchestrator. Only the search
+// client and scraper of the options are used at construction time.
+func NewOrchestrator(client llm.Client, optFuncs ...OrchestratorOptionFunc) *Orchestrator {
+	opts := NewOrchestratorOptions(optFuncs...)
+
+	webScraper := opts.Scraper
+	if webScraper == nil {
+		webScraper = surf.NewScraper()
+	}
+
+	searchClient := opts.SearchClient
+	if searchClient == nil {
+		searchClient = duckduckgo.NewClient(webScraper)
+	}
+
+	researchAgent := article.NewResearchAgent(client, searchClient, webScraper)
 
 	return &Orchestrator{
 		researcher:      researchAgent,
 		planner:         NewPlannerHandler(client),
-		chapterWriter:   NewChapterWriterHandler(client),
+		chapterWriter:   newChapterWriterHandler(client, webScraper),
 		chapterEditor:   NewChapterEditorHandler(client),
 		coherenceEditor: NewCoherenceEditorHandler(client),
 		citationLinker:  NewCitationLinkerHandler(client),
@@ -557,10 +842,16 @@ func NewOrchestrator(client llm.Client) *Orchestrator {
 
 // WriteWhitePaper is a convenience function.
 func WriteWhitePaper(ctx context.Context, client llm.Client, subject string, emit agent.EmitFunc, optFuncs ...OrchestratorOptionFunc) (WhitePaper, error) {
-	o := NewOrchestrator(client)
+	o := NewOrchestrator(client, optFuncs...)
 	return o.WriteWhitePaper(ctx, subject, emit, optFuncs...)
 }
 
+// Plan is a convenience function running the research and planning phases only.
+func Plan(ctx context.Context, client llm.Client, subject string, emit agent.EmitFunc, optFuncs ...OrchestratorOptionFunc) (WhitePaperPlan, error) {
+	o := NewOrchestrator(client, optFuncs...)
+	return o.Plan(ctx, subject, emit, optFuncs...)
+}
+
 // FixOptions configures the fix pipeline.
 type FixOptions struct {
 	InputDir          string
@@ -568,6 +859,7 @@ type FixOptions struct {
 	AdditionalContext string
 	KnowledgeBase     article.KnowledgeBase
 	ForceEnrichment   bool
+	Locale            locale.Locale
 }
 
 // FixOptionFunc configures FixOptions.
@@ -595,6 +887,10 @@ func WithFixKnowledgeBase(kb article.KnowledgeBase) FixOptionFunc {
 	return func(o *FixOptions) { o.KnowledgeBase = kb }
 }
 
+func WithFixLocale(l locale.Locale) FixOptionFunc {
+	return func(o *FixOptions) { o.Locale = l }
+}
+
 func WithFixForceEnrichment(v bool) FixOptionFunc {
 	return func(o *FixOptions) { o.ForceEnrichment = v }
 }
@@ -602,22 +898,19 @@ func WithFixForceEnrichment(v bool) FixOptionFunc {
 // FixWhitePaper applies > EDITOR: annotations found in the whitepaper output
 // directory, then runs a coherence pass to update index.md and bibliography.
 func (o *Orchestrator) FixWhitePaper(ctx context.Context, emit agent.EmitFunc, optFuncs ...FixOptionFunc) (FixResult, error) {
-	opts := &FixOptions{}
+	opts := &FixOptions{Locale: locale.Default}
 	for _, fn := range optFuncs {
 		fn(opts)
 	}
 
 	// Load the plan saved during generation
-	planData, err := os.ReadFile(filepath.Join(opts.InputDir, "plan.json"))
+	plan, err := loadPlanFromDir(opts.InputDir)
 	if err != nil {
-		return FixResult{}, errors.Wrap(err, "could not read plan.json — was the whitepaper generated with a recent version of ghostwriter?")
-	}
-	var plan WhitePaperPlan
-	if err := json.Unmarshal(planData, &plan); err != nil {
-		return FixResult{}, errors.Wrap(err, "could not parse plan.json")
+		return FixResult{}, errors.WithStack(err)
 	}
 
 	// Build context
+	ctx = withCtxLocale(ctx, opts.Locale)
 	ctx = withCtxPlan(ctx, plan)
 	if opts.StyleGuidelines != "" {
 		ctx = withCtxStyleGuidelines(ctx, opts.StyleGuidelines)
@@ -647,7 +940,12 @@ func (o *Orchestrator) FixWhitePaper(ctx context.Context, emit agent.EmitFunc, o
 
 	var result FixResult
 
-	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: "Corrections"}))
+	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: ctxLocale(ctx).T(locale.PhaseFixes)}))
+
+	// When the budget of the run is used up, the remaining files keep their
+	// annotations so that the fix can be run again.
+	var budgetErr error
+	tracker := usage.ContextTracker(ctx)
 
 	for _, filePath := range matches {
 		af, err := ParseAnnotatedFile(filePath)
@@ -660,6 +958,10 @@ func (o *Orchestrator) FixWhitePaper(ctx context.Context, emit agent.EmitFunc, o
 			continue
 		}
 
+		if budgetErr = tracker.CheckBudget(); budgetErr != nil {
+			break
+		}
+
 		_ = emit(agent.NewEvent(EventTypeChapterStart, &ChapterStartData{
 			Number: af.Number,
 			Total:  len(matches),
@@ -687,6 +989,7 @@ func (o *Orchestrator) FixWhitePaper(ctx context.Context, emit agent.EmitFunc, o
 		fixCtx := withCtxChapter(ctx, chapter)
 		fixCtx = withCtxAnnotations(fixCtx, af.Annotations)
 		fixCtx = withCtxAllChapters(fixCtx, allChapters)
+		fixCtx = article.WithContextAgentRole(fixCtx, article.RoleEditor)
 
 		// Serialize content for the editor input
 		contentJSON, err := json.Marshal(content)
@@ -707,6 +1010,10 @@ func (o *Orchestrator) FixWhitePaper(ctx context.Context, emit agent.EmitFunc, o
 		}
 
 		if err := agent.NewRunner(o.chapterEditor).Run(fixCtx, agent.NewInput(string(contentJSON)), editEmit); err != nil {
+			if errors.Is(err, usage.ErrBudgetExceeded) {
+				budgetErr = err
+				break
+			}
 			return FixResult{}, errors.Wrapf(err, "could not fix chapter %q", af.Title)
 		}
 
@@ -741,19 +1048,25 @@ func (o *Orchestrator) FixWhitePaper(ctx context.Context, emit agent.EmitFunc, o
 
 	if len(result.FixedFiles) == 0 && !opts.ForceEnrichment {
 		_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
-			Name: "Corrections",
+			Name: ctxLocale(ctx).T(locale.PhaseFixes),
 			Done: true,
-			Info: "aucune annotation trouvée",
+			Info: ctxLocale(ctx).T(locale.FixesNoAnnotation),
 		}))
 		return result, nil
 	}
 
 	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
-		Name: "Corrections",
+		Name: ctxLocale(ctx).T(locale.PhaseFixes),
 		Done: true,
-		Info: fmt.Sprintf("%d fichier(s) corrigé(s)", len(result.FixedFiles)),
+		Info: ctxLocale(ctx).T(locale.FixesDone, len(result.FixedFiles)),
 	}))
 
+	// The fixed chapters are on disk: the white paper is reassembled by the
+	// next run.
+	if budgetErr != nil {
+		return result, errors.Wrap(budgetErr, "fixes incomplete")
+	}
+
 	// Coherence pass with updated chapters
 	coherence, err := o.coherencePass(ctx, plan, allChapters, emit)
 	if err != nil {
@@ -767,30 +1080,10 @@ func (o *Orchestrator) FixWhitePaper(ctx context.Context, emit agent.EmitFunc, o
 	}
 
 	// Collect sources from KB if available
-	sources := extractSources(ctx)
-	slices.SortFunc(sources, func(a, b article.Source) int {
-		if a.Relevance > b.Relevance {
-			return -1
-		}
-		if a.Relevance < b.Relevance {
-			return 1
-		}
-		return 0
-	})
-	if len(coherence.Bibliography) == 0 {
-		for _, s := range sources {
-			if s.URL != "" {
-				coherence.Bibliography = append(coherence.Bibliography, BibEntry{
-					URL:        s.URL,
-					Title:      s.Title,
-					SourceType: s.SourceType,
-				})
-			}
-		}
-	}
+	collectSources(ctx, &coherence)
 
 	// Re-assemble to update index.md, bibliography.md, appendices
-	if _, err := Assemble(plan, allChapters, coherence, AssembleOptions{OutputDir: opts.InputDir}); err != nil {
+	if _, err := Assemble(plan, allChapters, coherence, AssembleOptions{OutputDir: opts.InputDir, Locale: opts.Locale}); err != nil {
 		return result, errors.Wrap(err, "assembly phase failed")
 	}
 
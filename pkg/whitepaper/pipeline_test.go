@@ -0,0 +1,507 @@
+package whitepaper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/fakellm"
+	"github.com/bornholm/ghostwriter/pkg/fakeweb"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/bornholm/ghostwriter/pkg/usage"
+	"github.com/pkg/errors"
+)
+
+const testSubject = "Solar energy for small businesses"
+
+var testChapterFiles = []string{
+	"chapter-01-context-and-stakes.md",
+	"chapter-02-current-landscape.md",
+	"chapter-03-challenges-and-risks.md",
+}
+
+func TestWriteWhitePaper(t *testing.T) {
+	web := newTestWeb(t)
+	client := fakellm.NewClient()
+	dir := t.TempDir()
+	events := &eventRecorder{}
+
+	wp, err := writeTestWhitePaper(t, client, web, dir, nil, events)
+	if err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	if !slices.Equal(wp.ChapterFiles, testChapterFiles) {
+		t.Errorf("expected chapter files %v, got %v", testChapterFiles, wp.ChapterFiles)
+	}
+	if e := filepath.Join(dir, "index.md"); wp.Entrypoint != e {
+		t.Errorf("expected entrypoint %q, got %q", e, wp.Entrypoint)
+	}
+	if len(wp.Metadata.Sources) != 3 {
+		t.Errorf("expected 3 sources, got %d", len(wp.Metadata.Sources))
+	}
+
+	index := readTestFile(t, dir, "index.md")
+	for _, expected := range append([]string{`title: "` + testSubject + `"`, "## Abstract", "## Executive Summary", "appendix-01-methodology.md"}, testChapterFiles...) {
+		if !strings.Contains(index, expected) {
+			t.Errorf("expected index.md to contain %q, got:\n%s", expected, index)
+		}
+	}
+
+	chapter := readTestFile(t, dir, testChapterFiles[0])
+	if !strings.HasPrefix(chapter, "# Context and Stakes\n\n") {
+		t.Errorf("expected the chapter to start with its title, got:\n%s", chapter)
+	}
+	if !strings.Contains(chapter, "```mermaid") || !strings.Contains(chapter, "See [Solar basics]("+web.URL("/solar-basics")+")") {
+		t.Errorf("expected the chapter to be enriched, got:\n%s", chapter)
+	}
+
+	bibliography := readTestFile(t, dir, "bibliography.md")
+	for _, path := range []string{"/solar-basics", "/solar-costs", "/solar-grid"} {
+		if !strings.Contains(bibliography, web.URL(path)) {
+			t.Errorf("expected the bibliography to cite %s, got:\n%s", path, bibliography)
+		}
+	}
+	if strings.Contains(bibliography, web.URL("/missing")) {
+		t.Errorf("expected the page that could not be scraped to be left out, got:\n%s", bibliography)
+	}
+
+	readTestFile(t, dir, "plan.json")
+	readTestFile(t, dir, "appendix-01-methodology.md")
+	if _, err := os.Stat(filepath.Join(dir, checkpointFile)); !os.IsNotExist(err) {
+		t.Error("expected the checkpoint to be removed")
+	}
+
+	expectedEvents := []string{
+		"Research", "Research done",
+		"Planning", "Planning done",
+		"Writing",
+		"chapter 1 start", "chapter 1 done (300 words)",
+		"chapter 2 start", "chapter 2 done (300 words)",
+		"chapter 3 start", "chapter 3 done (300 words)",
+		"Coherence", "Coherence done",
+		"Enrichment",
+		"chapter 1 start", "chapter 1 done", "chapter 2 start", "chapter 2 done", "chapter 3 start", "chapter 3 done",
+		"Enrichment done",
+	}
+	assertEvents(t, expectedEvents, events.labels(true))
+
+	expectedCalls := map[article.AgentRole]int{
+		article.RoleResearcher: 3,
+		article.RolePlanner:    1,
+		// The writers, editors and citation linkers search the knowledge base
+		// before each chapter
+		article.RoleWriter:          6,
+		article.RoleEditor:          6,
+		article.RoleCoherenceEditor: 1,
+		article.RoleCitationLinker:  6,
+		article.RoleDiagramInserter: 3,
+	}
+	for role, expected := range expectedCalls {
+		if calls := client.CallsFor(role); calls != expected {
+			t.Errorf("expected %d calls for role %s, got %d", expected, role, calls)
+		}
+	}
+}
+
+func TestWriteWhitePaperErrors(t *testing.T) {
+	errUnavailable := errors.New("model unavailable")
+
+	t.Run("planner failure", func(t *testing.T) {
+		dir := t.TempDir()
+
+		_, err := writeTestWhitePaper(t, fakellm.NewClient(fakellm.WithError(article.RolePlanner, errUnavailable)), newTestWeb(t), dir, nil, &eventRecorder{})
+		if !errors.Is(err, errUnavailable) || !strings.Contains(err.Error(), "planning phase failed") {
+			t.Errorf("expected the planning phase to fail, got: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "index.md")); !os.IsNotExist(err) {
+			t.Error("expected no white paper to be assembled")
+		}
+	})
+
+	t.Run("invalid plans", func(t *testing.T) {
+		client := fakellm.NewClient(fakellm.WithChapters(0))
+
+		_, err := writeTestWhitePaper(t, client, newTestWeb(t), t.TempDir(), nil, &eventRecorder{})
+		if err == nil || !strings.Contains(err.Error(), "plan has no chapters") {
+			t.Errorf("expected the plan to be refused, got: %v", err)
+		}
+		if calls := client.CallsFor(article.RolePlanner); calls != plannerMaxRetries {
+			t.Errorf("expected %d planning attempts, got %d", plannerMaxRetries, calls)
+		}
+	})
+
+	t.Run("writer failure", func(t *testing.T) {
+		dir := t.TempDir()
+
+		_, err := writeTestWhitePaper(t, fakellm.NewClient(fakellm.WithError(article.RoleWriter, errUnavailable)), newTestWeb(t), dir, nil, &eventRecorder{})
+		if !errors.Is(err, errUnavailable) || !strings.Contains(err.Error(), `could not write chapter "Context and Stakes"`) {
+			t.Errorf("expected the writing phase to fail, got: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, checkpointFile)); err != nil {
+			t.Errorf("expected the checkpoint to be kept, got: %v", err)
+		}
+	})
+
+	t.Run("budget exceeded", func(t *testing.T) {
+		dir := t.TempDir()
+		events := &eventRecorder{}
+
+		// The first chapter is written, the second one exceeds the budget
+		client := fakellm.NewClient(fakellm.WithErrorAfter(article.RoleWriter, 2, usage.ErrBudgetExceeded))
+
+		wp, err := writeTestWhitePaper(t, client, newTestWeb(t), dir, nil, events)
+		if !errors.Is(err, usage.ErrBudgetExceeded) {
+			t.Fatalf("expected the budget to be exceeded, got: %v", err)
+		}
+		if !slices.Equal(wp.ChapterFiles, testChapterFiles[:1]) {
+			t.Errorf("expected the written chapter to be assembled, got %v", wp.ChapterFiles)
+		}
+		if index := readTestFile(t, dir, "index.md"); !strings.Contains(index, locale.English.T(locale.IncompleteWhitePaper, 1, 3)) {
+			t.Errorf("expected index.md to be marked as incomplete, got:\n%s", index)
+		}
+		if calls := client.CallsFor(article.RoleCoherenceEditor); calls != 0 {
+			t.Errorf("expected the coherence pass to be skipped, got %d calls", calls)
+		}
+		assertEvents(t, []string{"Research", "Research done", "Planning", "Planning done", "Writing", "chapter 1 start", "chapter 1 done", "chapter 2 start"}, events.labels(false))
+	})
+
+	t.Run("search failure", func(t *testing.T) {
+		web := newTestWeb(t)
+		web.FailSearches(errors.New("search engine unavailable"))
+		dir := t.TempDir()
+
+		wp, err := writeTestWhitePaper(t, fakellm.NewClient(), web, dir, nil, &eventRecorder{})
+		if err != nil {
+			t.Fatalf("expected the white paper to be written without sources, got: %v", err)
+		}
+		if len(wp.Metadata.Sources) != 0 {
+			t.Errorf("expected no sources, got %d", len(wp.Metadata.Sources))
+		}
+		if bibliography := readTestFile(t, dir, "bibliography.md"); !strings.Contains(bibliography, locale.English.T(locale.NoSources)) {
+			t.Errorf("expected an empty bibliography, got:\n%s", bibliography)
+		}
+	})
+}
+
+func TestFixWhitePaper(t *testing.T) {
+	web := newTestWeb(t)
+	dir := t.TempDir()
+
+	kb, err := article.NewKnowledgeBase()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if _, err := writeTestWhitePaper(t, fakellm.NewClient(), web, dir, kb, &eventRecorder{}); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	fix := func(client *fakellm.Client, events *eventRecorder) (FixResult, error) {
+		return NewOrchestrator(client).FixWhitePaper(context.Background(), events.emit,
+			WithFixInputDir(dir),
+			WithFixKnowledgeBase(kb),
+			WithFixLocale(locale.English),
+		)
+	}
+
+	t.Run("without annotations", func(t *testing.T) {
+		client := fakellm.NewClient()
+
+		result, err := fix(client, &eventRecorder{})
+		if err != nil {
+			t.Fatalf("expected no error, got: %+v", err)
+		}
+		if len(result.FixedFiles) != 0 || len(result.SkippedFiles) != 3 {
+			t.Errorf("expected every chapter to be skipped, got %+v", result)
+		}
+		if calls := client.Calls(); len(calls) != 0 {
+			t.Errorf("expected no chat completion, got %d", len(calls))
+		}
+	})
+
+	t.Run("with annotations", func(t *testing.T) {
+		annotated := filepath.Join(dir, testChapterFiles[1])
+		original := readTestFile(t, dir, testChapterFiles[1])
+		withAnnotation := strings.Replace(original, "\n\n", "\n\n> EDITOR: Compare with wind power.\n\n", 1)
+		if err := os.WriteFile(annotated, []byte(withAnnotation), 0o644); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		client := fakellm.NewClient()
+		events := &eventRecorder{}
+
+		result, err := fix(client, events)
+		if err != nil {
+			t.Fatalf("expected no error, got: %+v", err)
+		}
+		if !slices.Equal(result.FixedFiles, []string{annotated}) || len(result.SkippedFiles) != 2 {
+			t.Errorf("expected only the annotated chapter to be fixed, got %+v", result)
+		}
+
+		fixed := readTestFile(t, dir, testChapterFiles[1])
+		if strings.Contains(fixed, "> EDITOR:") {
+			t.Errorf("expected the annotation to be removed, got:\n%s", fixed)
+		}
+		if !strings.Contains(fixed, "This revision addresses the reviewer annotations: Compare with wind power.") {
+			t.Errorf("expected the annotation to be addressed, got:\n%s", fixed)
+		}
+
+		// A knowledge base search and the edited chapter
+		if calls := client.CallsFor(article.RoleEditor); calls != 2 {
+			t.Errorf("expected the annotated chapter only to be edited, got %d calls", calls)
+		}
+
+		expectedEvents := []string{
+			"Fixes", "chapter 2 start", "chapter 2 done", "Fixes done",
+			"Coherence", "Coherence done",
+			"Enrichment",
+			"chapter 1 start", "chapter 1 done", "chapter 2 start", "chapter 2 done", "chapter 3 start", "chapter 3 done",
+			"Enrichment done",
+		}
+		assertEvents(t, expectedEvents, events.labels(false))
+
+		index := readTestFile(t, dir, "index.md")
+		for _, f := range testChapterFiles {
+			if !strings.Contains(index, f) {
+				t.Errorf("expected the reassembled index.md to include %s, got:\n%s", f, index)
+			}
+		}
+	})
+}
+
+func TestRewriteChapter(t *testing.T) {
+	web := newTestWeb(t)
+	dir := t.TempDir()
+
+	kb, err := article.NewKnowledgeBase()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if _, err := writeTestWhitePaper(t, fakellm.NewClient(), web, dir, kb, &eventRecorder{}); err != nil {
+		t.Fatalf("expected no error, got: %+v", err)
+	}
+
+	rewrite := func(client *fakellm.Client, events *eventRecorder, chapter int) (RewriteResult, error) {
+		return NewOrchestrator(client).RewriteChapter(context.Background(), events.emit,
+			WithRewriteInputDir(dir),
+			WithRewriteChapter(chapter),
+			WithRewriteInstructions("Compare with wind power."),
+			WithRewriteKnowledgeBase(kb),
+			WithRewriteMaxReviewRounds(1),
+			WithRewriteLocale(locale.English),
+		)
+	}
+
+	t.Run("unknown chapter", func(t *testing.T) {
+		client := fakellm.NewClient()
+
+		_, err := rewrite(client, &eventRecorder{}, 9)
+		if !errors.Is(err, ErrChapterNotFound) {
+			t.Errorf("expected ErrChapterNotFound, got: %v", err)
+		}
+		if calls := client.Calls(); len(calls) != 0 {
+			t.Errorf("expected no chat completion, got %d", len(calls))
+		}
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		// A pending annotation of a neighbouring chapter survives the rewrite
+		first := strings.Replace(readTestFile(t, dir, testChapterFiles[0]), "\n\n", "\n\n> EDITOR: Compare with wind power.\n\n", 1)
+		if err := os.WriteFile(filepath.Join(dir, testChapterFiles[0]), []byte(first), 0644); err != nil {
+			t.Fatalf("expected no error, got: %+v", err)
+		}
+		last := readTestFile(t, dir, testChapterFiles[2])
+
+		client := fakellm.NewClient()
+		events := &eventRecorder{}
+
+		result, err := rewrite(client, events, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got: %+v", err)
+		}
+		if e := filepath.Join(dir, testChapterFiles[1]); result.File != e {
+			t.Errorf("expected the chapter to be written to %q, got %q", e, result.File)
+		}
+		if e := filepath.Join(dir, "index.md"); result.Entrypoint != e {
+			t.Errorf("expected entrypoint %q, got %q", e, result.Entrypoint)
+		}
+		if result.Chapter.Number != 2 || result.Chapter.WordCount != 300 {
+			t.Errorf("unexpected rewritten chapter: %+v", result.Chapter)
+		}
+
+		// The writer is given the instructions and the neighbouring chapters
+		var prompt string
+		for _, call := range client.Calls() {
+			if call.Role == article.RoleWriter {
+				prompt = call.Prompt
+				break
+			}
+		}
+		for _, expected := range []string{
+			"- **Title:** Current Landscape",
+			"**Rewrite Instructions (PRIORITY):**\n```\nCompare with wind power.\n```",
+			"**Previous Chapter (for continuity):**\n- Title: Context and Stakes",
+			"**Next Chapter (for continuity):**\n- Title: Challenges and Risks",
+		} {
+			if !strings.Contains(prompt, expected) {
+				t.Errorf("expected the writer prompt to contain %q, got:\n%s", expected, prompt)
+			}
+		}
+
+		expectedCalls := map[article.AgentRole]int{
+			article.RoleResearcher:      0,
+			article.RolePlanner:         0,
+			article.RoleWriter:          2,
+			article.RoleEditor:          2,
+			article.RoleCoherenceEditor: 1,
+		}
+		for role, expected := range expectedCalls {
+			if calls := client.CallsFor(role); calls != expected {
+				t.Errorf("expected %d calls for role %s, got %d", expected, role, calls)
+			}
+		}
+
+		expectedEvents := []string{
+			"Rewrite", "chapter 2 start", "chapter 2 done", "Rewrite done",
+			"Coherence", "Coherence done",
+		}
+		assertEvents(t, expectedEvents, events.labels(false))
+
+		if chapter := readTestFile(t, dir, testChapterFiles[1]); !strings.HasPrefix(chapter, "# Current Landscape\n\n") {
+			t.Errorf("expected the rewritten chapter to start with its title, got:\n%s", chapter)
+		}
+		if readTestFile(t, dir, testChapterFiles[0]) != first || readTestFile(t, dir, testChapterFiles[2]) != last {
+			t.Error("expected the other chapters to be left untouched")
+		}
+
+		index := readTestFile(t, dir, "index.md")
+		for _, f := range testChapterFiles {
+			if !strings.Contains(index, f) {
+				t.Errorf("expected the reassembled index.md to include %s, got:\n%s", f, index)
+			}
+		}
+		if strings.Contains(index, "Incomplete white paper") {
+			t.Errorf("expected no incomplete marker, got:\n%s", index)
+		}
+	})
+
+	t.Run("incomplete white paper", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(dir, testChapterFiles[2])); err != nil {
+			t.Fatalf("expected no error, got: %+v", err)
+		}
+
+		if _, err := rewrite(fakellm.NewClient(), &eventRecorder{}, 1); err != nil {
+			t.Fatalf("expected no error, got: %+v", err)
+		}
+
+		if index := readTestFile(t, dir, "index.md"); !strings.Contains(index, locale.English.T(locale.IncompleteWhitePaper, 2, 3)) {
+			t.Errorf("expected index.md to stay marked as incomplete, got:\n%s", index)
+		}
+	})
+}
+
+func writeTestWhitePaper(t *testing.T, client *fakellm.Client, web *fakeweb.Web, dir string, kb article.KnowledgeBase, events *eventRecorder) (WhitePaper, error) {
+	t.Helper()
+
+	opts := []OrchestratorOptionFunc{
+		WithOutputDir(dir),
+		WithTargetWordCount(900),
+		WithResearchDepth(article.ResearchBasic),
+		WithMaxReviewRounds(1),
+		WithSearchClient(web.SearchClient()),
+		WithScraper(web.Scraper()),
+		WithLocale(locale.English),
+	}
+	if kb != nil {
+		opts = append(opts, WithKnowledgeBase(kb))
+	}
+
+	return WriteWhitePaper(context.Background(), client, testSubject, events.emit, opts...)
+}
+
+func newTestWeb(t *testing.T) *fakeweb.Web {
+	t.Helper()
+
+	web := fakeweb.New(
+		fakeweb.Page{Path: "/solar-basics", Title: "Solar basics", Description: "How solar panels work", Body: "Solar panels convert sunlight into electricity."},
+		fakeweb.Page{Path: "/solar-costs", Title: "Solar costs", Description: "The cost of solar installations", Body: "Installation costs have dropped over the last decade."},
+		fakeweb.Page{Path: "/missing", Title: "Missing page", Status: http.StatusNotFound},
+		fakeweb.Page{Path: "/solar-grid", Title: "Solar and the grid", Description: "Selling solar energy to the grid", Body: "Surplus energy can be sold to the grid."},
+	)
+	t.Cleanup(web.Close)
+
+	return web
+}
+
+func readTestFile(t *testing.T, dir string, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("expected %s to be written, got: %v", name, err)
+	}
+
+	return string(data)
+}
+
+// eventRecorder records the phase and chapter events of a pipeline.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []agent.Event
+}
+
+func (r *eventRecorder) emit(evt agent.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, evt)
+	return nil
+}
+
+// labels describes the recorded events, with the word counts of the written
+// chapters if withWordCounts is true.
+func (r *eventRecorder) labels(withWordCounts bool) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var labels []string
+	writing := false
+	for _, evt := range r.events {
+		switch data := evt.Data().(type) {
+		case *PhaseData:
+			writing = data.Name == locale.English.T(locale.PhaseWriting)
+			if data.Done {
+				labels = append(labels, data.Name+" done")
+			} else {
+				labels = append(labels, data.Name)
+			}
+		case *ChapterStartData:
+			labels = append(labels, fmt.Sprintf("chapter %d start", data.Number))
+		case *ChapterDoneData:
+			if withWordCounts && writing {
+				labels = append(labels, fmt.Sprintf("chapter %d done (%d words)", data.Number, data.WordCount))
+			} else {
+				labels = append(labels, fmt.Sprintf("chapter %d done", data.Number))
+			}
+		}
+	}
+
+	return labels
+}
+
+func assertEvents(t *testing.T, expected, actual []string) {
+	t.Helper()
+
+	if !slices.Equal(expected, actual) {
+		t.Errorf("unexpected events:\nexpected: %q\ngot:      %q", expected, actual)
+	}
+}
@@ -0,0 +1,166 @@
+package whitepaper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LoadPlan reads a plan from a JSON file, typically produced by Plan and
+// edited by hand, and prepares it with PreparePlan.
+func LoadPlan(path string, targetWordCount int) (WhitePaperPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return WhitePaperPlan{}, errors.Wrap(err, "could not read plan")
+	}
+
+	var plan WhitePaperPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return WhitePaperPlan{}, errors.Wrapf(err, "could not parse plan %q", path)
+	}
+
+	plan, err = PreparePlan(plan, targetWordCount)
+	if err != nil {
+		return WhitePaperPlan{}, errors.Wrapf(err, "invalid plan %q", path)
+	}
+
+	return plan, nil
+}
+
+// PreparePlan checks a plan provided by the user, fills in missing chapter
+// IDs, numbers and word counts, recomputes the total word count from the
+// chapters and validates the result.
+func PreparePlan(plan WhitePaperPlan, targetWordCount int) (WhitePaperPlan, error) {
+	if strings.TrimSpace(plan.Title) == "" {
+		return WhitePaperPlan{}, errors.New("title is empty")
+	}
+
+	for _, ch := range plan.allChapters() {
+		if ch == nil {
+			return WhitePaperPlan{}, errors.New("null chapter")
+		}
+		if strings.TrimSpace(ch.Title) == "" {
+			return WhitePaperPlan{}, errors.Errorf("chapter %d has no title", ch.Number)
+		}
+	}
+
+	// Word counts are likely to have been edited: derive the total from the chapters.
+	plan.TotalWords = 0
+	normalizePlan(&plan, targetWordCount)
+
+	if err := validatePlan(&plan); err != nil {
+		return WhitePaperPlan{}, errors.WithStack(err)
+	}
+
+	return plan, nil
+}
+
+// SavePlan writes the plan as plan.json and as a readable plan.md in dir.
+func SavePlan(dir string, plan WhitePaperPlan) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "could not create output directory")
+	}
+
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not serialize plan")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "plan.json"), planJSON, 0644); err != nil {
+		return errors.Wrap(err, "could not write plan.json")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "plan.md"), []byte(FormatPlanMarkdown(plan)), 0644); err != nil {
+		return errors.Wrap(err, "could not write plan.md")
+	}
+
+	return nil
+}
+
+// FormatPlanMarkdown renders the plan as a Markdown outline meant for review.
+func FormatPlanMarkdown(plan WhitePaperPlan) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", plan.Title)
+	if plan.Subtitle != "" {
+		fmt.Fprintf(&b, "_%s_\n\n", plan.Subtitle)
+	}
+
+	fmt.Fprintf(&b, "- **Target audience:** %s\n", plan.TargetAudience)
+	fmt.Fprintf(&b, "- **Total words:** %d\n", plan.TotalWords)
+	if len(plan.Keywords) > 0 {
+		fmt.Fprintf(&b, "- **Keywords:** %s\n", strings.Join(plan.Keywords, ", "))
+	}
+	b.WriteString("\n")
+
+	if plan.CentralArgument != "" {
+		fmt.Fprintf(&b, "## Central argument\n\n%s\n\n", plan.CentralArgument)
+	}
+
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "## %s\n\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+		b.WriteString("\n")
+	}
+
+	writeList("Objectives", plan.Objectives)
+	writeList("Executive summary guidance", plan.ExecutiveSummaryGuidance)
+
+	writeChapter := func(ch *Chapter) {
+		fmt.Fprintf(&b, "### %d. %s (%d words)\n\n", ch.Number, ch.Title, ch.WordCount)
+		if ch.Description != "" {
+			fmt.Fprintf(&b, "%s\n\n", ch.Description)
+		}
+		for _, kp := range ch.KeyPoints {
+			fmt.Fprintf(&b, "- %s\n", kp)
+		}
+		if len(ch.KeyPoints) > 0 {
+			b.WriteString("\n")
+		}
+		for _, sec := range ch.Sections {
+			if sec == nil {
+				continue
+			}
+			fmt.Fprintf(&b, "#### %s", sec.Title)
+			if sec.WordCount > 0 {
+				fmt.Fprintf(&b, " (%d words)", sec.WordCount)
+			}
+			b.WriteString("\n\n")
+			if sec.Description != "" {
+				fmt.Fprintf(&b, "%s\n\n", sec.Description)
+			}
+			for _, kp := range sec.KeyPoints {
+				fmt.Fprintf(&b, "- %s\n", kp)
+			}
+			if len(sec.KeyPoints) > 0 {
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	if len(plan.Parts) > 0 {
+		for _, part := range plan.Parts {
+			fmt.Fprintf(&b, "## Part: %s\n\n", part.Title)
+			for _, ch := range part.Chapters {
+				writeChapter(ch)
+			}
+		}
+	} else {
+		b.WriteString("## Chapters\n\n")
+		for _, ch := range plan.Chapters {
+			writeChapter(ch)
+		}
+	}
+
+	writeList("Appendices", plan.AppendixTitles)
+
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package whitepaper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPlan(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "plan.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("normalizes edited plan", func(t *testing.T) {
+		path := write(t, `{
+			"title": "Edited",
+			"total_words": 99999,
+			"chapters": [
+				{"title": "First Chapter", "word_count": 1200},
+				{"title": "Second", "number": "2"}
+			]
+		}`)
+
+		plan, err := LoadPlan(path, 3000)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		chapters := plan.allChapters()
+		if chapters[0].ID != "first_chapter" || chapters[0].Number != 1 {
+			t.Errorf("unexpected first chapter: %+v", chapters[0])
+		}
+		if chapters[1].WordCount != 1500 {
+			t.Errorf("expected missing word count to be derived from target, got %d", chapters[1].WordCount)
+		}
+		if plan.TotalWords != 2700 {
+			t.Errorf("expected total words to be recomputed, got %d", plan.TotalWords)
+		}
+	})
+
+	cases := map[string]string{
+		"invalid json":      `{"title": `,
+		"missing title":     `{"chapters": [{"title": "A", "word_count": 100}]}`,
+		"no chapters":       `{"title": "Empty"}`,
+		"untitled chapter":  `{"title": "T", "chapters": [{"word_count": 100}]}`,
+		"duplicate chapter": `{"title": "T", "chapters": [{"id": "a", "title": "A"}, {"id": "a", "title": "B"}]}`,
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadPlan(write(t, content), 1000); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSavePlan(t *testing.T) {
+	dir := t.TempDir()
+	plan := WhitePaperPlan{
+		Title:      "Saved",
+		Objectives: []string{"Understand"},
+		Parts: []*Part{
+			{Title: "Part One", Chapters: []*Chapter{
+				{ID: "intro", Number: 1, Title: "Introduction", WordCount: 500, KeyPoints: []string{"Context"}},
+			}},
+		},
+		TotalWords: 500,
+	}
+
+	if err := SavePlan(dir, plan); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	reloaded, err := LoadPlan(filepath.Join(dir, "plan.json"), 1000)
+	if err != nil {
+		t.Fatalf("could not reload saved plan: %v", err)
+	}
+	if reloaded.Title != plan.Title || len(reloaded.allChapters()) != 1 {
+		t.Errorf("unexpected reloaded plan: %+v", reloaded)
+	}
+
+	md, err := os.ReadFile(filepath.Join(dir, "plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Saved", "## Part: Part One", "### 1. Introduction (500 words)", "- Context", "- Understand"} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("expected plan.md to contain %q", want)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/bornholm/genai/agent"
@@ -58,7 +60,7 @@ func (h *PlannerHandler) generatePlan(ctx context.Context, subject string, targe
 		return WhitePaperPlan{}, errors.WithStack(err)
 	}
 
-	userPrompt := h.buildPlanningPrompt(subject, targetWordCount, kb,
+	userPrompt := h.buildPlanningPrompt(subject, targetWordCount, ctxParts(ctx), kb,
 		ctxStyleGuidelines(ctx), ctxAdditionalContext(ctx))
 
 	schema := h.buildSchema()
@@ -112,7 +114,7 @@ func (h *PlannerHandler) generatePlan(ctx context.Context, subject string, targe
 	return WhitePaperPlan{}, errors.Wrap(lastErr, fmt.Sprintf("plan generation failed after %d attempts", plannerMaxRetries))
 }
 
-func (h *PlannerHandler) buildPlanningPrompt(subject string, targetWordCount int, kb article.KnowledgeBase, styleGuidelines, additionalContext string) string {
+func (h *PlannerHandler) buildPlanningPrompt(subject string, targetWordCount int, parts bool, kb article.KnowledgeBase, styleGuidelines, additionalContext string) string {
 	var b strings.Builder
 
 	b.WriteString("Create a comprehensive white paper plan based on the research data provided below.\n\n")
@@ -135,12 +137,18 @@ func (h *PlannerHandler) buildPlanningPrompt(subject string, targetWordCount int
 		}
 	}
 	b.WriteString("**Key Topics Identified:**\n")
-	for kw, count := range allKeywords {
-		fmt.Fprintf(&b, "- %s (%d sources)\n", kw, count)
+	// Sorted so that the prompt is the same from one run to the next
+	for _, kw := range slices.Sorted(maps.Keys(allKeywords)) {
+		fmt.Fprintf(&b, "- %s (%d sources)\n", kw, allKeywords[kw])
 	}
 	b.WriteString("\n")
 
-	fmt.Fprintf(&b, "**Requirements:**\n- Target word count: %d words\n- Format: professional white paper\n\n", targetWordCount)
+	fmt.Fprintf(&b, "**Requirements:**\n- Target word count: %d words\n- Format: professional white paper\n", targetWordCount)
+	if parts {
+		b.WriteString("- Structure: group the chapters into parts\n\n")
+	} else {
+		b.WriteString("- Structure: flat list of chapters, leave `parts` empty\n\n")
+	}
 
 	if styleGuidelines != "" {
 		b.WriteString("**Style Guidelines:**\n```\n" + styleGuidelines + "\n```\n\n")
@@ -163,7 +171,17 @@ func (h *PlannerHandler) parsePlan(raw string, targetWordCount int) (WhitePaperP
 
 	plan := plans[0]
 
-	// Ensure chapter IDs and numbers
+	normalizePlan(&plan, targetWordCount)
+
+	if err := validatePlan(&plan); err != nil {
+		return WhitePaperPlan{}, errors.Wrap(err, "invalid plan")
+	}
+
+	return plan, nil
+}
+
+// normalizePlan ensures chapter IDs, numbers and word counts are set.
+func normalizePlan(plan *WhitePaperPlan, targetWordCount int) {
 	chapters := plan.allChapters()
 	for i, ch := range chapters {
 		if ch.Number == 0 {
@@ -182,12 +200,6 @@ func (h *PlannerHandler) parsePlan(raw string, targetWordCount int) (WhitePaperP
 			plan.TotalWords += ch.WordCount
 		}
 	}
-
-	if err := validatePlan(&plan); err != nil {
-		return WhitePaperPlan{}, errors.Wrap(err, "invalid plan")
-	}
-
-	return plan, nil
 }
 
 func validatePlan(plan *WhitePaperPlan) error {
@@ -3,8 +3,11 @@ package whitepaper
 import (
 	"context"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/Bornholm/amatl/pkg/html/layout"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/attrs"
@@ -23,6 +26,9 @@ const (
 	RenderFormatPDF  RenderFormat = "pdf"
 )
 
+// ErrMissingInclude is returned when index.md includes files that do not exist.
+var ErrMissingInclude = errors.New("missing included file")
+
 // RenderOptions configures the amatl rendering pass.
 type RenderOptions struct {
 	Format       RenderFormat
@@ -43,6 +49,10 @@ func RenderWhitePaper(ctx context.Context, indexMDPath string, opts RenderOption
 		return errors.WithStack(err)
 	}
 
+	if err := checkIncludes(source, filepath.Dir(absIndex)); err != nil {
+		return errors.WithStack(err)
+	}
+
 	sourcePath := resolver.Path(absIndex)
 	baseDir, err := sourcePath.Dir().Abs()
 	if err != nil {
@@ -95,3 +105,35 @@ func RenderWhitePaper(ctx context.Context, indexMDPath string, opts RenderOption
 
 	return nil
 }
+
+var includeDirectiveRegexp = regexp.MustCompile(`:include\{[^}]*url="([^"]+)"`)
+
+// checkIncludes verifies that every local file included by the source exists,
+// so that a broken output directory fails with an explicit error rather than
+// deep inside the amatl pipeline.
+func checkIncludes(source []byte, baseDir string) error {
+	var missing []string
+	for _, match := range includeDirectiveRegexp.FindAllSubmatch(source, -1) {
+		target := string(match[1])
+
+		if u, err := url.Parse(target); err == nil && u.Scheme != "" && u.Scheme != "file" {
+			continue // remote includes are resolved by amatl
+		} else if err == nil && u.Scheme == "file" {
+			target = u.Path
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(baseDir, target)
+		}
+
+		if _, err := os.Stat(target); err != nil {
+			missing = append(missing, string(match[1]))
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.Wrapf(ErrMissingInclude, "index.md includes missing files: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package whitepaper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCheckIncludes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "chapter-01-intro.md"), []byte("intro"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("all includes present", func(t *testing.T) {
+		source := []byte(":include{url=\"chapter-01-intro.md\", shiftHeadings=\"1\"}\n\n" +
+			":include{url=\"https://example.com/remote.md\"}\n")
+		if err := checkIncludes(source, dir); err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+	})
+
+	t.Run("missing include", func(t *testing.T) {
+		source := []byte(":include{url=\"chapter-01-intro.md\", shiftHeadings=\"1\"}\n\n" +
+			":include{url=\"bibliography.md\", shiftHeadings=\"1\"}\n")
+		err := checkIncludes(source, dir)
+		if !errors.Is(err, ErrMissingInclude) {
+			t.Fatalf("expected ErrMissingInclude, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package whitepaper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/bornholm/genai/agent"
+	"github.com/bornholm/genai/llm"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/locale"
+	"github.com/gosimple/slug"
+	"github.com/pkg/errors"
+)
+
+var ErrChapterNotFound = errors.New("chapter not found")
+
+// RewriteOptions configures the rewrite of a single chapter.
+type RewriteOptions struct {
+	InputDir          string
+	Chapter           int    // number of the chapter to rewrite
+	Instructions      string // optional guidance given to the writer
+	StyleGuidelines   string
+	AdditionalContext string
+	KnowledgeBase     article.KnowledgeBase
+	MaxReviewRounds   int
+	Locale            locale.Locale
+}
+
+// RewriteOptionFunc configures RewriteOptions.
+type RewriteOptionFunc func(*RewriteOptions)
+
+// RewriteResult is the outcome of a rewrite run.
+type RewriteResult struct {
+	Chapter    ChapterContent
+	File       string // path of the rewritten chapter file
+	Entrypoint string // path to index.md
+}
+
+func WithRewriteInputDir(dir string) RewriteOptionFunc {
+	return func(o *RewriteOptions) { o.InputDir = dir }
+}
+
+func WithRewriteChapter(number int) RewriteOptionFunc {
+	return func(o *RewriteOptions) { o.Chapter = number }
+}
+
+func WithRewriteInstructions(s string) RewriteOptionFunc {
+	return func(o *RewriteOptions) { o.Instructions = s }
+}
+
+func WithRewriteStyleGuidelines(s string) RewriteOptionFunc {
+	return func(o *RewriteOptions) { o.StyleGuidelines = s }
+}
+
+func WithRewriteAdditionalContext(s string) RewriteOptionFunc {
+	return func(o *RewriteOptions) { o.AdditionalContext = s }
+}
+
+func WithRewriteKnowledgeBase(kb article.KnowledgeBase) RewriteOptionFunc {
+	return func(o *RewriteOptions) { o.KnowledgeBase = kb }
+}
+
+func WithRewriteLocale(l locale.Locale) RewriteOptionFunc {
+	return func(o *RewriteOptions) { o.Locale = l }
+}
+
+// WithRewriteMaxReviewRounds sets the number of write→review rounds (minimum 1).
+func WithRewriteMaxReviewRounds(n int) RewriteOptionFunc {
+	return func(o *RewriteOptions) {
+		if n < 1 {
+			n = 1
+		}
+		o.MaxReviewRounds = n
+	}
+}
+
+// RewriteChapter writes again one chapter of an existing white paper output
+// directory, with its neighbouring chapters as continuity context, then runs
+// the coherence pass and reassembles index.md and the bibliography. The files
+// of the other chapters, and the editor annotations they hold, are left untouched.
+func (o *Orchestrator) RewriteChapter(ctx context.Context, emit agent.EmitFunc, optFuncs ...RewriteOptionFunc) (RewriteResult, error) {
+	opts := &RewriteOptions{MaxReviewRounds: 2, Locale: locale.Default}
+	for _, fn := range optFuncs {
+		fn(opts)
+	}
+
+	plan, err := loadPlanFromDir(opts.InputDir)
+	if err != nil {
+		return RewriteResult{}, errors.WithStack(err)
+	}
+
+	var chapter *Chapter
+	for _, ch := range plan.allChapters() {
+		if int(ch.Number) == opts.Chapter {
+			chapter = ch
+			break
+		}
+	}
+	if chapter == nil {
+		return RewriteResult{}, errors.Wrapf(ErrChapterNotFound, "no chapter %d in plan.json", opts.Chapter)
+	}
+
+	chapters, err := loadChaptersFromDir(opts.InputDir)
+	if err != nil {
+		return RewriteResult{}, errors.Wrap(err, "could not load existing chapters")
+	}
+
+	index := slices.IndexFunc(chapters, func(c ChapterContent) bool { return c.Number == opts.Chapter })
+	if index < 0 {
+		return RewriteResult{}, errors.Wrapf(ErrChapterNotFound, "no chapter-%02d-*.md file in %s", opts.Chapter, opts.InputDir)
+	}
+	previousFile := filepath.Join(opts.InputDir, chapters[index].ChapterID+".md")
+
+	kb := opts.KnowledgeBase
+	if kb == nil {
+		kb, err = article.NewKnowledgeBase()
+		if err != nil {
+			return RewriteResult{}, errors.WithStack(err)
+		}
+	}
+
+	ctx = withCtxSubject(ctx, plan.Title)
+	ctx = withCtxLocale(ctx, opts.Locale)
+	ctx = withCtxPlan(ctx, plan)
+	if opts.StyleGuidelines != "" {
+		ctx = withCtxStyleGuidelines(ctx, opts.StyleGuidelines)
+	}
+	if opts.AdditionalContext != "" {
+		ctx = withCtxAdditionalContext(ctx, opts.AdditionalContext)
+	}
+	ctx = withCtxKnowledgeBase(ctx, kb)
+	ctx = withCtxSearcher(ctx, NewKnowledgeBaseAdapter(kb))
+
+	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{Name: ctxLocale(ctx).T(locale.PhaseRewrite)}))
+	_ = emit(agent.NewEvent(EventTypeChapterStart, &ChapterStartData{
+		Number: int(chapter.Number),
+		Total:  len(chapters),
+		Title:  chapter.Title,
+		Target: chapter.WordCount,
+	}))
+
+	writeCtx := withCtxChapter(ctx, chapter)
+	writeCtx = withCtxInstructions(writeCtx, opts.Instructions)
+	if index > 0 {
+		writeCtx = withCtxPreviousChapter(writeCtx, &chapters[index-1])
+	}
+	if index < len(chapters)-1 {
+		writeCtx = withCtxNextChapter(writeCtx, &chapters[index+1])
+	}
+
+	content, err := o.runChapterWriter(writeCtx, chapter, emit)
+	if err != nil {
+		return RewriteResult{}, errors.WithStack(err)
+	}
+
+	// The editor sees the other chapters to detect redundancies
+	others := slices.Delete(slices.Clone(chapters), index, index+1)
+
+	for round := 0; round < opts.MaxReviewRounds; round++ {
+		editCtx := withCtxChapter(ctx, chapter)
+		editCtx = withCtxAllChapters(editCtx, others)
+
+		content, err = o.runChapterEditor(editCtx, chapter, content, round, emit)
+		if err != nil {
+			return RewriteResult{}, errors.WithStack(err)
+		}
+	}
+
+	chapters[index] = content
+
+	_ = emit(agent.NewEvent(EventTypeChapterDone, &ChapterDoneData{
+		Number:    content.Number,
+		Total:     len(chapters),
+		Title:     content.Title,
+		WordCount: content.WordCount,
+	}))
+	_ = emit(agent.NewEvent(EventTypePhase, &PhaseData{
+		Name: ctxLocale(ctx).T(locale.PhaseRewrite),
+		Done: true,
+		Info: ctxLocale(ctx).T(locale.RewriteDone, content.Number, content.WordCount),
+	}))
+
+	coherence, err := o.coherencePass(ctx, plan, chapters, emit)
+	if err != nil {
+		return RewriteResult{}, errors.Wrap(err, "coherence phase failed")
+	}
+
+	collectSources(ctx, &coherence)
+
+	// A new title gives the chapter file a new name
+	file := filepath.Join(opts.InputDir, fmt.Sprintf("chapter-%02d-%s.md", content.Number, slug.Make(content.Title)))
+	if previousFile != file {
+		if err := os.Remove(previousFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return RewriteResult{}, errors.Wrapf(err, "could not remove previous chapter file %q", previousFile)
+		}
+	}
+
+	whitePaper, err := Assemble(plan, chapters, coherence, AssembleOptions{
+		OutputDir: opts.InputDir,
+		Locale:    opts.Locale,
+		// A white paper stopped by its budget stays marked as incomplete
+		Incomplete: len(chapters) < len(plan.allChapters()),
+		Rewritten:  content.Number,
+	})
+	if err != nil {
+		return RewriteResult{}, errors.Wrap(err, "assembly phase failed")
+	}
+
+	return RewriteResult{
+		Chapter:    content,
+		File:       file,
+		Entrypoint: whitePaper.Entrypoint,
+	}, nil
+}
+
+// RewriteChapter is a convenience function.
+func RewriteChapter(ctx context.Context, client llm.Client, emit agent.EmitFunc, optFuncs ...RewriteOptionFunc) (RewriteResult, error) {
+	o := NewOrchestrator(client)
+	return o.RewriteChapter(ctx, emit, optFuncs...)
+}
@@ -7,12 +7,13 @@ import (
 	"fmt"
 	"strings"
 	"text/template"
-	"time"
 
 	"github.com/bornholm/genai/agent"
 	"github.com/bornholm/genai/agent/loop"
 	"github.com/bornholm/genai/llm"
 	"github.com/bornholm/genai/llm/prompt"
+	"github.com/bornholm/ghostwriter/pkg/article"
+	"github.com/bornholm/ghostwriter/pkg/scraper"
 	"github.com/bornholm/ghostwriter/pkg/scraper/surf"
 	"github.com/bornholm/ghostwriter/pkg/tool"
 	"github.com/pkg/errors"
@@ -37,13 +38,14 @@ func (h *ChapterWriterHandler) Handle(ctx context.Context, input agent.Input, em
 
 	subject := ctxSubject(ctx, input.Message)
 	previous := ctxPreviousChapter(ctx)
+	next := ctxNextChapter(ctx)
 
 	ks, ok := ctxSearcher(ctx)
 	if !ok {
 		return errors.New("knowledge searcher not found in context")
 	}
 
-	content, err := h.writeChapter(ctx, chapter, subject, ks, previous, emit)
+	content, err := h.writeChapter(ctx, chapter, subject, ks, previous, next, emit)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -56,9 +58,9 @@ func (h *ChapterWriterHandler) Handle(ctx context.Context, input agent.Input, em
 	return emit(agent.NewEvent(agent.EventTypeComplete, &agent.CompleteData{Message: string(contentJSON)}))
 }
 
-func (h *ChapterWriterHandler) writeChapter(ctx context.Context, chapter *Chapter, subject string, ks KnowledgeSearcher, previous *ChapterContent, emit agent.EmitFunc) (ChapterContent, error) {
+func (h *ChapterWriterHandler) writeChapter(ctx context.Context, chapter *Chapter, subject string, ks KnowledgeSearcher, previous, next *ChapterContent, emit agent.EmitFunc) (ChapterContent, error) {
 	systemPrompt, err := prompt.FromFS[any](&writerPrompts, "prompts/writer_system.gotmpl", nil, prompt.WithFuncs(template.FuncMap{
-		"now": func() string { return time.Now().Format("2006-01-02") },
+		"now": func() string { return article.ContextNow(ctx).Format("2006-01-02") },
 	}))
 	if err != nil {
 		return ChapterContent{}, errors.WithStack(err)
@@ -67,7 +69,7 @@ func (h *ChapterWriterHandler) writeChapter(ctx context.Context, chapter *Chapte
 	tools := append(h.tools, NewSearchKnowledgeBaseTool(ks))
 
 	plan, _ := ctxPlan(ctx)
-	userPrompt := h.buildPrompt(chapter, plan, subject, ctxStyleGuidelines(ctx), ctxAdditionalContext(ctx), previous)
+	userPrompt := h.buildPrompt(chapter, plan, subject, ctxStyleGuidelines(ctx), ctxAdditionalContext(ctx), ctxInstructions(ctx), previous, next)
 
 	loopHandler, err := loop.NewHandler(
 		loop.WithClient(h.client),
@@ -100,7 +102,7 @@ func (h *ChapterWriterHandler) writeChapter(ctx context.Context, chapter *Chapte
 	}, nil
 }
 
-func (h *ChapterWriterHandler) buildPrompt(ch *Chapter, plan WhitePaperPlan, subject, styleGuidelines, additionalContext string, previous *ChapterContent) string {
+func (h *ChapterWriterHandler) buildPrompt(ch *Chapter, plan WhitePaperPlan, subject, styleGuidelines, additionalContext, instructions string, previous, next *ChapterContent) string {
 	var b strings.Builder
 
 	b.WriteString("Write chapter " + fmt.Sprintf("%d", ch.Number) + " of a professional white paper.\n\n")
@@ -162,6 +164,22 @@ func (h *ChapterWriterHandler) buildPrompt(ch *Chapter, plan WhitePaperPlan, sub
 		b.WriteString("Ensure a smooth logical transition from the previous chapter.\n\n")
 	}
 
+	if next != nil {
+		b.WriteString("**Next Chapter (for continuity):**\n")
+		fmt.Fprintf(&b, "- Title: %s\n", next.Title)
+		words := strings.Fields(next.Content)
+		end := min(len(words), 200)
+		b.WriteString("- First 200 words:\n")
+		b.WriteString(strings.Join(words[:end], " "))
+		b.WriteString("\n\n")
+		b.WriteString("End the chapter so that it leads naturally into the next one, without repeating its content.\n\n")
+	}
+
+	if instructions != "" {
+		b.WriteString("**Rewrite Instructions (PRIORITY):**\n```\n" + instructions + "\n```\n\n")
+		b.WriteString("This chapter is being rewritten: follow these instructions while keeping the chapter assignment above.\n\n")
+	}
+
 	b.WriteString("Start by querying the knowledge base for relevant information, then write the chapter.")
 	return b.String()
 }
@@ -174,8 +192,11 @@ func countWords(text string) int {
 }
 
 func NewChapterWriterHandler(client llm.ChatCompletionClient, extraTools ...llm.Tool) *ChapterWriterHandler {
-	scraper := surf.NewScraper()
-	defaultTools := []llm.Tool{tool.NewScrapeWebpageTool(scraper)}
+	return newChapterWriterHandler(client, surf.NewScraper(), extraTools...)
+}
+
+func newChapterWriterHandler(client llm.ChatCompletionClient, webScraper scraper.Scraper, extraTools ...llm.Tool) *ChapterWriterHandler {
+	defaultTools := []llm.Tool{tool.NewScrapeWebpageTool(webScraper)}
 	return &ChapterWriterHandler{
 		client: client,
 		tools:  append(defaultTools, extraTools...),